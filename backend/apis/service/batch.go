@@ -0,0 +1,284 @@
+package serviceApis
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"serverless-tesseract/models"
+	"serverless-tesseract/services"
+	"serverless-tesseract/utils"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// zipSignature starts every ZIP archive with at least one file
+var zipSignature = []byte("PK\x03\x04")
+
+// batchFile is a file of a batch, err is set when it cannot be processed
+type batchFile struct {
+	upload ocrUpload
+	err    *statusError
+}
+
+// OCRBatch godoc
+//
+//	@Summary		OCR Batch
+//	@Description	OCR many files in one request. Files are uploaded as repeated files fields, and ZIP archives among them are extracted. The organization is checked once for the pages of every file, identical files are only processed once, and the results or errors are returned by filename. Documents in an output format other than json are returned as download links.
+//	@Tags			OCR
+//	@Accept			multipart/form-data
+//	@Produce		json
+//
+// @Param 			X-API-Key 		header 		string 	true 	"API Key"
+// @Param			files			formData	[]file	true	"Files or ZIP archives of files"
+// @Param			cache_policy	formData	string	false	"Cache Policy (options: cache_first, no_cache, cache_only)"
+// @Param			engine			formData	string	false	"OCR Engine (options: see /api/ocr/engines)"
+// @Param			raw				formData	bool	false	"Raw (options: true, false)"
+// @Param			text_layer		formData	string	false	"Use the text layer of born-digital PDFs instead of OCR (options: auto, only, never)"
+// @Param			pages			formData	string	false	"Pages to OCR in every file, e.g. 1-3,7,10- (defaults to every page)"
+// @Param			languages		formData	string	false	"ISO 639-1 codes of the languages in the documents, comma separated or repeated, the first is the primary language, or auto to detect them per page (defaults to en, installed languages: see /api/ocr/engines)"
+// @Param			format			formData	string	false	"Response format, structured returns a page, block, paragraph, line and word tree (options: flat, structured)"
+// @Param			extract			formData	string	false	"Extract structured data from the words, comma separated or repeated, key_values returns label and value pairs using the organization's label synonyms (options: key_values)"
+// @Param			tables			formData	bool	false	"Detect tables and return their rows and columns as JSON and CSV alongside the words (options: true, false)"
+// @Param			barcodes		formData	bool	false	"Decode the QR, Code 128, EAN, UPC, Data Matrix and PDF417 barcodes on every page and return their payload, symbology and bounding box (options: true, false)"
+// @Param			output_format	formData	string	false	"Output document format, other formats than json require delivery=link (options: json, hocr, alto, pagexml, txt, tsv, searchable_pdf)"
+// @Param			delivery		formData	string	false	"How a document in an output format other than json is returned, batches only support link (options: link)"
+// @Param			preprocess		formData	string	false	"Image preprocessing steps to run before OCR, comma separated or all (options: orientation, upscale, grayscale, contrast, denoise, deskew, threshold)"
+// @Success		200			{object}	utils.BatchOCRResponse
+// @Failure		400			{object}	utils.ErrorResponse
+// @Failure		403			{object}	utils.ErrPermissionDeniedResponse
+// @Failure		500			{object}	utils.ErrorResponse
+// @Router			/api/ocr/batch [post]
+func OCRBatch(c *gin.Context) {
+	organizationID, ok := getAuthorizedOrganization(c)
+	if !ok {
+		return
+	}
+
+	options, err := parseOCROptions(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, validationErrorResponse(err))
+		return
+	}
+
+	if options.OutputFormat != utils.OutputJSON && options.Delivery != utils.DeliveryLink {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse{Error: "Invalid delivery: batches return documents as download links, use delivery=link"})
+		return
+	}
+
+	form, err := c.MultipartForm()
+	if err != nil || len(form.File["files"]) == 0 {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse{Error: "Failed to get files"})
+		return
+	}
+
+	files, err := readBatchFiles(form.File["files"])
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	response := utils.BatchOCRResponse{Files: map[string]utils.BatchFileResult{}}
+
+	// identical files are processed once, under the name of the first
+	var uploads []ocrUpload
+	firstByHash := map[string]string{}
+	duplicates := map[string]string{}
+	for _, file := range files {
+		if file.err != nil {
+			response.Files[file.upload.Filename] = utils.BatchFileResult{Status: file.err.status, Error: file.err.message}
+			continue
+		}
+
+		if first, ok := firstByHash[file.upload.Hash]; ok {
+			duplicates[file.upload.Filename] = first
+			continue
+		}
+
+		pages, err := services.CountSelectedPages(file.upload.Bytes, options.Pages)
+		if errors.Is(err, utils.ErrPageOutOfRange) {
+			response.Files[file.upload.Filename] = utils.BatchFileResult{Status: http.StatusBadRequest, Error: fmt.Sprintf("Invalid pages: %v", err)}
+			continue
+		}
+		if err != nil {
+			response.Files[file.upload.Filename] = utils.BatchFileResult{Status: http.StatusBadRequest, Error: fmt.Sprintf("Failed to read file: %v", err)}
+			continue
+		}
+
+		firstByHash[file.upload.Hash] = file.upload.Filename
+		uploads = append(uploads, file.upload)
+		response.Pages += pages
+	}
+
+	// check once that the organization can OCR every page of the batch
+	organization, ok := authorizeOCRPages(c, organizationID, response.Pages)
+	if !ok {
+		return
+	}
+
+	results := processBatch(c, uploads, options, organization)
+	if c.Request.Context().Err() != nil {
+		writeOCRError(c, c.Request.Context().Err())
+		return
+	}
+
+	for filename, result := range results {
+		response.Files[filename] = result
+	}
+	for filename, first := range duplicates {
+		result := response.Files[first]
+		result.DuplicateOf = first
+		response.Files[filename] = result
+	}
+
+	for _, result := range response.Files {
+		if result.Status == http.StatusOK {
+			response.Succeeded++
+		} else {
+			response.Failed++
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// processBatch OCRs the uploads one at a time and returns their results by
+// filename. Each document already OCRs as many of its pages at once as the
+// organization may, so files are not run in parallel on top of that. Once the
+// client is gone the remaining files are not started.
+func processBatch(c *gin.Context, uploads []ocrUpload, options ocrOptions, organization models.Organization) map[string]utils.BatchFileResult {
+	results := map[string]utils.BatchFileResult{}
+	for _, upload := range uploads {
+		if c.Request.Context().Err() != nil {
+			break
+		}
+		results[upload.Filename] = processBatchFile(c, upload, options, organization)
+	}
+	return results
+}
+
+// processBatchFile OCRs one upload of a batch and renders its results
+func processBatchFile(c *gin.Context, upload ocrUpload, options ocrOptions, organization models.Organization) utils.BatchFileResult {
+	results, err := processOCR(c, upload, options, organization)
+	if err != nil {
+		status, message := ocrErrorResponse(err)
+		return utils.BatchFileResult{Status: status, Error: message}
+	}
+
+	body, _, err := renderOCRResults(c.Request.Context(), results, options, upload.Bytes, organization.ID)
+	if err != nil {
+		return utils.BatchFileResult{Status: http.StatusInternalServerError, Error: err.Error()}
+	}
+
+	return utils.BatchFileResult{Status: http.StatusOK, Result: body}
+}
+
+// readBatchFiles reads the uploaded files, extracting ZIP archives into their
+// files. Files that cannot be processed are returned with their error, an error
+// is only returned when the batch is over BATCH_MAX_FILES or BATCH_SIZE_LIMIT or
+// two files have the same name.
+func readBatchFiles(headers []*multipart.FileHeader) ([]batchFile, error) {
+	var files []batchFile
+	size := 0
+	add := func(filename string, fileBytes []byte, err *statusError) error {
+		for _, file := range files {
+			if file.upload.Filename == filename {
+				return fmt.Errorf("Duplicate filename: %s", filename)
+			}
+		}
+		if len(files) == utils.BATCH_MAX_FILES {
+			return fmt.Errorf("A batch has at most %d files", utils.BATCH_MAX_FILES)
+		}
+		size += len(fileBytes)
+		if size > utils.BATCH_SIZE_LIMIT {
+			return errors.New("Batch size exceeds limit: " + strconv.Itoa(utils.BATCH_SIZE_LIMIT) + " bytes")
+		}
+
+		file := batchFile{upload: ocrUpload{Filename: filename, Bytes: fileBytes}, err: err}
+		if err == nil {
+			fileType, typeErr := services.DetectFileType(fileBytes)
+			if typeErr != nil {
+				file.err = &statusError{status: http.StatusUnsupportedMediaType, message: "Unsupported media type, supported types are PDF, PNG, JPEG, TIFF, WebP, BMP, GIF and HEIC"}
+			}
+			file.upload.FileType = fileType
+			file.upload.Hash = utils.GetSHA256Hash(fileBytes)
+		}
+		files = append(files, file)
+		return nil
+	}
+
+	for _, header := range headers {
+		if header.Size > int64(utils.BATCH_SIZE_LIMIT) {
+			return nil, errors.New("Batch size exceeds limit: " + strconv.Itoa(utils.BATCH_SIZE_LIMIT) + " bytes")
+		}
+
+		fileBytes, err := readFormFile(header)
+		if err != nil {
+			return nil, err
+		}
+
+		if !bytes.HasPrefix(fileBytes, zipSignature) {
+			var fileErr *statusError
+			if len(fileBytes) > utils.FILE_SIZE_LIMIT {
+				fileBytes, fileErr = nil, fileSizeError()
+			}
+			if err := add(header.Filename, fileBytes, fileErr); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		archive, err := zip.NewReader(bytes.NewReader(fileBytes), int64(len(fileBytes)))
+		if err != nil {
+			return nil, fmt.Errorf("Invalid ZIP archive %s: %v", header.Filename, err)
+		}
+		for _, entry := range archive.File {
+			// skip directories and the metadata archivers add next to the files
+			name := entry.Name
+			if entry.FileInfo().IsDir() || strings.HasPrefix(name, "__MACOSX/") || strings.HasPrefix(path.Base(name), ".") {
+				continue
+			}
+
+			entryBytes, fileErr := readZipEntry(entry)
+			if err := add(header.Filename+"/"+name, entryBytes, fileErr); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return files, nil
+}
+
+// readZipEntry extracts a file of a ZIP archive, reading no more than
+// FILE_SIZE_LIMIT bytes whatever size the archive claims the file has
+func readZipEntry(entry *zip.File) ([]byte, *statusError) {
+	if entry.UncompressedSize64 > uint64(utils.FILE_SIZE_LIMIT) {
+		return nil, fileSizeError()
+	}
+
+	src, err := entry.Open()
+	if err != nil {
+		return nil, &statusError{status: http.StatusBadRequest, message: fmt.Sprintf("Failed to extract file: %v", err)}
+	}
+	defer src.Close()
+
+	fileBytes, err := io.ReadAll(io.LimitReader(src, int64(utils.FILE_SIZE_LIMIT)+1))
+	if err != nil {
+		return nil, &statusError{status: http.StatusBadRequest, message: fmt.Sprintf("Failed to extract file: %v", err)}
+	}
+	if len(fileBytes) > utils.FILE_SIZE_LIMIT {
+		return nil, fileSizeError()
+	}
+
+	return fileBytes, nil
+}
+
+func fileSizeError() *statusError {
+	return &statusError{status: http.StatusBadRequest, message: "File size exceeds limit: " + strconv.Itoa(utils.FILE_SIZE_LIMIT) + " bytes"}
+}
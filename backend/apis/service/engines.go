@@ -0,0 +1,34 @@
+package serviceApis
+
+import (
+	"net/http"
+	"serverless-tesseract/services/engines"
+	"serverless-tesseract/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListEngines godoc
+//
+//	@Summary		List OCR Engines
+//	@Description	List the OCR engines enabled in this deployment with their capabilities and installed languages
+//	@Tags			OCR
+//
+// @Param 			X-API-Key 		header 		string 	true 	"API Key"
+// @Success		200			{array}		utils.OCREngineInfo
+// @Router			/api/ocr/engines [get]
+func ListEngines(c *gin.Context) {
+	response := []utils.OCREngineInfo{}
+	for _, engine := range engines.All() {
+		capabilities := engine.Capabilities()
+		response = append(response, utils.OCREngineInfo{
+			Name:               engine.Name(),
+			WordBoxes:          capabilities.WordBoxes,
+			Confidence:         capabilities.Confidence,
+			Layout:             capabilities.Layout,
+			SupportedLanguages: engine.SupportedLanguages(),
+		})
+	}
+
+	c.JSON(http.StatusOK, response)
+}
@@ -2,16 +2,23 @@ package serviceApis
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"mime/multipart"
 	"net/http"
-	"path/filepath"
 	"serverless-tesseract/db"
+	"serverless-tesseract/models"
 	"serverless-tesseract/polar"
 	"serverless-tesseract/services"
 	"serverless-tesseract/services/cache"
+	"serverless-tesseract/services/engines"
+	"serverless-tesseract/services/export"
+	"serverless-tesseract/services/preprocess"
 	"serverless-tesseract/utils"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -21,22 +28,41 @@ import (
 // OCRService godoc
 //
 //	@Summary		OCR Service
-//	@Description	OCR Service for the OCR Service
+//	@Description	OCR Service for the OCR Service. A JSON body with the file as base64 is handled like /api/ocr/json.
 //	@Tags			OCR
-//	@Accept			multipart/form-data
+//	@Accept			multipart/form-data,json
+//	@Produce		json,html,xml,plain,text/tab-separated-values,application/zip,application/pdf
 //
 // @Param 			X-API-Key 		header 		string 	true 	"API Key"
 // @Param			file			formData	file				true	"File"
 // @Param			cache_policy	formData	string	false	"Cache Policy (options: cache_first, no_cache, cache_only)"
-// @Param			engine			formData	string	false	"OCR Engine (options: TESSERACT, EASYOCR, DOCTR)"
+// @Param			engine			formData	string	false	"OCR Engine (options: see /api/ocr/engines)"
 // @Param			raw				formData	bool	false	"Raw (options: true, false)"
+// @Param			text_layer		formData	string	false	"Use the text layer of born-digital PDFs instead of OCR (options: auto, only, never)"
+// @Param			pages			formData	string	false	"Pages to OCR, e.g. 1-3,7,10- (defaults to every page)"
+// @Param			languages		formData	string	false	"ISO 639-1 codes of the languages in the document, comma separated or repeated, the first is the primary language, or auto to detect them per page (defaults to en, installed languages: see /api/ocr/engines)"
+// @Param			format			formData	string	false	"Response format, structured returns a page, block, paragraph, line and word tree (options: flat, structured)"
+// @Param			extract			formData	string	false	"Extract structured data from the words, comma separated or repeated, key_values returns label and value pairs using the organization's label synonyms (options: key_values)"
+// @Param			tables			formData	bool	false	"Detect tables and return their rows and columns as JSON and CSV alongside the words (options: true, false)"
+// @Param			barcodes		formData	bool	false	"Decode the QR, Code 128, EAN, UPC, Data Matrix and PDF417 barcodes on every page and return their payload, symbology and bounding box (options: true, false)"
+// @Param			output_format	formData	string	false	"Output document format, searchable_pdf returns the original document with an invisible text layer (options: json, hocr, alto, pagexml, txt, tsv, searchable_pdf)"
+// @Param			delivery		formData	string	false	"How a document in an output format other than json is returned, link returns an expiring download link (options: bytes, link)"
+// @Param			preprocess		formData	string	false	"Image preprocessing steps to run before OCR, comma separated or all (options: orientation, upscale, grayscale, contrast, denoise, deskew, threshold)"
 // @Param			organization_id	formData	string	true	"Organization ID"
-// @Success		200			{object}	utils.OCRResponseList
+// @Success		200			{object}	utils.OCRResponseList	"utils.StructuredOCRResponse when format is structured"
 // @Failure		400			{object}	utils.ErrorResponse
 // @Failure		403			{object}	utils.ErrPermissionDeniedResponse
+// @Failure		415			{object}	utils.ErrorResponse
 // @Failure		500			{object}	utils.ErrorResponse
+// @Failure		504			{object}	utils.ErrorResponse
 // @Router			/api/ocr [post]
 func OCRService2(c *gin.Context) {
+	// a JSON body carries the file as base64 or the source to read it from
+	if c.ContentType() == gin.MIMEJSON {
+		OCRJSON(c)
+		return
+	}
+
 	// get the file from the request
 	file, err := c.FormFile("file")
 	if err != nil {
@@ -56,41 +82,9 @@ func OCRService2(c *gin.Context) {
 		return
 	}
 
-	engine := c.PostForm("engine")
-
-	// if the engine is not set, set it to tesseract
-	if engine == "" {
-		engine = string(utils.EngineTesseract)
-	}
-
-	// validate the engine
-	if !utils.IsValidEngine(engine) {
-		c.JSON(http.StatusBadRequest, utils.ErrorResponse{Error: "Invalid engine"})
-		return
-	}
-
-	raw := c.PostForm("raw")
-	// if raw is not set, set it to true
-	if raw == "" {
-		raw = "true"
-	}
-
-	// validate the raw
-	if raw != "true" && raw != "false" {
-		c.JSON(http.StatusBadRequest, utils.ErrorResponse{Error: "Invalid raw"})
-		return
-	}
-
-	cache_policy := c.PostForm("cache_policy")
-
-	// if the cache_policy is not set, set it to cache_first
-	if cache_policy == "" {
-		cache_policy = string(utils.CacheFirst)
-	}
-
-	// validate the cache_policy
-	if !utils.IsValidCachePolicy(cache_policy) {
-		c.JSON(http.StatusBadRequest, utils.ErrorResponse{Error: "Invalid cache policy"})
+	options, err := parseOCROptions(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, validationErrorResponse(err))
 		return
 	}
 
@@ -101,56 +95,81 @@ func OCRService2(c *gin.Context) {
 		return
 	}
 
-	// Open the uploaded file
-	src, err := file.Open()
+	fileBytes, err := readFormFile(file)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, utils.ErrorResponse{Error: "Failed to open uploaded file"})
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse{Error: err.Error()})
 		return
 	}
-	defer src.Close()
 
-	// Read the file data into memory
-	buffer := bytes.NewBuffer(nil)
-	if _, err := io.Copy(buffer, src); err != nil {
-		c.JSON(http.StatusInternalServerError, utils.ErrorResponse{Error: "Failed to read file data"})
+	fileType, ok := detectFileType(c, fileBytes)
+	if !ok {
 		return
 	}
-	fileBytes := buffer.Bytes()
 
 	// check if the user can use OCR
-	organization, err := db.GetOrganization(organizationID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, utils.ErrorResponse{Error: fmt.Sprintf("Failed to get organization: %v", err)})
+	organization, ok := authorizeOCR(c, organizationID)
+	if !ok {
 		return
 	}
 
-	canUseOCR, err := polar.CanUserUseOCR(c, organization)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, utils.ErrorResponse{Error: fmt.Sprintf("Failed to check if user can use OCR: %v", err)})
+	if !validatePages(c, fileBytes, options.Pages) {
 		return
 	}
 
-	if !canUseOCR {
-		c.JSON(http.StatusForbidden, utils.ErrPermissionDeniedResponse{Error: utils.ErrPermissionDenied.Error()})
+	results, err := processOCR(c, ocrUpload{
+		Filename: file.Filename,
+		Bytes:    fileBytes,
+		FileType: fileType,
+		// calculate the hash based off the file bytes
+		Hash: utils.GetSHA256Hash(fileBytes),
+	}, options, organization)
+	if err != nil {
+		writeOCRError(c, err)
 		return
 	}
 
-	// calculate the hash based off the file bytes
-	fileHash := utils.GetSHA256Hash(fileBytes)
-	// catch engine as a string to into a utils.OCREngine struct
+	writeOCRResults(c, results, options, fileBytes, organizationID)
+}
+
+// ocrUpload is an uploaded file ready to be OCR'd
+type ocrUpload struct {
+	Filename string
+	Bytes    []byte
+	FileType services.FileType
+	Hash     string
+}
+
+// processOCR returns the results of the upload from the cache or by running OCR,
+// depending on the cache policy, and records the request. Errors that are not
+// OCR failures are returned as a *statusError with the status to respond with.
+func processOCR(c *gin.Context, upload ocrUpload, options ocrOptions, organization models.Organization) (utils.OCRResponseList, error) {
+	organizationID := organization.ID
+	engine := options.Engine
+	raw := options.Raw
+	cache_policy := options.CachePolicy
+	text_layer := string(options.TextLayer)
+	preprocess := options.Preprocess.String()
+	languages := strings.Join(options.Languages, ",")
+	fileHash := upload.Hash
+	mime_type := upload.FileType.MediaType
 
 	results, cache_hit, err := cache.GetCacheResult(
 		fileHash,
 		utils.CachePolicyType(cache_policy),
 		organizationID,
 		engine,
-		raw == "true",
+		raw,
+		options.TextLayer,
+		preprocess,
+		languages,
+		options.Tables,
+		options.Barcodes,
 	)
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, utils.ErrorResponse{Error: fmt.Sprintf("Failed to get cache result: %v", err)})
-		return
+		return utils.OCRResponseList{}, &statusError{status: http.StatusInternalServerError, message: fmt.Sprintf("Failed to get cache result: %v", err)}
 	}
+	results = cache.SelectPages(results, options.Pages)
 
 	// if the cache_policy is cache_only, return the results
 	if cache_policy == string(utils.CacheOnly) || (cache_hit && cache_policy == string(utils.CacheFirst)) {
@@ -163,205 +182,651 @@ func OCRService2(c *gin.Context) {
 				cache_hit,
 				engine,
 				organizationID,
-				file.Filename,
+				upload.Filename,
 				success,
 				0,
 				fileHash,
-				raw == "true",
+				raw,
+				text_layer,
+				preprocess,
+				languages,
+				options.Tables,
+				options.Barcodes,
+				mime_type,
+				nil,
 				nil,
 			)
 			if err != nil {
-				c.JSON(http.StatusInternalServerError, utils.ErrorResponse{Error: fmt.Sprintf("Failed to record OCR request: %v", err)})
-				return
+				return utils.OCRResponseList{}, &statusError{status: http.StatusInternalServerError, message: fmt.Sprintf("Failed to record OCR request: %v", err)}
 			}
-			c.JSON(http.StatusNotFound, utils.ErrorResponse{Error: "No cache results found"})
-			return
+			return utils.OCRResponseList{}, &statusError{status: http.StatusNotFound, message: "No cache results found"}
 		}
 		token_count := results.NumberOfTokens
 		num_of_pages := int32(1)
-		_, err = db.CreateOCRRequest(
+		request, err := db.CreateOCRRequest(
 			c,
 			num_of_pages,
 			cache_hit,
 			engine,
 			organizationID,
-			file.Filename,
+			upload.Filename,
 			true,
 			int64(token_count),
 			fileHash,
-			raw == "true",
+			raw,
+			text_layer,
+			preprocess,
+			languages,
+			options.Tables,
+			options.Barcodes,
+			mime_type,
 			&fileHash,
+			nil,
 		)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, utils.ErrorResponse{Error: fmt.Sprintf("Failed to record OCR request: %v", err)})
-			return
+			return utils.OCRResponseList{}, &statusError{status: http.StatusInternalServerError, message: fmt.Sprintf("Failed to record OCR request: %v", err)}
 		}
+		results.RequestID = request.ID
 		results.Cached = cache_hit
-		results.Raw = raw == "true"
+		results.Raw = raw
 		results.Engine = utils.OCREngineType(engine)
-		c.JSON(http.StatusOK, results)
-		return
+		results.Languages = options.Languages
+		return *results, nil
 	}
 
 	// can assume the cache_policy is cache_first or no_cache
-	fileExt := strings.ToLower(filepath.Ext(file.Filename))
 	cache_hit = false
-	allResults := utils.OCRResponseList{}
-	number_of_pages := int32(0)
-	number_of_tokens := int64(0)
-	if fileExt == ".pdf" {
-		// split the pdf into image pages
-		imageBytesList, err := services.ProcessPDF(&fileBytes)
-		if err != nil {
-			_, err = db.CreateOCRRequest(
-				c,
-				int32(0),
-				cache_hit,
-				engine,
-				organizationID,
-				file.Filename,
-				false,
-				0,
-				fileHash,
-				raw == "true",
-				nil,
-			)
-			if err != nil {
-				log.Printf("Failed to process PDF: %v", err)
-				c.JSON(http.StatusInternalServerError, utils.ErrorResponse{Error: fmt.Sprintf("Failed to process PDF: %v", err)})
-				return
-			}
-			c.JSON(http.StatusInternalServerError, utils.ErrorResponse{Error: fmt.Sprintf("Failed to process PDF: %v", err)})
-			return
-		}
-
-		for i, imgBytes := range imageBytesList {
-			pageResults, err := services.RunOCR(imgBytes, utils.OCREngineType(engine), i+1, raw == "true")
-			if err != nil {
-				db.CreateOCRRequest(
-					c,
-					// record the number of pages that were processed
-					int32(i+1),
-					cache_hit,
-					engine,
-					organizationID,
-					file.Filename,
-					false,
-					number_of_tokens,
-					fileHash,
-					raw == "true",
-					nil,
-				)
-				if err != nil {
-					log.Printf("Failed to create OCR request: %v", err)
-					c.JSON(http.StatusInternalServerError, utils.ErrorResponse{Error: fmt.Sprintf("Failed to OCR page %d: %v", i+1, err)})
-					return
-				}
-				c.JSON(http.StatusInternalServerError, utils.ErrorResponse{Error: fmt.Sprintf("Failed to OCR page %d: %v", i+1, err)})
-				return
-			}
-			allResults.OCRResponses = append(allResults.OCRResponses, pageResults.OCRResponses...)
-			allResults.Raw = raw == "true"
-			allResults.Cached = cache_hit
-			allResults.Engine = utils.OCREngineType(engine)
-			allResults.NumberOfTokens = pageResults.NumberOfTokens + allResults.NumberOfTokens
-			number_of_pages = int32(i + 1)
-			number_of_tokens += pageResults.NumberOfTokens
-		}
-
-		err = db.SaveFileHashCache(
-			fileHash,
-			allResults,
-			organizationID,
+	allResults, number_of_pages, err := services.OCRDocument(c.Request.Context(), upload.Bytes, services.DocumentOptions{
+		Engine:          utils.OCREngineType(engine),
+		Raw:             raw,
+		Pages:           options.Pages,
+		TextLayer:       options.TextLayer,
+		Languages:       options.Languages,
+		Preprocess:      options.Preprocess,
+		Tables:          options.Tables,
+		Barcodes:        options.Barcodes,
+		OrganizationID:  organizationID,
+		PageConcurrency: organization.PageConcurrency(),
+		OnPageResult:    options.OnPageResult,
+	})
+	if errors.Is(err, utils.ErrInvalidFileType) {
+		return utils.OCRResponseList{}, &statusError{status: http.StatusBadRequest, message: fmt.Sprintf("Invalid file: %v", err)}
+	}
+	if err != nil {
+		_, recordErr := db.CreateOCRRequest(
+			c,
+			number_of_pages,
+			cache_hit,
 			engine,
-			raw == "true",
+			organizationID,
+			upload.Filename,
+			false,
+			allResults.NumberOfTokens,
+			fileHash,
+			raw,
+			text_layer,
+			preprocess,
+			languages,
+			options.Tables,
+			options.Barcodes,
+			mime_type,
+			nil,
+			nil,
 		)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, utils.ErrorResponse{Error: fmt.Sprintf("Failed to save cache result: %v", err)})
-			return
+		if recordErr != nil {
+			log.Printf("Failed to create OCR request: %v", recordErr)
 		}
+		return utils.OCRResponseList{}, err
+	}
 
-	} else if fileExt == ".png" || fileExt == ".jpg" || fileExt == ".jpeg" {
-		pageResults, err := services.RunOCR(fileBytes, utils.OCREngineType(engine), 1, raw == "true")
-		number_of_pages = int32(1)
-		if err != nil {
-			db.CreateOCRRequest(
-				c,
-				int32(0),
-				cache_hit,
-				engine,
-				organizationID,
-				file.Filename,
-				false,
-				0,
-				fileHash,
-				raw == "true",
-				nil,
-			)
-			if err != nil {
-				log.Printf("Failed to create OCR request: %v", err)
-			}
-			c.JSON(http.StatusInternalServerError, utils.ErrorResponse{Error: fmt.Sprintf("Failed to OCR page: %v", err)})
-			return
-		}
-		allResults.OCRResponses = append(allResults.OCRResponses, pageResults.OCRResponses...)
-		allResults.Engine = utils.OCREngineType(engine)
-		allResults.NumberOfTokens = pageResults.NumberOfTokens
-		allResults.Raw = raw == "true"
-		allResults.Cached = cache_hit
-		number_of_tokens = pageResults.NumberOfTokens
-		err = db.SaveFileHashCache(
+	// only whole documents are cached, a page selection is served from them with cache.SelectPages
+	var cacheHash *string
+	if len(options.Pages) == 0 {
+		allResults.DocumentKey, err = db.SaveFileHashCache(
 			fileHash,
 			allResults,
 			organizationID,
 			engine,
-			raw == "true",
+			raw,
+			text_layer,
+			preprocess,
+			languages,
+			options.Tables,
+			options.Barcodes,
 		)
-		if err != nil {
-			db.CreateOCRRequest(
-				c,
-				int32(1),
-				cache_hit,
-				engine,
-				organizationID,
-				file.Filename,
-				false,
-				0,
-				fileHash,
-				raw == "true",
-				nil,
-			)
-			if err != nil {
-				log.Printf("Failed to create OCR request: %v", err)
-			}
-			c.JSON(http.StatusInternalServerError, utils.ErrorResponse{Error: fmt.Sprintf("Failed to save cache result: %v", err)})
-			return
+		cacheHash = &fileHash
+	}
+	if err != nil {
+		_, recordErr := db.CreateOCRRequest(
+			c,
+			number_of_pages,
+			cache_hit,
+			engine,
+			organizationID,
+			upload.Filename,
+			false,
+			0,
+			fileHash,
+			raw,
+			text_layer,
+			preprocess,
+			languages,
+			options.Tables,
+			options.Barcodes,
+			mime_type,
+			nil,
+			nil,
+		)
+		if recordErr != nil {
+			log.Printf("Failed to create OCR request: %v", recordErr)
 		}
-	} else {
-		c.JSON(http.StatusBadRequest, utils.ErrorResponse{Error: "Invalid file type"})
-		return
+		return utils.OCRResponseList{}, &statusError{status: http.StatusInternalServerError, message: fmt.Sprintf("Failed to save cache result: %v", err)}
 	}
 
-	_, err = db.CreateOCRRequest(
+	request, err := db.CreateOCRRequest(
 		c,
 		number_of_pages,
 		cache_hit,
 		engine,
 		organizationID,
-		file.Filename,
+		upload.Filename,
 		true,
-		number_of_tokens,
+		allResults.NumberOfTokens,
 		fileHash,
-		raw == "true",
-		&fileHash,
+		raw,
+		text_layer,
+		preprocess,
+		languages,
+		options.Tables,
+		options.Barcodes,
+		mime_type,
+		cacheHash,
+		nil,
 	)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, utils.ErrorResponse{Error: fmt.Sprintf("Failed to create OCR request: %v", err)})
-		return
+		return utils.OCRResponseList{}, &statusError{status: http.StatusInternalServerError, message: fmt.Sprintf("Failed to create OCR request: %v", err)}
 	}
 
+	allResults.RequestID = request.ID
 	allResults.Cached = cache_hit
-	allResults.Raw = raw == "true"
+	allResults.Raw = raw
 	allResults.Engine = utils.OCREngineType(engine)
-	c.JSON(http.StatusOK, allResults)
+	allResults.Languages = options.Languages
+	return allResults, nil
+}
+
+// writeOCRResults responds with the results in the requested format, see renderOCRResults
+func writeOCRResults(c *gin.Context, results utils.OCRResponseList, options ocrOptions, fileBytes []byte, organizationID int64) {
+	body, document, err := renderOCRResults(c.Request.Context(), results, options, fileBytes, organizationID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if document != nil {
+		c.Data(http.StatusOK, document.ContentType, document.Body)
+		return
+	}
+	c.JSON(http.StatusOK, body)
+}
+
+// renderOCRResults returns the results in the requested format, rendering them as
+// a document when an output format other than json is requested. The document is
+// returned as is, or as a download link in the JSON body with delivery=link.
+// fileBytes is the original file searchable PDFs are drawn over, nil when it is
+// no longer available.
+func renderOCRResults(ctx context.Context, results utils.OCRResponseList, options ocrOptions, fileBytes []byte, organizationID int64) (any, *export.Document, error) {
+	format, outputFormat, delivery := options.Format, options.OutputFormat, options.Delivery
+
+	// key values are extracted as results are returned, so changes to the
+	// organization's label synonyms apply to cached results too
+	if slices.Contains(options.Extract, utils.ExtractKeyValues) {
+		synonyms, err := labelSynonyms(organizationID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Failed to get key value labels: %v", err)
+		}
+		results.KeyValues = services.ExtractKeyValues(results, synonyms)
+	}
+
+	if outputFormat != "" && outputFormat != utils.OutputJSON {
+		document, err := export.Artifact(ctx, results, outputFormat, fileBytes)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Failed to render %s: %v", outputFormat, err)
+		}
+
+		if delivery == utils.DeliveryLink {
+			url, expiresAt, err := export.Link(document, outputFormat, organizationID)
+			if err != nil {
+				return nil, nil, fmt.Errorf("Failed to create download link: %v", err)
+			}
+			return utils.DownloadLinkResponse{URL: url, ContentType: document.ContentType, ExpiresAt: expiresAt}, nil, nil
+		}
+
+		// searchable PDFs are expensive to build, so they are kept in R2 even when the bytes are returned
+		if outputFormat == utils.OutputSearchablePDF {
+			if _, err := export.Store(document, outputFormat, organizationID); err != nil {
+				log.Printf("Failed to store searchable PDF: %v", err)
+			}
+		}
+
+		return nil, &document, nil
+	}
+
+	if format == utils.FormatStructured {
+		return services.StructureResults(results), nil, nil
+	}
+	return results, nil, nil
+}
+
+// detectFileType sniffs the format of the upload from its content, writing a 415
+// response and returning false when it is not a format that can be processed
+func detectFileType(c *gin.Context, fileBytes []byte) (services.FileType, bool) {
+	fileType, err := services.DetectFileType(fileBytes)
+	if err != nil {
+		c.JSON(http.StatusUnsupportedMediaType, utils.ErrorResponse{Error: "Unsupported media type, supported types are PDF, PNG, JPEG, TIFF, WebP, BMP, GIF and HEIC"})
+		return fileType, false
+	}
+	return fileType, true
+}
+
+// validatePages checks the page selection against the file, writing a 400 response
+// and returning false when it selects pages the file does not have
+func validatePages(c *gin.Context, fileBytes []byte, pages utils.PageSelection) bool {
+	err := services.ValidatePageSelection(fileBytes, pages)
+	if errors.Is(err, utils.ErrPageOutOfRange) {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse{Error: fmt.Sprintf("Invalid pages: %v", err)})
+		return false
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse{Error: fmt.Sprintf("Failed to read file: %v", err)})
+		return false
+	}
+	return true
+}
+
+// statusError is an error responded with its own status and message
+type statusError struct {
+	status  int
+	message string
+}
+
+func (e *statusError) Error() string {
+	return e.message
+}
+
+// writeOCRError responds with the status of a *statusError, a 504 when OCR ran
+// past its deadline and a 500 otherwise
+func writeOCRError(c *gin.Context, err error) {
+	if errors.Is(err, context.Canceled) {
+		// the client disconnected, there is no one left to respond to
+		log.Printf("OCR cancelled: %v", err)
+		c.Abort()
+		return
+	}
+
+	status, message := ocrErrorResponse(err)
+	c.JSON(status, utils.ErrorResponse{Error: message})
+}
+
+// ocrErrorResponse returns the status and message an error is responded with
+func ocrErrorResponse(err error) (int, string) {
+	var statusErr *statusError
+	if errors.As(err, &statusErr) {
+		return statusErr.status, statusErr.message
+	}
+
+	var timeoutErr *utils.TimeoutError
+	if errors.As(err, &timeoutErr) {
+		return http.StatusGatewayTimeout, fmt.Sprintf("OCR timed out: %v", timeoutErr)
+	}
+
+	if errors.Is(err, utils.ErrImageTooLarge) {
+		return http.StatusBadRequest, fmt.Sprintf("Invalid file: %v", err)
+	}
+
+	return http.StatusInternalServerError, fmt.Sprintf("Failed to OCR file: %v", err)
+}
+
+// ocrOptions are the processing options shared by the OCR endpoints
+type ocrOptions struct {
+	Engine       string
+	Raw          bool
+	CachePolicy  string
+	TextLayer    utils.TextLayerModeType
+	Pages        utils.PageSelection
+	Preprocess   preprocess.Steps
+	Languages    []string
+	Tables       bool
+	Barcodes     bool
+	Extract      []utils.ExtractType
+	Format       utils.ResponseFormatType
+	OutputFormat utils.OutputFormatType
+	Delivery     utils.DeliveryType
+
+	// OnPageResult is set by handlers that stream each page's results, see
+	// services.DocumentOptions
+	OnPageResult func(pageNumber int, results utils.OCRResponseList)
+}
+
+// validationError lists the invalid fields of a request, its message joins
+// the errors of every field
+type validationError struct {
+	Fields []utils.FieldError
+}
+
+func (e *validationError) add(field string, err error) {
+	e.Fields = append(e.Fields, utils.FieldError{Field: field, Error: err.Error()})
+}
+
+func (e *validationError) Error() string {
+	messages := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		messages[i] = field.Error
+	}
+	return strings.Join(messages, "; ")
+}
+
+// validationErrorResponse is the 400 response for a request that failed
+// validation, with the errors of its fields when they are known
+func validationErrorResponse(err error) utils.ErrorResponse {
+	var invalid *validationError
+	if errors.As(err, &invalid) {
+		return utils.ErrorResponse{Error: invalid.Error(), Fields: invalid.Fields}
+	}
+	return utils.ErrorResponse{Error: err.Error()}
+}
+
+// ocrFields are the OCR options as they are sent in a form or a JSON body,
+// before they are validated
+type ocrFields struct {
+	Engine       string
+	Raw          string
+	CachePolicy  string
+	TextLayer    string
+	Pages        string
+	Preprocess   string
+	Languages    []string
+	Tables       string
+	Barcodes     string
+	Extract      []string
+	Format       string
+	OutputFormat string
+	Delivery     string
+}
+
+// parseOCROptions reads the engine, raw, cache_policy, text_layer, pages,
+// preprocess, languages, tables, barcodes, extract, format, output_format and delivery form fields, applying defaults for fields that are not set
+func parseOCROptions(c *gin.Context) (ocrOptions, error) {
+	return validateOCROptions(ocrFields{
+		Engine:       c.PostForm("engine"),
+		Raw:          c.PostForm("raw"),
+		CachePolicy:  c.PostForm("cache_policy"),
+		TextLayer:    c.PostForm("text_layer"),
+		Pages:        c.PostForm("pages"),
+		Preprocess:   c.PostForm("preprocess"),
+		Languages:    c.PostFormArray("languages"),
+		Tables:       c.PostForm("tables"),
+		Barcodes:     c.PostForm("barcodes"),
+		Extract:      c.PostFormArray("extract"),
+		Format:       c.PostForm("format"),
+		OutputFormat: c.PostForm("output_format"),
+		Delivery:     c.PostForm("delivery"),
+	})
+}
+
+// validateOCROptions validates the OCR options of a form or JSON body, applying
+// defaults for fields that are not set. Every invalid field is reported in the
+// returned *validationError.
+func validateOCROptions(fields ocrFields) (ocrOptions, error) {
+	invalid := &validationError{}
+
+	engine := fields.Engine
+
+	// if the engine is not set, set it to tesseract
+	if engine == "" {
+		engine = string(utils.EngineTesseract)
+	}
+
+	// validate the engine
+	if !utils.IsValidEngine(engine) {
+		invalid.add("engine", errors.New("Invalid engine"))
+	}
+	ocrEngine, engineFound := engines.Get(utils.OCREngineType(engine))
+
+	// languages can be repeated or comma separated
+	var requestedLanguages []string
+	for _, value := range fields.Languages {
+		requestedLanguages = append(requestedLanguages, strings.Split(value, ",")...)
+	}
+
+	// validate the languages against the engine's installed languages
+	// the languages can only be checked against a valid engine
+	var languages []string
+	if engineFound {
+		var err error
+		languages, err = engines.ResolveLanguages(ocrEngine, requestedLanguages)
+		if err != nil {
+			invalid.add("languages", fmt.Errorf("Invalid languages: %v", err))
+		}
+	}
+
+	format := fields.Format
+
+	// if the format is not set, return a flat list
+	if format == "" {
+		format = string(utils.FormatFlat)
+	}
+
+	// validate the format
+	if !utils.IsValidResponseFormat(format) {
+		invalid.add("format", errors.New("Invalid format"))
+	}
+
+	output_format := fields.OutputFormat
+
+	// if the output_format is not set, return json
+	if output_format == "" {
+		output_format = string(utils.OutputJSON)
+	}
+
+	// validate the output_format
+	if !utils.IsValidOutputFormat(output_format) {
+		invalid.add("output_format", errors.New("Invalid output format"))
+	}
+
+	delivery, err := parseDelivery(fields.Delivery, utils.OutputFormatType(output_format))
+	if err != nil {
+		invalid.add("delivery", err)
+	}
+
+	tables := fields.Tables
+
+	// if tables is not set, do not detect tables
+	if tables == "" {
+		tables = "false"
+	}
+
+	// validate the tables
+	if tables != "true" && tables != "false" {
+		invalid.add("tables", errors.New("Invalid tables"))
+	}
+
+	barcodes := fields.Barcodes
+
+	// if barcodes is not set, do not detect barcodes
+	if barcodes == "" {
+		barcodes = "false"
+	}
+
+	// validate the barcodes
+	if barcodes != "true" && barcodes != "false" {
+		invalid.add("barcodes", errors.New("Invalid barcodes"))
+	}
+
+	extract, err := parseExtract(fields.Extract)
+	if err != nil {
+		invalid.add("extract", err)
+	}
+
+	raw := fields.Raw
+	// the structured format, output formats, tables and extraction are built from individual words
+	needsWords := format == string(utils.FormatStructured) || output_format != string(utils.OutputJSON) || tables == "true" || len(extract) > 0
+	if raw == "true" && needsWords {
+		invalid.add("raw", errors.New("raw cannot be used with the structured format, tables, extract or output formats other than json"))
+	}
+	if raw == "" && needsWords {
+		raw = "false"
+	}
+	// if raw is not set, set it to true
+	if raw == "" {
+		raw = "true"
+	}
+
+	// validate the raw
+	if raw != "true" && raw != "false" {
+		invalid.add("raw", errors.New("Invalid raw"))
+	}
+
+	cache_policy := fields.CachePolicy
+
+	// if the cache_policy is not set, set it to cache_first
+	if cache_policy == "" {
+		cache_policy = string(utils.CacheFirst)
+	}
+
+	// validate the cache_policy
+	if !utils.IsValidCachePolicy(cache_policy) {
+		invalid.add("cache_policy", errors.New("Invalid cache policy"))
+	}
+
+	text_layer := fields.TextLayer
+
+	// if the text_layer is not set, OCR every page
+	if text_layer == "" {
+		text_layer = string(utils.TextLayerNever)
+	}
+
+	// validate the text_layer
+	if !utils.IsValidTextLayerMode(text_layer) {
+		invalid.add("text_layer", errors.New("Invalid text layer"))
+	}
+
+	// an empty selection selects every page
+	pages, err := utils.ParsePageSelection(fields.Pages)
+	if err != nil {
+		invalid.add("pages", fmt.Errorf("Invalid pages: %v", err))
+	}
+
+	// no preprocessing unless steps are requested
+	steps, err := preprocess.Parse(fields.Preprocess)
+	if err != nil {
+		invalid.add("preprocess", fmt.Errorf("Invalid preprocess: %v", err))
+	}
+
+	if len(invalid.Fields) > 0 {
+		return ocrOptions{}, invalid
+	}
+
+	return ocrOptions{
+		Engine:       engine,
+		Raw:          raw == "true",
+		CachePolicy:  cache_policy,
+		TextLayer:    utils.TextLayerModeType(text_layer),
+		Pages:        pages,
+		Preprocess:   steps,
+		Languages:    languages,
+		Tables:       tables == "true",
+		Barcodes:     barcodes == "true",
+		Extract:      extract,
+		Format:       utils.ResponseFormatType(format),
+		OutputFormat: utils.OutputFormatType(output_format),
+		Delivery:     delivery,
+	}, nil
+}
+
+// parseExtract validates what is extracted from the words, the values can be
+// repeated or comma separated and nothing is extracted when there are none
+func parseExtract(values []string) ([]utils.ExtractType, error) {
+	extract := []utils.ExtractType{}
+	for _, value := range values {
+		for _, name := range strings.Split(value, ",") {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				continue
+			}
+			if !utils.IsValidExtract(name) {
+				return nil, fmt.Errorf("Invalid extract: %s", name)
+			}
+			if !slices.Contains(extract, utils.ExtractType(name)) {
+				extract = append(extract, utils.ExtractType(name))
+			}
+		}
+	}
+	return extract, nil
+}
+
+// parseDelivery validates how a rendered document is delivered, defaulting to
+// the response body. Links are only available for output formats other than json.
+func parseDelivery(delivery string, outputFormat utils.OutputFormatType) (utils.DeliveryType, error) {
+	// if the delivery is not set, return the bytes
+	if delivery == "" {
+		delivery = string(utils.DeliveryBytes)
+	}
+
+	// validate the delivery
+	if !utils.IsValidDelivery(delivery) {
+		return "", errors.New("Invalid delivery")
+	}
+	if delivery == string(utils.DeliveryLink) && outputFormat == utils.OutputJSON {
+		return "", errors.New("delivery=link requires an output format other than json")
+	}
+
+	return utils.DeliveryType(delivery), nil
+}
+
+// readFormFile reads an uploaded file into memory
+func readFormFile(file *multipart.FileHeader) ([]byte, error) {
+	// Open the uploaded file
+	src, err := file.Open()
+	if err != nil {
+		return nil, errors.New("Failed to open uploaded file")
+	}
+	defer src.Close()
+
+	// Read the file data into memory
+	buffer := bytes.NewBuffer(nil)
+	if _, err := io.Copy(buffer, src); err != nil {
+		return nil, errors.New("Failed to read file data")
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// authorizeOCR checks that the organization is allowed to use OCR, writing the
+// error response and returning false when it is not
+func authorizeOCR(c *gin.Context, organizationID int64) (models.Organization, bool) {
+	return authorizeOCRPages(c, organizationID, 1)
+}
+
+// authorizeOCRPages checks that the organization is allowed to OCR the given
+// number of pages, writing the error response and returning false when it is not
+func authorizeOCRPages(c *gin.Context, organizationID int64, pages int) (models.Organization, bool) {
+	organization, err := db.GetOrganization(organizationID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse{Error: fmt.Sprintf("Failed to get organization: %v", err)})
+		return organization, false
+	}
+
+	canUseOCR, err := polar.CanUserUseOCRPages(c, organization, pages)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse{Error: fmt.Sprintf("Failed to check if user can use OCR: %v", err)})
+		return organization, false
+	}
+
+	if !canUseOCR {
+		c.JSON(http.StatusForbidden, utils.ErrPermissionDeniedResponse{Error: utils.ErrPermissionDenied.Error()})
+		return organization, false
+	}
+
+	return organization, true
 }
@@ -0,0 +1,281 @@
+package serviceApis
+
+import (
+	"fmt"
+	"net/http"
+	"serverless-tesseract/db"
+	"serverless-tesseract/models"
+	"serverless-tesseract/r2"
+	"serverless-tesseract/services/jobs"
+	"serverless-tesseract/utils"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateOCRJob godoc
+//
+//	@Summary		Create OCR Job
+//	@Description	Queue a file for asynchronous OCR. The job ID is returned immediately and the job can be polled for its status and result.
+//	@Tags			OCR Jobs
+//	@Accept			multipart/form-data
+//
+// @Param 			X-API-Key 		header 		string 	true 	"API Key"
+// @Param			file			formData	file				true	"File"
+// @Param			cache_policy	formData	string	false	"Cache Policy (options: cache_first, no_cache, cache_only)"
+// @Param			engine			formData	string	false	"OCR Engine (options: see /api/ocr/engines)"
+// @Param			raw				formData	bool	false	"Raw (options: true, false)"
+// @Param			text_layer		formData	string	false	"Use the text layer of born-digital PDFs instead of OCR (options: auto, only, never)"
+// @Param			pages			formData	string	false	"Pages to OCR, e.g. 1-3,7,10- (defaults to every page)"
+// @Param			languages		formData	string	false	"ISO 639-1 codes of the languages in the document, comma separated or repeated, the first is the primary language, or auto to detect them per page (defaults to en, installed languages: see /api/ocr/engines)"
+// @Param			format			formData	string	false	"Response format, structured returns a page, block, paragraph, line and word tree (options: flat, structured)"
+// @Param			extract			formData	string	false	"Extract structured data from the words, comma separated or repeated, key_values returns label and value pairs using the organization's label synonyms (options: key_values)"
+// @Param			tables			formData	bool	false	"Detect tables and return their rows and columns as JSON and CSV alongside the words (options: true, false)"
+// @Param			barcodes		formData	bool	false	"Decode the QR, Code 128, EAN, UPC, Data Matrix and PDF417 barcodes on every page and return their payload, symbology and bounding box (options: true, false)"
+// @Param			output_format	formData	string	false	"Output document format, searchable_pdf returns the original document with an invisible text layer (options: json, hocr, alto, pagexml, txt, tsv, searchable_pdf)"
+// @Param			preprocess		formData	string	false	"Image preprocessing steps to run before OCR, comma separated or all (options: orientation, upscale, grayscale, contrast, denoise, deskew, threshold)"
+// @Success		202			{object}	utils.OCRJobResponse
+// @Failure		400			{object}	utils.ErrorResponse
+// @Failure		403			{object}	utils.ErrPermissionDeniedResponse
+// @Failure		415			{object}	utils.ErrorResponse
+// @Failure		500			{object}	utils.ErrorResponse
+// @Router			/api/ocr/jobs [post]
+func CreateOCRJob(c *gin.Context) {
+	// get the file from the request
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse{Error: "Failed to get file"})
+		return
+	}
+
+	if file.Size > int64(utils.FILE_SIZE_LIMIT) {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse{Error: "File size exceeds limit: " + strconv.Itoa(utils.FILE_SIZE_LIMIT) + " bytes"})
+		return
+	}
+
+	organizationID := c.GetInt64("authed_organization_id")
+
+	if organizationID == 0 {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse{Error: "Organization ID is required"})
+		return
+	}
+
+	options, err := parseOCROptions(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, validationErrorResponse(err))
+		return
+	}
+
+	// check the token's scopes
+	scopes := c.GetStringSlice("authed_scopes")
+	if !utils.Contains(scopes, "SERVICE_OCR") {
+		c.JSON(http.StatusForbidden, utils.ErrPermissionDeniedResponse{Error: utils.ErrPermissionDenied.Error()})
+		return
+	}
+
+	fileBytes, err := readFormFile(file)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	fileType, ok := detectFileType(c, fileBytes)
+	if !ok {
+		return
+	}
+
+	// check if the user can use OCR
+	if _, ok := authorizeOCR(c, organizationID); !ok {
+		return
+	}
+
+	if !validatePages(c, fileBytes, options.Pages) {
+		return
+	}
+
+	// persist the upload so the job survives restarts
+	jobID := utils.GenerateID()
+	uploadKey := fmt.Sprintf("uploads/%d/%s%s", organizationID, jobID, fileType.Extension)
+	err = r2.UploadFile(uploadKey, fileBytes, fileType.MediaType)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse{Error: fmt.Sprintf("Failed to store file: %v", err)})
+		return
+	}
+
+	job, err := db.CreateOCRJob(
+		jobID,
+		organizationID,
+		file.Filename,
+		utils.GetSHA256Hash(fileBytes),
+		uploadKey,
+		options.Engine,
+		options.Raw,
+		options.CachePolicy,
+		string(options.TextLayer),
+		options.Preprocess.String(),
+		strings.Join(options.Languages, ","),
+		options.Tables,
+		options.Barcodes,
+		joinExtract(options.Extract),
+		string(options.Format),
+		string(options.OutputFormat),
+		fileType.MediaType,
+		options.Pages.String(),
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse{Error: fmt.Sprintf("Failed to create OCR job: %v", err)})
+		return
+	}
+
+	jobs.Notify()
+	c.JSON(http.StatusAccepted, jobResponse(job))
+}
+
+// GetOCRJob godoc
+//
+//	@Summary		Get OCR Job
+//	@Description	Get the status and progress of an OCR job
+//	@Tags			OCR Jobs
+//
+// @Param 			X-API-Key 		header 		string 	true 	"API Key"
+// @Param			id				path		string	true	"Job ID"
+// @Success		200			{object}	utils.OCRJobResponse
+// @Failure		403			{object}	utils.ErrPermissionDeniedResponse
+// @Failure		404			{object}	utils.ErrorResponse
+// @Failure		500			{object}	utils.ErrorResponse
+// @Router			/api/ocr/jobs/{id} [get]
+func GetOCRJob(c *gin.Context) {
+	job, ok := getAuthorizedJob(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, jobResponse(*job))
+}
+
+// GetOCRJobResult godoc
+//
+//	@Summary		Get OCR Job Result
+//	@Description	Get the OCR results of a completed job, in the output format the job was created with
+//	@Tags			OCR Jobs
+//	@Produce		json,html,xml,plain,text/tab-separated-values,application/zip,application/pdf
+//
+// @Param 			X-API-Key 		header 		string 	true 	"API Key"
+// @Param			id				path		string	true	"Job ID"
+// @Param			delivery		query		string	false	"How a document in an output format other than json is returned, link returns an expiring download link (options: bytes, link)"
+// @Success		200			{object}	utils.OCRResponseList	"utils.StructuredOCRResponse when the job was created with the structured format, utils.DownloadLinkResponse with delivery=link"
+// @Failure		400			{object}	utils.ErrorResponse
+// @Failure		403			{object}	utils.ErrPermissionDeniedResponse
+// @Failure		404			{object}	utils.ErrorResponse
+// @Failure		409			{object}	utils.ErrorResponse
+// @Failure		500			{object}	utils.ErrorResponse
+// @Router			/api/ocr/jobs/{id}/result [get]
+func GetOCRJobResult(c *gin.Context) {
+	job, ok := getAuthorizedJob(c)
+	if !ok {
+		return
+	}
+
+	if job.Status == utils.JobFailed {
+		c.JSON(http.StatusConflict, utils.ErrorResponse{Error: fmt.Sprintf("Job failed: %s", job.Error)})
+		return
+	}
+
+	if job.Status != utils.JobCompleted {
+		c.JSON(http.StatusConflict, utils.ErrorResponse{Error: fmt.Sprintf("Job is not completed, current status: %s", job.Status)})
+		return
+	}
+
+	delivery, err := parseDelivery(c.Query("delivery"), job.OutputFormat)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	results, err := r2.GetObject(job.ResultKey)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse{Error: fmt.Sprintf("Failed to get job result: %v", err)})
+		return
+	}
+
+	// rendered output formats are stored next to the job's results, searchable
+	// PDFs are rendered by the worker as the upload is deleted once the job is done
+	results.DocumentKey = job.ResultKey
+	options := ocrOptions{
+		Format:       job.Format,
+		OutputFormat: job.OutputFormat,
+		Delivery:     delivery,
+		Extract:      jobExtract(*job),
+	}
+	writeOCRResults(c, *results, options, nil, job.OrganizationID)
+}
+
+// joinExtract joins the extract options to be stored with a job
+func joinExtract(extract []utils.ExtractType) string {
+	names := make([]string, len(extract))
+	for i, name := range extract {
+		names[i] = string(name)
+	}
+	return strings.Join(names, ",")
+}
+
+// jobExtract splits the comma separated extract of the job
+func jobExtract(job models.OrganizationOCRJob) []utils.ExtractType {
+	extract := []utils.ExtractType{}
+	if job.Extract == "" {
+		return extract
+	}
+	for _, name := range strings.Split(job.Extract, ",") {
+		extract = append(extract, utils.ExtractType(name))
+	}
+	return extract
+}
+
+// getAuthorizedJob loads the job in the request path for the authed organization,
+// writing the error response and returning false when it cannot be returned
+func getAuthorizedJob(c *gin.Context) (*models.OrganizationOCRJob, bool) {
+	organizationID, ok := getAuthorizedOrganization(c)
+	if !ok {
+		return nil, false
+	}
+
+	job, err := db.GetOCRJob(c.Param("id"), organizationID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse{Error: fmt.Sprintf("Failed to get job: %v", err)})
+		return nil, false
+	}
+
+	if job == nil {
+		c.JSON(http.StatusNotFound, utils.ErrorResponse{Error: "Job not found"})
+		return nil, false
+	}
+
+	return job, true
+}
+
+func jobResponse(job models.OrganizationOCRJob) utils.OCRJobResponse {
+	return utils.OCRJobResponse{
+		ID:           job.ID,
+		Status:       job.Status,
+		Filename:     job.Filename,
+		FileHash:     job.FileHash,
+		Engine:       job.OCREngine,
+		Raw:          job.Raw,
+		TextLayer:    job.TextLayer,
+		Preprocess:   job.Preprocess,
+		Languages:    strings.Split(job.Languages, ","),
+		Tables:       job.Tables,
+		Barcodes:     job.Barcodes,
+		Extract:      jobExtract(job),
+		Format:       job.Format,
+		OutputFormat: job.OutputFormat,
+		Pages:        job.Pages,
+		PagesTotal:   job.PagesTotal,
+		PagesDone:    job.PagesDone,
+		Error:        job.Error,
+		CreatedAt:    job.CreatedAt,
+		StartedAt:    job.StartedAt,
+		CompletedAt:  job.CompletedAt,
+	}
+}
@@ -0,0 +1,222 @@
+package serviceApis
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"serverless-tesseract/services/source"
+	"serverless-tesseract/utils"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// jsonBodyLimit is the largest JSON body read, a base64 encoded file of
+// FILE_SIZE_LIMIT bytes and the other fields
+var jsonBodyLimit = int64(base64.StdEncoding.EncodedLen(utils.FILE_SIZE_LIMIT)) + 64*1024
+
+// OCRJSON godoc
+//
+//	@Summary		OCR JSON
+//	@Description	OCR a document sent as base64 in a JSON body, or read from a source_url or from the key of a bucket instead of being uploaded. The URL must be https on an allowed host and the bucket must be allowed. The other fields are the options of /api/ocr, with the same validation, and invalid fields are listed in the error's fields.
+//	@Tags			OCR
+//	@Accept			json
+//	@Produce		json,html,xml,plain,text/tab-separated-values,application/zip,application/pdf
+//
+// @Param 			X-API-Key 		header 		string 					true 	"API Key"
+// @Param			request			body		utils.OCRJSONRequest	true	"Document and OCR options"
+// @Success		200			{object}	utils.OCRResponseList	"utils.StructuredOCRResponse when format is structured"
+// @Failure		400			{object}	utils.ErrorResponse
+// @Failure		403			{object}	utils.ErrPermissionDeniedResponse
+// @Failure		415			{object}	utils.ErrorResponse
+// @Failure		500			{object}	utils.ErrorResponse
+// @Failure		502			{object}	utils.ErrorResponse
+// @Failure		504			{object}	utils.ErrorResponse
+// @Router			/api/ocr/json [post]
+func OCRJSON(c *gin.Context) {
+	organizationID, ok := getAuthorizedOrganization(c)
+	if !ok {
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, jsonBodyLimit)
+
+	var request utils.OCRJSONRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, bindErrorResponse(err))
+		return
+	}
+
+	// the document and the options are validated together so every invalid field is reported
+	invalid := &validationError{}
+	fileBytes := decodeJSONFile(request, invalid)
+	hasURL := request.SourceURL != ""
+	hasObject := request.Bucket != "" || request.Key != ""
+
+	if request.Bucket != "" && request.Key == "" {
+		invalid.add("key", errors.New("key is required with bucket"))
+	}
+	if request.Key != "" && request.Bucket == "" {
+		invalid.add("bucket", errors.New("bucket is required with key"))
+	}
+
+	switch sources := countTrue(request.File != "", hasURL, hasObject); {
+	case sources == 0:
+		invalid.add("file", errors.New("One of file, source_url or bucket and key is required"))
+	case sources > 1:
+		invalid.add("file", errors.New("Only one of file, source_url or bucket and key can be set"))
+	}
+
+	options, err := validateOCROptions(jsonOCRFields(request))
+	var optionsInvalid *validationError
+	if errors.As(err, &optionsInvalid) {
+		invalid.Fields = append(invalid.Fields, optionsInvalid.Fields...)
+	}
+
+	if len(invalid.Fields) > 0 {
+		c.JSON(http.StatusBadRequest, validationErrorResponse(invalid))
+		return
+	}
+
+	// check if the user can use OCR before downloading anything for them
+	organization, ok := authorizeOCR(c, organizationID)
+	if !ok {
+		return
+	}
+
+	filename := request.Filename
+	switch {
+	case hasURL:
+		fileBytes, filename, err = source.FetchURL(c.Request.Context(), request.SourceURL)
+	case hasObject:
+		fileBytes, filename, err = source.FetchObject(c.Request.Context(), request.Bucket, request.Key)
+	case filename == "":
+		filename = "document"
+	}
+	if err != nil {
+		writeSourceError(c, err)
+		return
+	}
+
+	fileType, ok := detectFileType(c, fileBytes)
+	if !ok {
+		return
+	}
+
+	if !validatePages(c, fileBytes, options.Pages) {
+		return
+	}
+
+	results, err := processOCR(c, ocrUpload{
+		Filename: filename,
+		Bytes:    fileBytes,
+		FileType: fileType,
+		Hash:     utils.GetSHA256Hash(fileBytes),
+	}, options, organization)
+	if err != nil {
+		writeOCRError(c, err)
+		return
+	}
+
+	writeOCRResults(c, results, options, fileBytes, organizationID)
+}
+
+// decodeJSONFile decodes the base64 file of a JSON body, an invalid or too large
+// file is added to the validation errors
+func decodeJSONFile(request utils.OCRJSONRequest, invalid *validationError) []byte {
+	if request.File == "" {
+		return nil
+	}
+
+	if base64.StdEncoding.DecodedLen(len(request.File)) > utils.FILE_SIZE_LIMIT+2 {
+		invalid.add("file", errors.New("File size exceeds limit: "+strconv.Itoa(utils.FILE_SIZE_LIMIT)+" bytes"))
+		return nil
+	}
+
+	fileBytes, err := base64.StdEncoding.DecodeString(request.File)
+	if err != nil {
+		invalid.add("file", fmt.Errorf("Invalid file, it must be base64 encoded: %v", err))
+		return nil
+	}
+	if len(fileBytes) > utils.FILE_SIZE_LIMIT {
+		invalid.add("file", errors.New("File size exceeds limit: "+strconv.Itoa(utils.FILE_SIZE_LIMIT)+" bytes"))
+		return nil
+	}
+
+	return fileBytes
+}
+
+// bindErrorResponse is the 400 response for a JSON body that could not be
+// decoded, naming the field when a value has the wrong type
+func bindErrorResponse(err error) utils.ErrorResponse {
+	var typeErr *json.UnmarshalTypeError
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		invalid := &validationError{}
+		invalid.add(typeErr.Field, fmt.Errorf("Invalid %s: expected %s, got %s", typeErr.Field, typeErr.Type, typeErr.Value))
+		return validationErrorResponse(invalid)
+	case errors.As(err, &maxBytesErr):
+		return utils.ErrorResponse{Error: "Request body exceeds limit: " + strconv.FormatInt(maxBytesErr.Limit, 10) + " bytes"}
+	default:
+		return utils.ErrorResponse{Error: fmt.Sprintf("Invalid request body: %v", err)}
+	}
+}
+
+// countTrue counts the values that are true
+func countTrue(values ...bool) int {
+	count := 0
+	for _, value := range values {
+		if value {
+			count++
+		}
+	}
+	return count
+}
+
+// jsonOCRFields converts the typed options of a JSON body to the fields
+// validateOCROptions reads, unset booleans stay unset so their defaults apply
+func jsonOCRFields(request utils.OCRJSONRequest) ocrFields {
+	formatBool := func(value *bool) string {
+		if value == nil {
+			return ""
+		}
+		return strconv.FormatBool(*value)
+	}
+
+	return ocrFields{
+		Engine:       request.Engine,
+		Raw:          formatBool(request.Raw),
+		CachePolicy:  request.CachePolicy,
+		TextLayer:    request.TextLayer,
+		Pages:        request.Pages,
+		Preprocess:   request.Preprocess,
+		Languages:    request.Languages,
+		Tables:       formatBool(request.Tables),
+		Barcodes:     formatBool(request.Barcodes),
+		Extract:      request.Extract,
+		Format:       request.Format,
+		OutputFormat: request.OutputFormat,
+		Delivery:     request.Delivery,
+	}
+}
+
+// writeSourceError writes the response for a document that could not be read
+// from its source, a source that is not allowed or too large is the client's
+// error and an unavailable source is a bad gateway
+func writeSourceError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, context.Canceled):
+		// the client went away, there is no one to respond to
+		writeOCRError(c, err)
+	case errors.Is(err, utils.ErrFileTooLarge):
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse{Error: "File size exceeds limit: " + strconv.Itoa(utils.FILE_SIZE_LIMIT) + " bytes"})
+	case errors.Is(err, utils.ErrSourceNotAllowed), errors.Is(err, utils.ErrAddressNotPublic):
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse{Error: fmt.Sprintf("Invalid source: %v", err)})
+	default:
+		c.JSON(http.StatusBadGateway, utils.ErrorResponse{Error: fmt.Sprintf("Failed to get file from source: %v", err)})
+	}
+}
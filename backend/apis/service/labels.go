@@ -0,0 +1,152 @@
+package serviceApis
+
+import (
+	"fmt"
+	"net/http"
+	"serverless-tesseract/db"
+	"serverless-tesseract/models"
+	"serverless-tesseract/utils"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UpsertKeyValueLabel godoc
+//
+//	@Summary		Set Key-Value Label
+//	@Description	Set the label synonyms of a key for extract=key_values. Labels matching a synonym are returned under the key, and the organization's synonyms take precedence over the built in ones. Setting a key that already exists replaces its synonyms.
+//	@Tags			Key-Value Labels
+//	@Accept			json
+//
+// @Param 			X-API-Key 		header 		string 	true 	"API Key"
+// @Param			label			body		utils.KeyValueLabelRequest	true	"Label"
+// @Success		200			{object}	utils.KeyValueLabelResponse
+// @Failure		400			{object}	utils.ErrorResponse
+// @Failure		403			{object}	utils.ErrPermissionDeniedResponse
+// @Failure		500			{object}	utils.ErrorResponse
+// @Router			/api/key-value-labels [post]
+func UpsertKeyValueLabel(c *gin.Context) {
+	organizationID, ok := getAuthorizedOrganization(c)
+	if !ok {
+		return
+	}
+
+	var request utils.KeyValueLabelRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse{Error: "Invalid label: key and synonyms are required"})
+		return
+	}
+
+	key := strings.TrimSpace(request.Key)
+	if key == "" {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse{Error: "Invalid label: key is required"})
+		return
+	}
+
+	synonyms := []string{}
+	for _, synonym := range request.Synonyms {
+		if synonym = strings.TrimSpace(synonym); synonym != "" {
+			synonyms = append(synonyms, synonym)
+		}
+	}
+	if len(synonyms) == 0 {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse{Error: "Invalid label: at least one synonym is required"})
+		return
+	}
+
+	label, err := db.UpsertKeyValueLabel(utils.GenerateID(), organizationID, key, synonyms)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse{Error: fmt.Sprintf("Failed to set key value label: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, keyValueLabelResponse(label))
+}
+
+// ListKeyValueLabels godoc
+//
+//	@Summary		List Key-Value Labels
+//	@Description	List the organization's label synonyms for extract=key_values
+//	@Tags			Key-Value Labels
+//
+// @Param 			X-API-Key 		header 		string 	true 	"API Key"
+// @Success		200			{array}		utils.KeyValueLabelResponse
+// @Failure		403			{object}	utils.ErrPermissionDeniedResponse
+// @Failure		500			{object}	utils.ErrorResponse
+// @Router			/api/key-value-labels [get]
+func ListKeyValueLabels(c *gin.Context) {
+	organizationID, ok := getAuthorizedOrganization(c)
+	if !ok {
+		return
+	}
+
+	labels, err := db.GetKeyValueLabels(organizationID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse{Error: fmt.Sprintf("Failed to get key value labels: %v", err)})
+		return
+	}
+
+	response := []utils.KeyValueLabelResponse{}
+	for _, label := range labels {
+		response = append(response, keyValueLabelResponse(label))
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// DeleteKeyValueLabel godoc
+//
+//	@Summary		Delete Key-Value Label
+//	@Description	Delete a key and its label synonyms
+//	@Tags			Key-Value Labels
+//
+// @Param 			X-API-Key 		header 		string 	true 	"API Key"
+// @Param			id				path		string	true	"Label ID"
+// @Success		204
+// @Failure		403			{object}	utils.ErrPermissionDeniedResponse
+// @Failure		404			{object}	utils.ErrorResponse
+// @Failure		500			{object}	utils.ErrorResponse
+// @Router			/api/key-value-labels/{id} [delete]
+func DeleteKeyValueLabel(c *gin.Context) {
+	organizationID, ok := getAuthorizedOrganization(c)
+	if !ok {
+		return
+	}
+
+	deleted, err := db.DeleteKeyValueLabel(c.Param("id"), organizationID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse{Error: fmt.Sprintf("Failed to delete key value label: %v", err)})
+		return
+	}
+
+	if !deleted {
+		c.JSON(http.StatusNotFound, utils.ErrorResponse{Error: "Key value label not found"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// labelSynonyms returns the organization's label synonyms by key
+func labelSynonyms(organizationID int64) (map[string][]string, error) {
+	labels, err := db.GetKeyValueLabels(organizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	synonyms := map[string][]string{}
+	for _, label := range labels {
+		synonyms[label.Key] = label.Synonyms
+	}
+	return synonyms, nil
+}
+
+func keyValueLabelResponse(label models.OrganizationKeyValueLabel) utils.KeyValueLabelResponse {
+	return utils.KeyValueLabelResponse{
+		ID:        label.ID,
+		Key:       label.Key,
+		Synonyms:  label.Synonyms,
+		CreatedAt: label.CreatedAt,
+		UpdatedAt: label.UpdatedAt,
+	}
+}
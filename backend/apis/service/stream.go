@@ -0,0 +1,270 @@
+package serviceApis
+
+import (
+	"encoding/json"
+	"net/http"
+	"serverless-tesseract/services"
+	"serverless-tesseract/utils"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ndjsonContentType is requested in the Accept header to stream newline
+// delimited JSON instead of server-sent events
+const ndjsonContentType = "application/x-ndjson"
+
+// streamEvent is a line of an NDJSON stream, server-sent events carry the
+// event name and data in their own fields
+type streamEvent struct {
+	Event string `json:"event"`
+	Data  any    `json:"data"`
+}
+
+// ocrStream writes the events of a streamed OCR request. The status and headers
+// are only written with the first event, so a request that fails before any
+// page is done still gets a regular error response.
+type ocrStream struct {
+	c       *gin.Context
+	ndjson  bool
+	started bool
+}
+
+func (s *ocrStream) send(event string, data any) {
+	if !s.started {
+		s.started = true
+		if s.ndjson {
+			s.c.Header("Content-Type", ndjsonContentType)
+		} else {
+			s.c.Header("Content-Type", "text/event-stream")
+		}
+		s.c.Header("Cache-Control", "no-cache")
+		// proxies must not hold the events back until the response is complete
+		s.c.Header("X-Accel-Buffering", "no")
+		s.c.Status(http.StatusOK)
+	}
+
+	if s.ndjson {
+		_ = json.NewEncoder(s.c.Writer).Encode(streamEvent{Event: event, Data: data})
+	} else {
+		s.c.SSEvent(event, data)
+	}
+	s.c.Writer.Flush()
+}
+
+// OCRStream godoc
+//
+//	@Summary		OCR Stream
+//	@Description	OCR a file and stream the results of each page as soon as it is done, as server-sent events or as newline delimited JSON when the Accept header is application/x-ndjson. Every page is a "page" event, pages are sent in the order they finish. A "summary" event with the totals, whether the results were cached and the ID of the recorded request ends the stream, or an "error" event when OCR fails after pages were sent. Cached results are sent page by page too.
+//	@Tags			OCR
+//	@Accept			multipart/form-data
+//	@Produce		text/event-stream,application/x-ndjson
+//
+// @Param 			X-API-Key 		header 		string 	true 	"API Key"
+// @Param			file			formData	file	true	"File"
+// @Param			cache_policy	formData	string	false	"Cache Policy (options: cache_first, no_cache, cache_only)"
+// @Param			engine			formData	string	false	"OCR Engine (options: see /api/ocr/engines)"
+// @Param			raw				formData	bool	false	"Raw (options: true, false)"
+// @Param			text_layer		formData	string	false	"Use the text layer of born-digital PDFs instead of OCR (options: auto, only, never)"
+// @Param			pages			formData	string	false	"Pages to OCR, e.g. 1-3,7,10- (defaults to every page)"
+// @Param			languages		formData	string	false	"ISO 639-1 codes of the languages in the document, comma separated or repeated, the first is the primary language, or auto to detect them per page (defaults to en, installed languages: see /api/ocr/engines)"
+// @Param			extract			formData	string	false	"Extract structured data from the words, comma separated or repeated, key_values are returned in the summary event (options: key_values)"
+// @Param			tables			formData	bool	false	"Detect tables and return their rows and columns as JSON and CSV alongside the words (options: true, false)"
+// @Param			barcodes		formData	bool	false	"Decode the QR, Code 128, EAN, UPC, Data Matrix and PDF417 barcodes on every page and return their payload, symbology and bounding box (options: true, false)"
+// @Param			preprocess		formData	string	false	"Image preprocessing steps to run before OCR, comma separated or all (options: orientation, upscale, grayscale, contrast, denoise, deskew, threshold)"
+// @Success		200			{object}	utils.OCRStreamPage		"page events, followed by a utils.OCRStreamSummary summary event"
+// @Failure		400			{object}	utils.ErrorResponse
+// @Failure		403			{object}	utils.ErrPermissionDeniedResponse
+// @Failure		415			{object}	utils.ErrorResponse
+// @Failure		500			{object}	utils.ErrorResponse
+// @Failure		504			{object}	utils.ErrorResponse
+// @Router			/api/ocr/stream [post]
+func OCRStream(c *gin.Context) {
+	organizationID, ok := getAuthorizedOrganization(c)
+	if !ok {
+		return
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse{Error: "Failed to get file"})
+		return
+	}
+
+	if file.Size > int64(utils.FILE_SIZE_LIMIT) {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse{Error: "File size exceeds limit: " + strconv.Itoa(utils.FILE_SIZE_LIMIT) + " bytes"})
+		return
+	}
+
+	options, err := parseOCROptions(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, validationErrorResponse(err))
+		return
+	}
+
+	// pages are streamed as flat lists of words
+	if options.Format != utils.FormatFlat || options.OutputFormat != utils.OutputJSON {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse{Error: "Streaming only supports format=flat and output_format=json"})
+		return
+	}
+
+	fileBytes, err := readFormFile(file)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	fileType, ok := detectFileType(c, fileBytes)
+	if !ok {
+		return
+	}
+
+	organization, ok := authorizeOCR(c, organizationID)
+	if !ok {
+		return
+	}
+
+	if !validatePages(c, fileBytes, options.Pages) {
+		return
+	}
+	pages, err := services.CountSelectedPages(fileBytes, options.Pages)
+	if err != nil {
+		pages = 1
+	}
+
+	// every page can be queued without waiting on the client, so a slow client
+	// never holds up OCR
+	type pageResult struct {
+		number  int
+		results utils.OCRResponseList
+	}
+	pageResults := make(chan pageResult, pages)
+	options.OnPageResult = func(pageNumber int, results utils.OCRResponseList) {
+		select {
+		case pageResults <- pageResult{number: pageNumber, results: results}:
+		default:
+		}
+	}
+
+	var results utils.OCRResponseList
+	go func() {
+		defer close(pageResults)
+		results, err = processOCR(c, ocrUpload{
+			Filename: file.Filename,
+			Bytes:    fileBytes,
+			FileType: fileType,
+			Hash:     utils.GetSHA256Hash(fileBytes),
+		}, options, organization)
+	}()
+
+	stream := &ocrStream{c: c, ndjson: strings.Contains(c.GetHeader("Accept"), ndjsonContentType)}
+	streamed := map[int]bool{}
+	for page := range pageResults {
+		streamed[page.number] = true
+		stream.send("page", streamPage(page.number, page.results))
+	}
+
+	if err != nil {
+		if !stream.started {
+			writeOCRError(c, err)
+			return
+		}
+		_, message := ocrErrorResponse(err)
+		stream.send("error", utils.ErrorResponse{Error: message})
+		return
+	}
+
+	// cached results and pages that could not be queued are sent from the whole document
+	for _, page := range splitPages(results) {
+		if !streamed[page.PageNumber] {
+			stream.send("page", page)
+		}
+	}
+
+	summary := utils.OCRStreamSummary{
+		RequestID:      results.RequestID,
+		Pages:          pages,
+		Words:          len(results.OCRResponses),
+		NumberOfTokens: results.NumberOfTokens,
+		Cached:         results.Cached,
+		Engine:         results.Engine,
+		Raw:            results.Raw,
+		Languages:      results.Languages,
+	}
+	if slices.Contains(options.Extract, utils.ExtractKeyValues) {
+		synonyms, err := labelSynonyms(organizationID)
+		if err != nil {
+			stream.send("error", utils.ErrorResponse{Error: "Failed to get key value labels: " + err.Error()})
+			return
+		}
+		summary.KeyValues = services.ExtractKeyValues(results, synonyms)
+	}
+	stream.send("summary", summary)
+}
+
+// streamPage is the page event for the results of a single page
+func streamPage(pageNumber int, results utils.OCRResponseList) utils.OCRStreamPage {
+	page := utils.OCRStreamPage{
+		PageNumber:   pageNumber,
+		OCRResponses: results.OCRResponses,
+		Tables:       results.Tables,
+		Barcodes:     results.Barcodes,
+	}
+	if page.OCRResponses == nil {
+		page.OCRResponses = []utils.OCRResponse{}
+	}
+	for i := range results.PageSizes {
+		if results.PageSizes[i].PageNumber == pageNumber {
+			page.PageSize = &results.PageSizes[i]
+		}
+	}
+	for i := range results.PageLanguages {
+		if results.PageLanguages[i].PageNumber == pageNumber {
+			page.PageLanguage = &results.PageLanguages[i]
+		}
+	}
+	return page
+}
+
+// splitPages splits the results of a document into the results of its pages,
+// in page order
+func splitPages(results utils.OCRResponseList) []utils.OCRStreamPage {
+	pages := map[int]*utils.OCRResponseList{}
+	page := func(pageNumber int) *utils.OCRResponseList {
+		if pages[pageNumber] == nil {
+			pages[pageNumber] = &utils.OCRResponseList{}
+		}
+		return pages[pageNumber]
+	}
+
+	for _, response := range results.OCRResponses {
+		page(response.PageNumber).OCRResponses = append(page(response.PageNumber).OCRResponses, response)
+	}
+	for _, size := range results.PageSizes {
+		page(size.PageNumber).PageSizes = append(page(size.PageNumber).PageSizes, size)
+	}
+	for _, language := range results.PageLanguages {
+		page(language.PageNumber).PageLanguages = append(page(language.PageNumber).PageLanguages, language)
+	}
+	for _, table := range results.Tables {
+		page(table.PageNumber).Tables = append(page(table.PageNumber).Tables, table)
+	}
+	for _, barcode := range results.Barcodes {
+		page(barcode.PageNumber).Barcodes = append(page(barcode.PageNumber).Barcodes, barcode)
+	}
+
+	pageNumbers := make([]int, 0, len(pages))
+	for pageNumber := range pages {
+		pageNumbers = append(pageNumbers, pageNumber)
+	}
+	sort.Ints(pageNumbers)
+
+	streamPages := make([]utils.OCRStreamPage, len(pageNumbers))
+	for i, pageNumber := range pageNumbers {
+		streamPages[i] = streamPage(pageNumber, *pages[pageNumber])
+	}
+	return streamPages
+}
@@ -0,0 +1,414 @@
+package serviceApis
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"serverless-tesseract/db"
+	"serverless-tesseract/models"
+	"serverless-tesseract/services"
+	"serverless-tesseract/services/engines"
+	"serverless-tesseract/utils"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UpsertOCRTemplate godoc
+//
+//	@Summary		Save OCR Template
+//	@Description	Save a template of named zones to OCR with /api/templates/{id}/ocr. Zones are rectangles in fractions of the page's width and height from its top left corner, and can set their own engine, languages, character whitelist and a regular expression their text has to match. Zones without an engine or languages use the template's. Saving a template with the name of an existing one replaces it.
+//	@Tags			OCR Templates
+//	@Accept			json
+//
+// @Param 			X-API-Key 		header 		string 	true 	"API Key"
+// @Param			template		body		utils.TemplateRequest	true	"Template"
+// @Success		200			{object}	utils.TemplateResponse
+// @Failure		400			{object}	utils.ErrorResponse
+// @Failure		403			{object}	utils.ErrPermissionDeniedResponse
+// @Failure		500			{object}	utils.ErrorResponse
+// @Router			/api/templates [post]
+func UpsertOCRTemplate(c *gin.Context) {
+	organizationID, ok := getAuthorizedOrganization(c)
+	if !ok {
+		return
+	}
+
+	var request utils.TemplateRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse{Error: "Invalid template: name and zones are required"})
+		return
+	}
+
+	request, err := validateTemplate(request)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse{Error: fmt.Sprintf("Invalid template: %v", err)})
+		return
+	}
+
+	template, err := db.UpsertOCRTemplate(
+		utils.GenerateID(),
+		organizationID,
+		request.Name,
+		request.Engine,
+		strings.Join(request.Languages, ","),
+		request.Zones,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse{Error: fmt.Sprintf("Failed to save OCR template: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, templateResponse(template))
+}
+
+// ListOCRTemplates godoc
+//
+//	@Summary		List OCR Templates
+//	@Description	List the organization's OCR templates
+//	@Tags			OCR Templates
+//
+// @Param 			X-API-Key 		header 		string 	true 	"API Key"
+// @Success		200			{array}		utils.TemplateResponse
+// @Failure		403			{object}	utils.ErrPermissionDeniedResponse
+// @Failure		500			{object}	utils.ErrorResponse
+// @Router			/api/templates [get]
+func ListOCRTemplates(c *gin.Context) {
+	organizationID, ok := getAuthorizedOrganization(c)
+	if !ok {
+		return
+	}
+
+	templates, err := db.GetOCRTemplates(organizationID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse{Error: fmt.Sprintf("Failed to get OCR templates: %v", err)})
+		return
+	}
+
+	response := []utils.TemplateResponse{}
+	for _, template := range templates {
+		response = append(response, templateResponse(template))
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetOCRTemplate godoc
+//
+//	@Summary		Get OCR Template
+//	@Description	Get one of the organization's OCR templates
+//	@Tags			OCR Templates
+//
+// @Param 			X-API-Key 		header 		string 	true 	"API Key"
+// @Param			id				path		string	true	"Template ID"
+// @Success		200			{object}	utils.TemplateResponse
+// @Failure		403			{object}	utils.ErrPermissionDeniedResponse
+// @Failure		404			{object}	utils.ErrorResponse
+// @Failure		500			{object}	utils.ErrorResponse
+// @Router			/api/templates/{id} [get]
+func GetOCRTemplate(c *gin.Context) {
+	organizationID, ok := getAuthorizedOrganization(c)
+	if !ok {
+		return
+	}
+
+	template, ok := getOrganizationTemplate(c, organizationID)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, templateResponse(*template))
+}
+
+// DeleteOCRTemplate godoc
+//
+//	@Summary		Delete OCR Template
+//	@Description	Delete one of the organization's OCR templates
+//	@Tags			OCR Templates
+//
+// @Param 			X-API-Key 		header 		string 	true 	"API Key"
+// @Param			id				path		string	true	"Template ID"
+// @Success		204
+// @Failure		403			{object}	utils.ErrPermissionDeniedResponse
+// @Failure		404			{object}	utils.ErrorResponse
+// @Failure		500			{object}	utils.ErrorResponse
+// @Router			/api/templates/{id} [delete]
+func DeleteOCRTemplate(c *gin.Context) {
+	organizationID, ok := getAuthorizedOrganization(c)
+	if !ok {
+		return
+	}
+
+	deleted, err := db.DeleteOCRTemplate(c.Param("id"), organizationID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse{Error: fmt.Sprintf("Failed to delete OCR template: %v", err)})
+		return
+	}
+
+	if !deleted {
+		c.JSON(http.StatusNotFound, utils.ErrorResponse{Error: "OCR template not found"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// OCRTemplate godoc
+//
+//	@Summary		OCR Template Zones
+//	@Description	OCR only the zones of a template and return the text of each zone by name. Only the pages the zones are on are processed and billed. A field is invalid when its text does not match the zone's pattern.
+//	@Tags			OCR Templates
+//	@Accept			multipart/form-data
+//
+// @Param 			X-API-Key 		header 		string 	true 	"API Key"
+// @Param			id				path		string	true	"Template ID"
+// @Param			file			formData	file	true	"File"
+// @Success		200			{object}	utils.TemplateOCRResponse
+// @Failure		400			{object}	utils.ErrorResponse
+// @Failure		403			{object}	utils.ErrPermissionDeniedResponse
+// @Failure		404			{object}	utils.ErrorResponse
+// @Failure		415			{object}	utils.ErrorResponse
+// @Failure		500			{object}	utils.ErrorResponse
+// @Failure		504			{object}	utils.ErrorResponse
+// @Router			/api/templates/{id}/ocr [post]
+func OCRTemplate(c *gin.Context) {
+	organizationID, ok := getAuthorizedOrganization(c)
+	if !ok {
+		return
+	}
+
+	template, ok := getOrganizationTemplate(c, organizationID)
+	if !ok {
+		return
+	}
+
+	// get the file from the request
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse{Error: "Failed to get file"})
+		return
+	}
+
+	if file.Size > int64(utils.FILE_SIZE_LIMIT) {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse{Error: "File size exceeds limit: " + strconv.Itoa(utils.FILE_SIZE_LIMIT) + " bytes"})
+		return
+	}
+
+	fileBytes, err := readFormFile(file)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	fileType, ok := detectFileType(c, fileBytes)
+	if !ok {
+		return
+	}
+
+	// check if the user can OCR every page the zones are on
+	zones := templateZones(*template)
+	zonePages := services.ZonePages(zones)
+	organization, ok := authorizeOCRPages(c, organizationID, len(zonePages))
+	if !ok {
+		return
+	}
+
+	if !validatePages(c, fileBytes, zonePages) {
+		return
+	}
+
+	fields, number_of_pages, err := services.OCRZones(c.Request.Context(), fileBytes, zones, services.DocumentOptions{
+		OrganizationID:  organizationID,
+		PageConcurrency: organization.PageConcurrency(),
+	})
+	if errors.Is(err, utils.ErrInvalidFileType) {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse{Error: fmt.Sprintf("Invalid file: %v", err)})
+		return
+	}
+
+	// zones are recorded as a request of the template's engine and languages,
+	// billed by the pages they are on and with a token per field
+	_, recordErr := db.CreateOCRRequest(
+		c,
+		number_of_pages,
+		false,
+		string(template.OCREngine),
+		organizationID,
+		file.Filename,
+		err == nil,
+		int64(len(fields)),
+		utils.GetSHA256Hash(fileBytes),
+		false,
+		string(utils.TextLayerNever),
+		"",
+		template.Languages,
+		false,
+		false,
+		fileType.MediaType,
+		nil,
+		nil,
+	)
+	if err != nil {
+		if recordErr != nil {
+			log.Printf("Failed to create OCR request: %v", recordErr)
+		}
+		writeOCRError(c, err)
+		return
+	}
+	if recordErr != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse{Error: fmt.Sprintf("Failed to create OCR request: %v", recordErr)})
+		return
+	}
+
+	response := utils.TemplateOCRResponse{
+		TemplateID: template.ID,
+		Template:   template.Name,
+		Fields:     fields,
+		Valid:      true,
+	}
+	for _, field := range fields {
+		response.Valid = response.Valid && field.Valid
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// validateTemplate checks the template's engine, languages and zones, returning
+// the template with defaults applied: the TESSERACT engine, the engine's default
+// language and page 1 for zones without a page
+func validateTemplate(request utils.TemplateRequest) (utils.TemplateRequest, error) {
+	request.Name = strings.TrimSpace(request.Name)
+	if request.Name == "" {
+		return request, errors.New("name is required")
+	}
+
+	if request.Engine == "" {
+		request.Engine = utils.EngineTesseract
+	}
+	languages, err := templateLanguages(request.Engine, request.Languages)
+	if err != nil {
+		return request, err
+	}
+	request.Languages = languages
+
+	if len(request.Zones) == 0 {
+		return request, errors.New("at least one zone is required")
+	}
+	if len(request.Zones) > utils.TEMPLATE_MAX_ZONES {
+		return request, fmt.Errorf("a template has at most %d zones", utils.TEMPLATE_MAX_ZONES)
+	}
+
+	names := map[string]bool{}
+	for i, zone := range request.Zones {
+		zone.Name = strings.TrimSpace(zone.Name)
+		if zone.Name == "" {
+			return request, fmt.Errorf("zone %d has no name", i+1)
+		}
+		if names[zone.Name] {
+			return request, fmt.Errorf("zone %s is defined twice", zone.Name)
+		}
+		names[zone.Name] = true
+
+		if zone.Page == 0 {
+			zone.Page = 1
+		}
+		if zone.Page < 0 {
+			return request, fmt.Errorf("zone %s has an invalid page", zone.Name)
+		}
+		if zone.X < 0 || zone.Y < 0 || zone.Width <= 0 || zone.Height <= 0 || zone.X+zone.Width > 1 || zone.Y+zone.Height > 1 {
+			return request, fmt.Errorf("zone %s must be within the page, x, y, width and height are fractions of the page between 0 and 1", zone.Name)
+		}
+
+		if zone.Engine != "" || len(zone.Languages) > 0 {
+			engine, requested := zone.Engine, zone.Languages
+			if engine == "" {
+				engine = request.Engine
+			}
+			// the template's languages have to be installed for the zone's engine too
+			if len(requested) == 0 {
+				requested = request.Languages
+			}
+			languages, err := templateLanguages(engine, requested)
+			if err != nil {
+				return request, fmt.Errorf("zone %s: %v", zone.Name, err)
+			}
+			zone.Languages = languages
+		}
+
+		if zone.Pattern != "" {
+			if _, err := regexp.Compile(zone.Pattern); err != nil {
+				return request, fmt.Errorf("zone %s has an invalid pattern: %v", zone.Name, err)
+			}
+		}
+
+		request.Zones[i] = zone
+	}
+
+	return request, nil
+}
+
+// templateLanguages validates the engine and resolves its languages, which
+// cannot be detected per zone
+func templateLanguages(engine utils.OCREngineType, requested []string) ([]string, error) {
+	if !utils.IsValidEngine(string(engine)) {
+		return nil, errors.New("invalid engine")
+	}
+	ocrEngine, _ := engines.Get(engine)
+
+	languages, err := engines.ResolveLanguages(ocrEngine, requested)
+	if err != nil {
+		return nil, fmt.Errorf("invalid languages: %v", err)
+	}
+	if len(languages) == 1 && languages[0] == engines.AutoLanguage {
+		return nil, errors.New("invalid languages: auto is not supported by templates")
+	}
+	return languages, nil
+}
+
+// getOrganizationTemplate returns the template of the id path parameter, writing
+// the error response and returning false when the organization has no such template
+func getOrganizationTemplate(c *gin.Context, organizationID int64) (*models.OrganizationOCRTemplate, bool) {
+	template, err := db.GetOCRTemplate(c.Param("id"), organizationID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse{Error: fmt.Sprintf("Failed to get OCR template: %v", err)})
+		return nil, false
+	}
+
+	if template == nil {
+		c.JSON(http.StatusNotFound, utils.ErrorResponse{Error: "OCR template not found"})
+		return nil, false
+	}
+
+	return template, true
+}
+
+// templateZones returns the template's zones with the template's engine and
+// languages set on the zones that do not have their own
+func templateZones(template models.OrganizationOCRTemplate) []utils.TemplateZone {
+	zones := make([]utils.TemplateZone, len(template.Zones))
+	for i, zone := range template.Zones {
+		if zone.Engine == "" {
+			zone.Engine = template.OCREngine
+		}
+		if len(zone.Languages) == 0 {
+			zone.Languages = strings.Split(template.Languages, ",")
+		}
+		zones[i] = zone
+	}
+	return zones
+}
+
+func templateResponse(template models.OrganizationOCRTemplate) utils.TemplateResponse {
+	return utils.TemplateResponse{
+		ID:        template.ID,
+		Name:      template.Name,
+		Engine:    template.OCREngine,
+		Languages: strings.Split(template.Languages, ","),
+		Zones:     template.Zones,
+		CreatedAt: template.CreatedAt,
+		UpdatedAt: template.UpdatedAt,
+	}
+}
@@ -0,0 +1,221 @@
+package serviceApis
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"serverless-tesseract/db"
+	"serverless-tesseract/models"
+	"serverless-tesseract/utils"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateWebhook godoc
+//
+//	@Summary		Create Webhook
+//	@Description	Register a URL that is sent a signed POST request whenever an OCR request completes or fails. The payload is signed with HMAC-SHA256 over "{X-Webhook-Timestamp}.{body}" and sent in the X-Webhook-Signature header. A secret is generated when one is not provided and is only returned in this response.
+//	@Tags			Webhooks
+//	@Accept			json
+//
+// @Param 			X-API-Key 		header 		string 	true 	"API Key"
+// @Param			webhook			body		utils.CreateWebhookRequest	true	"Webhook"
+// @Success		201			{object}	utils.WebhookResponse
+// @Failure		400			{object}	utils.ErrorResponse
+// @Failure		403			{object}	utils.ErrPermissionDeniedResponse
+// @Failure		500			{object}	utils.ErrorResponse
+// @Router			/api/webhooks [post]
+func CreateWebhook(c *gin.Context) {
+	organizationID, ok := getAuthorizedOrganization(c)
+	if !ok {
+		return
+	}
+
+	var request utils.CreateWebhookRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse{Error: "Invalid webhook: url is required"})
+		return
+	}
+
+	if !isValidWebhookURL(request.URL) {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse{Error: "Invalid webhook url"})
+		return
+	}
+
+	secret := request.Secret
+	if secret == "" {
+		secret = "whsec_" + utils.GenerateID()
+	}
+
+	webhook, err := db.CreateWebhook(utils.GenerateID(), organizationID, request.URL, secret)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse{Error: fmt.Sprintf("Failed to create webhook: %v", err)})
+		return
+	}
+
+	response := webhookResponse(webhook)
+	response.Secret = webhook.Secret
+	c.JSON(http.StatusCreated, response)
+}
+
+// ListWebhooks godoc
+//
+//	@Summary		List Webhooks
+//	@Description	List the organization's webhooks
+//	@Tags			Webhooks
+//
+// @Param 			X-API-Key 		header 		string 	true 	"API Key"
+// @Success		200			{array}		utils.WebhookResponse
+// @Failure		403			{object}	utils.ErrPermissionDeniedResponse
+// @Failure		500			{object}	utils.ErrorResponse
+// @Router			/api/webhooks [get]
+func ListWebhooks(c *gin.Context) {
+	organizationID, ok := getAuthorizedOrganization(c)
+	if !ok {
+		return
+	}
+
+	webhooks, err := db.GetWebhooks(organizationID, false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse{Error: fmt.Sprintf("Failed to get webhooks: %v", err)})
+		return
+	}
+
+	response := []utils.WebhookResponse{}
+	for _, webhook := range webhooks {
+		response = append(response, webhookResponse(webhook))
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// DeleteWebhook godoc
+//
+//	@Summary		Delete Webhook
+//	@Description	Delete a webhook and its delivery log
+//	@Tags			Webhooks
+//
+// @Param 			X-API-Key 		header 		string 	true 	"API Key"
+// @Param			id				path		string	true	"Webhook ID"
+// @Success		204
+// @Failure		403			{object}	utils.ErrPermissionDeniedResponse
+// @Failure		404			{object}	utils.ErrorResponse
+// @Failure		500			{object}	utils.ErrorResponse
+// @Router			/api/webhooks/{id} [delete]
+func DeleteWebhook(c *gin.Context) {
+	organizationID, ok := getAuthorizedOrganization(c)
+	if !ok {
+		return
+	}
+
+	deleted, err := db.DeleteWebhook(c.Param("id"), organizationID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse{Error: fmt.Sprintf("Failed to delete webhook: %v", err)})
+		return
+	}
+
+	if !deleted {
+		c.JSON(http.StatusNotFound, utils.ErrorResponse{Error: "Webhook not found"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ListWebhookDeliveries godoc
+//
+//	@Summary		List Webhook Deliveries
+//	@Description	List the 100 most recent deliveries of a webhook, including failed attempts
+//	@Tags			Webhooks
+//
+// @Param 			X-API-Key 		header 		string 	true 	"API Key"
+// @Param			id				path		string	true	"Webhook ID"
+// @Success		200			{array}		utils.WebhookDeliveryResponse
+// @Failure		403			{object}	utils.ErrPermissionDeniedResponse
+// @Failure		500			{object}	utils.ErrorResponse
+// @Router			/api/webhooks/{id}/deliveries [get]
+func ListWebhookDeliveries(c *gin.Context) {
+	organizationID, ok := getAuthorizedOrganization(c)
+	if !ok {
+		return
+	}
+
+	deliveries, err := db.GetWebhookDeliveries(c.Param("id"), organizationID, 100)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse{Error: fmt.Sprintf("Failed to get webhook deliveries: %v", err)})
+		return
+	}
+
+	response := []utils.WebhookDeliveryResponse{}
+	for _, delivery := range deliveries {
+		response = append(response, utils.WebhookDeliveryResponse{
+			ID:             delivery.ID,
+			Event:          delivery.Event,
+			Status:         delivery.Status,
+			Attempts:       delivery.Attempts,
+			ResponseStatus: delivery.ResponseStatus,
+			Error:          delivery.Error,
+			Payload:        delivery.Payload,
+			CreatedAt:      delivery.CreatedAt,
+			NextAttemptAt:  delivery.NextAttemptAt,
+			DeliveredAt:    delivery.DeliveredAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// getAuthorizedOrganization returns the authed organization when the token has
+// the OCR scope, writing the error response and returning false otherwise
+func getAuthorizedOrganization(c *gin.Context) (int64, bool) {
+	organizationID := c.GetInt64("authed_organization_id")
+
+	if organizationID == 0 {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse{Error: "Organization ID is required"})
+		return 0, false
+	}
+
+	// check the token's scopes
+	scopes := c.GetStringSlice("authed_scopes")
+	if !utils.Contains(scopes, "SERVICE_OCR") {
+		c.JSON(http.StatusForbidden, utils.ErrPermissionDeniedResponse{Error: utils.ErrPermissionDenied.Error()})
+		return 0, false
+	}
+
+	return organizationID, true
+}
+
+// isValidWebhookURL only accepts absolute https URLs that are not an internal
+// address, plain http and internal addresses are allowed in development. Names
+// are checked again for every delivery when they are resolved.
+func isValidWebhookURL(rawURL string) bool {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Host == "" {
+		return false
+	}
+
+	if os.Getenv("ENV") == "development" {
+		return parsed.Scheme == "http" || parsed.Scheme == "https"
+	}
+
+	if ip := net.ParseIP(parsed.Hostname()); ip != nil && !utils.IsPublicIP(ip) {
+		return false
+	}
+	if strings.EqualFold(parsed.Hostname(), "localhost") {
+		return false
+	}
+
+	return parsed.Scheme == "https"
+}
+
+func webhookResponse(webhook models.OrganizationWebhook) utils.WebhookResponse {
+	return utils.WebhookResponse{
+		ID:        webhook.ID,
+		URL:       webhook.URL,
+		Enabled:   webhook.Enabled,
+		CreatedAt: webhook.CreatedAt,
+	}
+}
@@ -1,6 +1,7 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
@@ -12,13 +13,20 @@ import (
 	"strings"
 	"time"
 
-	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
 )
 
 var DB *sql.DB
 
+var ocrRequestListeners []func(models.OrganizationOCRRequest)
+
+// OnOCRRequestRecorded registers a function that is called every time an OCR
+// request, successful or not, is recorded with CreateOCRRequest
+func OnOCRRequestRecorded(listener func(models.OrganizationOCRRequest)) {
+	ocrRequestListeners = append(ocrRequestListeners, listener)
+}
+
 // ConnectDatabase initializes the database connection
 func init() {
 	// Load environment variables from .env file if it exists
@@ -65,7 +73,7 @@ func init() {
 }
 
 func CreateOCRRequest(
-	c *gin.Context,
+	ctx context.Context,
 	num_of_pages int32,
 	cache_hit bool,
 	ocr_engine string,
@@ -75,8 +83,15 @@ func CreateOCRRequest(
 	token_count int64,
 	file_hash string,
 	raw bool,
+	text_layer string,
+	preprocess string,
+	languages string,
+	tables bool,
+	barcodes bool,
+	mime_type string,
 	// optional
 	cache_hash_id *string,
+	job_id *string,
 ) (models.OrganizationOCRRequest, error) {
 	// insert into organization_ocr_request table
 	insertQuery := `
@@ -91,14 +106,21 @@ func CreateOCRRequest(
 			"tokenCount",
 			"fileHash",
 			"cacheFileHash",
-			"raw"
+			"raw",
+			"jobId",
+			"textLayer",
+			"preprocess",
+			"languages",
+			"tables",
+			"barcodes",
+			"mimeType"
 		) 
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)
 		RETURNING id
 	`
 
 	var id int64
-	err := DB.QueryRow(insertQuery, time.Now(), cache_hit, num_of_pages, ocr_engine, organizationId, filename, success, token_count, file_hash, cache_hash_id, raw).Scan(&id)
+	err := DB.QueryRow(insertQuery, time.Now(), cache_hit, num_of_pages, ocr_engine, organizationId, filename, success, token_count, file_hash, cache_hash_id, raw, job_id, text_layer, preprocess, languages, tables, barcodes, mime_type).Scan(&id)
 	if err != nil {
 		return models.OrganizationOCRRequest{}, fmt.Errorf("failed to insert into organization_ocr_request: %w", err)
 	}
@@ -110,7 +132,7 @@ func CreateOCRRequest(
 			log.Printf("Failed to get organization polar customer ID: %v", err)
 			return models.OrganizationOCRRequest{}, fmt.Errorf("failed to get organization polar customer ID: %w", err)
 		}
-		polar.IngestMeter(c, polarCustomerId, num_of_pages)
+		polar.IngestMeter(ctx, polarCustomerId, num_of_pages)
 	}
 
 	cache_hash_id_or_nil := ""
@@ -118,7 +140,12 @@ func CreateOCRRequest(
 		cache_hash_id_or_nil = *cache_hash_id
 	}
 
-	return models.OrganizationOCRRequest{
+	job_id_or_nil := ""
+	if job_id != nil {
+		job_id_or_nil = *job_id
+	}
+
+	request := models.OrganizationOCRRequest{
 		ID:             id,
 		CreatedAt:      time.Now(),
 		CacheHit:       cache_hit,
@@ -131,12 +158,25 @@ func CreateOCRRequest(
 		FileHash:       file_hash,
 		CacheHash:      cache_hash_id_or_nil,
 		Raw:            raw,
-	}, nil
+		TextLayer:      utils.TextLayerModeType(text_layer),
+		Preprocess:     preprocess,
+		Languages:      languages,
+		Tables:         tables,
+		Barcodes:       barcodes,
+		MimeType:       mime_type,
+		JobID:          job_id_or_nil,
+	}
+
+	for _, listener := range ocrRequestListeners {
+		listener(request)
+	}
+
+	return request, nil
 }
 
 func GetOrganization(organizationId int64) (models.Organization, error) {
 	query := `
-		SELECT id, name, email, "polarCustomerId" FROM organization WHERE id = $1
+		SELECT id, name, email, "polarCustomerId", "ocrPageConcurrency" FROM organization WHERE id = $1
 	`
 
 	var organization models.Organization
@@ -145,6 +185,7 @@ func GetOrganization(organizationId int64) (models.Organization, error) {
 		&organization.Name,
 		&organization.Email,
 		&organization.PolarCustomerId,
+		&organization.OCRPageConcurrency,
 	)
 	if err != nil {
 		return models.Organization{}, fmt.Errorf("failed to get organization: %w", err)
@@ -153,12 +194,12 @@ func GetOrganization(organizationId int64) (models.Organization, error) {
 	return organization, nil
 }
 
-func GetFileHashCache(hash string, organizationId int64, raw bool, engine string) (results *utils.OCRResponseList, err error) {
-	// find unique cache result based off raw, organizationId, and hash
+func GetFileHashCache(hash string, organizationId int64, raw bool, engine string, text_layer string, preprocess string, languages string, tables bool, barcodes bool) (results *utils.OCRResponseList, err error) {
+	// find unique cache result based off raw, organizationId, text layer mode, preprocessing steps, languages, table and barcode detection, and hash
 	query := `
 		SELECT "documentKey", "ocrEngine", raw
 		FROM organization_file_cache 
-		WHERE hash = $1 AND "organizationId" = $2 AND raw = $3 AND "ocrEngine" = $4
+		WHERE hash = $1 AND "organizationId" = $2 AND raw = $3 AND "ocrEngine" = $4 AND "textLayer" = $5 AND preprocess = $6 AND languages = $7 AND tables = $8 AND barcodes = $9
 		ORDER BY "createdAt" DESC
 		LIMIT 1
 	`
@@ -167,7 +208,7 @@ func GetFileHashCache(hash string, organizationId int64, raw bool, engine string
 	var ocrEngine string
 	var rawValue bool
 
-	err = DB.QueryRow(query, hash, organizationId, raw, engine).Scan(&documentKey, &ocrEngine, &rawValue)
+	err = DB.QueryRow(query, hash, organizationId, raw, engine, text_layer, preprocess, languages, tables, barcodes).Scan(&documentKey, &ocrEngine, &rawValue)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -184,6 +225,7 @@ func GetFileHashCache(hash string, organizationId int64, raw bool, engine string
 		log.Printf("failed to get object from r2: %v", err)
 		return nil, nil
 	}
+	ocrResponseList.DocumentKey = documentKey
 
 	return ocrResponseList, nil
 }
@@ -235,13 +277,19 @@ func UpdateOrganizationPolarCustomerId(organizationId int64, polarCustomerId str
 	return nil
 }
 
+// SaveFileHashCache stores the results in R2 and returns the key they were stored at
 func SaveFileHashCache(
 	hash string,
 	results utils.OCRResponseList,
 	organizationId int64,
 	engine string,
 	raw bool,
-) error {
+	text_layer string,
+	preprocess string,
+	languages string,
+	tables bool,
+	barcodes bool,
+) (string, error) {
 	document_key := fmt.Sprintf("%d-%s-%s-%d.json", organizationId, engine, hash, time.Now().Unix())
 
 	query := `
@@ -251,10 +299,15 @@ func SaveFileHashCache(
 			"createdAt", 
 			"ocrEngine",
 			"organizationId",
-			"raw"
+			"raw",
+			"textLayer",
+			preprocess,
+			languages,
+			tables,
+			barcodes
 		)
-		VALUES ($1, $2, $3, $4, $5, $6)
-		ON CONFLICT (hash, "organizationId", raw, "ocrEngine")
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		ON CONFLICT (hash, "organizationId", raw, "ocrEngine", "textLayer", preprocess, languages, tables, barcodes)
 		DO UPDATE SET
 			"documentKey" = $2,
 			"createdAt" = $3,
@@ -262,32 +315,32 @@ func SaveFileHashCache(
 			"raw" = $6
 	`
 
-	_, err := DB.Exec(query, hash, document_key, time.Now(), engine, organizationId, raw)
+	_, err := DB.Exec(query, hash, document_key, time.Now(), engine, organizationId, raw, text_layer, preprocess, languages, tables, barcodes)
 	if err != nil {
-		return fmt.Errorf("failed to save file hash cache: %w", err)
+		return "", fmt.Errorf("failed to save file hash cache: %w", err)
 	}
 
 	err = r2.UploadObject(document_key, results)
 	if err != nil {
 		// delete the cache from the db
-		err = DeleteFileHashCache(hash, organizationId, raw, engine)
+		err = DeleteFileHashCache(hash, organizationId, raw, engine, text_layer, preprocess, languages, tables, barcodes)
 		if err != nil {
 			log.Printf("failed to delete cache: %s", err)
-			return fmt.Errorf("failed to delete cache: %w", err)
+			return "", fmt.Errorf("failed to delete cache: %w", err)
 		}
-		return fmt.Errorf("failed to upload object to r2: %w", err)
+		return "", fmt.Errorf("failed to upload object to r2: %w", err)
 	}
 
-	return nil
+	return document_key, nil
 }
 
-func DeleteFileHashCache(hash string, organizationId int64, raw bool, engine string) error {
+func DeleteFileHashCache(hash string, organizationId int64, raw bool, engine string, text_layer string, preprocess string, languages string, tables bool, barcodes bool) error {
 	query := `
 		DELETE FROM organization_file_cache
-		WHERE hash = $1 AND "organizationId" = $2 AND raw = $3 AND "ocrEngine" = $4
+		WHERE hash = $1 AND "organizationId" = $2 AND raw = $3 AND "ocrEngine" = $4 AND "textLayer" = $5 AND preprocess = $6 AND languages = $7 AND tables = $8 AND barcodes = $9
 	`
 
-	_, err := DB.Exec(query, hash, organizationId, raw, engine)
+	_, err := DB.Exec(query, hash, organizationId, raw, engine, text_layer, preprocess, languages, tables, barcodes)
 	if err != nil {
 		return fmt.Errorf("failed to delete file hash cache: %w", err)
 	}
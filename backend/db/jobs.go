@@ -0,0 +1,273 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"serverless-tesseract/models"
+	"serverless-tesseract/utils"
+	"time"
+)
+
+const ocrJobColumns = `
+	id,
+	"organizationId",
+	status,
+	filename,
+	"fileHash",
+	"uploadKey",
+	COALESCE("resultKey", ''),
+	"ocrEngine",
+	raw,
+	"cachePolicy",
+	"textLayer",
+	preprocess,
+	languages,
+	tables,
+	barcodes,
+	extract,
+	format,
+	"outputFormat",
+	COALESCE("mimeType", ''),
+	pages,
+	"pagesTotal",
+	"pagesDone",
+	COALESCE(error, ''),
+	"createdAt",
+	"updatedAt",
+	"startedAt",
+	"completedAt",
+	COALESCE("claimToken", '')
+`
+
+func scanOCRJob(row *sql.Row) (models.OrganizationOCRJob, error) {
+	var job models.OrganizationOCRJob
+	err := row.Scan(
+		&job.ID,
+		&job.OrganizationID,
+		&job.Status,
+		&job.Filename,
+		&job.FileHash,
+		&job.UploadKey,
+		&job.ResultKey,
+		&job.OCREngine,
+		&job.Raw,
+		&job.CachePolicy,
+		&job.TextLayer,
+		&job.Preprocess,
+		&job.Languages,
+		&job.Tables,
+		&job.Barcodes,
+		&job.Extract,
+		&job.Format,
+		&job.OutputFormat,
+		&job.MimeType,
+		&job.Pages,
+		&job.PagesTotal,
+		&job.PagesDone,
+		&job.Error,
+		&job.CreatedAt,
+		&job.UpdatedAt,
+		&job.StartedAt,
+		&job.CompletedAt,
+		&job.ClaimToken,
+	)
+	return job, err
+}
+
+func CreateOCRJob(
+	id string,
+	organizationId int64,
+	filename string,
+	file_hash string,
+	upload_key string,
+	ocr_engine string,
+	raw bool,
+	cache_policy string,
+	text_layer string,
+	preprocess string,
+	languages string,
+	tables bool,
+	barcodes bool,
+	extract string,
+	format string,
+	output_format string,
+	mime_type string,
+	pages string,
+) (models.OrganizationOCRJob, error) {
+	query := `
+		INSERT INTO organization_ocr_job (
+			id,
+			"organizationId",
+			status,
+			filename,
+			"fileHash",
+			"uploadKey",
+			"ocrEngine",
+			raw,
+			"cachePolicy",
+			"textLayer",
+			preprocess,
+			languages,
+			tables,
+			barcodes,
+			extract,
+			format,
+			"outputFormat",
+			"mimeType",
+			pages,
+			"createdAt",
+			"updatedAt"
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $20)
+		RETURNING ` + ocrJobColumns
+
+	job, err := scanOCRJob(DB.QueryRow(query, id, organizationId, utils.JobQueued, filename, file_hash, upload_key, ocr_engine, raw, cache_policy, text_layer, preprocess, languages, tables, barcodes, extract, format, output_format, mime_type, pages, time.Now()))
+	if err != nil {
+		return models.OrganizationOCRJob{}, fmt.Errorf("failed to insert into organization_ocr_job: %w", err)
+	}
+
+	return job, nil
+}
+
+// GetOCRJob returns the job with the given id if it belongs to the organization,
+// nil is returned when no such job exists
+func GetOCRJob(id string, organizationId int64) (*models.OrganizationOCRJob, error) {
+	query := `SELECT ` + ocrJobColumns + ` FROM organization_ocr_job WHERE id = $1 AND "organizationId" = $2`
+
+	job, err := scanOCRJob(DB.QueryRow(query, id, organizationId))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get OCR job: %w", err)
+	}
+
+	return &job, nil
+}
+
+// ClaimNextOCRJob marks the oldest queued job as processing and returns it.
+// SKIP LOCKED allows several workers, or several instances of the service,
+// to claim jobs concurrently without handing out the same job twice. The job's
+// claim token identifies the claim, updates made with another token, such as
+// by a worker whose job was requeued, are refused.
+func ClaimNextOCRJob() (*models.OrganizationOCRJob, error) {
+	query := `
+		UPDATE organization_ocr_job
+		SET status = $1, "startedAt" = $2, "updatedAt" = $2, "claimToken" = $4
+		WHERE id = (
+			SELECT id
+			FROM organization_ocr_job
+			WHERE status = $3
+			ORDER BY "createdAt"
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + ocrJobColumns
+
+	job, err := scanOCRJob(DB.QueryRow(query, utils.JobProcessing, time.Now(), utils.JobQueued, utils.GenerateID()))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to claim OCR job: %w", err)
+	}
+
+	return &job, nil
+}
+
+// HeartbeatOCRJob keeps a job that is still being processed from being requeued
+// as stale. It returns utils.ErrJobClaimLost when the job is no longer claimed
+// with the token.
+func HeartbeatOCRJob(id string, claim_token string) error {
+	query := `
+		UPDATE organization_ocr_job
+		SET "updatedAt" = $1
+		WHERE id = $2 AND "claimToken" = $3 AND status = $4
+	`
+
+	result, err := DB.Exec(query, time.Now(), id, claim_token, utils.JobProcessing)
+	if err != nil {
+		return fmt.Errorf("failed to heartbeat OCR job: %w", err)
+	}
+
+	return claimedRow(result)
+}
+
+func UpdateOCRJobProgress(id string, claim_token string, pages_done int32, pages_total int32) error {
+	query := `
+		UPDATE organization_ocr_job
+		SET "pagesDone" = $1, "pagesTotal" = $2, "updatedAt" = $3
+		WHERE id = $4 AND "claimToken" = $5
+	`
+
+	_, err := DB.Exec(query, pages_done, pages_total, time.Now(), id, claim_token)
+	if err != nil {
+		return fmt.Errorf("failed to update OCR job progress: %w", err)
+	}
+
+	return nil
+}
+
+// CompleteOCRJob stores the result of a job, it returns utils.ErrJobClaimLost
+// when the job is no longer claimed with the token
+func CompleteOCRJob(id string, claim_token string, result_key string, pages_done int32) error {
+	query := `
+		UPDATE organization_ocr_job
+		SET status = $1, "resultKey" = $2, "pagesDone" = $3, "pagesTotal" = GREATEST("pagesTotal", $3), "updatedAt" = $4, "completedAt" = $4
+		WHERE id = $5 AND "claimToken" = $6 AND status = $7
+	`
+
+	result, err := DB.Exec(query, utils.JobCompleted, result_key, pages_done, time.Now(), id, claim_token, utils.JobProcessing)
+	if err != nil {
+		return fmt.Errorf("failed to complete OCR job: %w", err)
+	}
+
+	return claimedRow(result)
+}
+
+// FailOCRJob records why a job failed, it returns utils.ErrJobClaimLost when
+// the job is no longer claimed with the token
+func FailOCRJob(id string, claim_token string, job_error string, pages_done int32) error {
+	query := `
+		UPDATE organization_ocr_job
+		SET status = $1, error = $2, "pagesDone" = $3, "updatedAt" = $4, "completedAt" = $4
+		WHERE id = $5 AND "claimToken" = $6 AND status = $7
+	`
+
+	result, err := DB.Exec(query, utils.JobFailed, job_error, pages_done, time.Now(), id, claim_token, utils.JobProcessing)
+	if err != nil {
+		return fmt.Errorf("failed to fail OCR job: %w", err)
+	}
+
+	return claimedRow(result)
+}
+
+// claimedRow returns utils.ErrJobClaimLost when an update made with a claim
+// token matched no job
+func claimedRow(result sql.Result) error {
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to read updated OCR jobs: %w", err)
+	}
+	if rows == 0 {
+		return utils.ErrJobClaimLost
+	}
+	return nil
+}
+
+// RequeueStaleOCRJobs puts processing jobs that have not been updated within
+// the timeout back in the queue, returning the number of jobs requeued
+func RequeueStaleOCRJobs(timeout time.Duration) (int64, error) {
+	query := `
+		UPDATE organization_ocr_job
+		SET status = $1, "pagesDone" = 0, "updatedAt" = $2, "claimToken" = NULL
+		WHERE status = $3 AND "updatedAt" < $4
+	`
+
+	result, err := DB.Exec(query, utils.JobQueued, time.Now(), utils.JobProcessing, time.Now().Add(-timeout))
+	if err != nil {
+		return 0, fmt.Errorf("failed to requeue stale OCR jobs: %w", err)
+	}
+
+	return result.RowsAffected()
+}
@@ -0,0 +1,101 @@
+package db
+
+import (
+	"fmt"
+	"serverless-tesseract/models"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// UpsertKeyValueLabel sets the synonyms of one of an organization's label keys,
+// replacing the synonyms the key had before
+func UpsertKeyValueLabel(id string, organizationId int64, key string, synonyms []string) (models.OrganizationKeyValueLabel, error) {
+	query := `
+		INSERT INTO organization_key_value_label (
+			id,
+			"organizationId",
+			key,
+			synonyms,
+			"createdAt",
+			"updatedAt"
+		)
+		VALUES ($1, $2, $3, $4, $5, $5)
+		ON CONFLICT ("organizationId", key)
+		DO UPDATE SET
+			synonyms = $4,
+			"updatedAt" = $5
+		RETURNING id, "organizationId", key, synonyms, "createdAt", "updatedAt"
+	`
+
+	var label models.OrganizationKeyValueLabel
+	err := DB.QueryRow(query, id, organizationId, key, pq.Array(synonyms), time.Now()).Scan(
+		&label.ID,
+		&label.OrganizationID,
+		&label.Key,
+		pq.Array(&label.Synonyms),
+		&label.CreatedAt,
+		&label.UpdatedAt,
+	)
+	if err != nil {
+		return models.OrganizationKeyValueLabel{}, fmt.Errorf("failed to upsert organization_key_value_label: %w", err)
+	}
+
+	return label, nil
+}
+
+// GetKeyValueLabels returns the label synonyms of an organization
+func GetKeyValueLabels(organizationId int64) ([]models.OrganizationKeyValueLabel, error) {
+	query := `
+		SELECT id, "organizationId", key, synonyms, "createdAt", "updatedAt"
+		FROM organization_key_value_label
+		WHERE "organizationId" = $1
+		ORDER BY key
+	`
+
+	rows, err := DB.Query(query, organizationId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get key value labels: %w", err)
+	}
+	defer rows.Close()
+
+	labels := []models.OrganizationKeyValueLabel{}
+	for rows.Next() {
+		var label models.OrganizationKeyValueLabel
+		err := rows.Scan(
+			&label.ID,
+			&label.OrganizationID,
+			&label.Key,
+			pq.Array(&label.Synonyms),
+			&label.CreatedAt,
+			&label.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan key value label: %w", err)
+		}
+		labels = append(labels, label)
+	}
+
+	return labels, rows.Err()
+}
+
+// DeleteKeyValueLabel deletes one of an organization's label keys, returning
+// false when the label does not exist
+func DeleteKeyValueLabel(id string, organizationId int64) (bool, error) {
+	query := `
+		DELETE FROM organization_key_value_label
+		WHERE id = $1 AND "organizationId" = $2
+	`
+
+	result, err := DB.Exec(query, id, organizationId)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete key value label: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete key value label: %w", err)
+	}
+
+	return deleted > 0, nil
+}
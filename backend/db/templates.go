@@ -0,0 +1,141 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"serverless-tesseract/models"
+	"serverless-tesseract/utils"
+	"time"
+)
+
+const templateColumns = `id, "organizationId", name, "ocrEngine", languages, zones, "createdAt", "updatedAt"`
+
+// UpsertOCRTemplate saves one of an organization's OCR templates, replacing the
+// engine, languages and zones of the template with the same name
+func UpsertOCRTemplate(id string, organizationId int64, name string, ocrEngine utils.OCREngineType, languages string, zones []utils.TemplateZone) (models.OrganizationOCRTemplate, error) {
+	zonesJSON, err := json.Marshal(zones)
+	if err != nil {
+		return models.OrganizationOCRTemplate{}, fmt.Errorf("failed to marshal template zones: %w", err)
+	}
+
+	query := `
+		INSERT INTO organization_ocr_template (
+			id,
+			"organizationId",
+			name,
+			"ocrEngine",
+			languages,
+			zones,
+			"createdAt",
+			"updatedAt"
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $7)
+		ON CONFLICT ("organizationId", name)
+		DO UPDATE SET
+			"ocrEngine" = $4,
+			languages = $5,
+			zones = $6,
+			"updatedAt" = $7
+		RETURNING ` + templateColumns
+
+	template, err := scanOCRTemplate(DB.QueryRow(query, id, organizationId, name, ocrEngine, languages, zonesJSON, time.Now()))
+	if err != nil {
+		return models.OrganizationOCRTemplate{}, fmt.Errorf("failed to upsert organization_ocr_template: %w", err)
+	}
+
+	return template, nil
+}
+
+// GetOCRTemplate returns one of an organization's OCR templates, or nil when it does not exist
+func GetOCRTemplate(id string, organizationId int64) (*models.OrganizationOCRTemplate, error) {
+	query := `
+		SELECT ` + templateColumns + `
+		FROM organization_ocr_template
+		WHERE id = $1 AND "organizationId" = $2
+	`
+
+	template, err := scanOCRTemplate(DB.QueryRow(query, id, organizationId))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get OCR template: %w", err)
+	}
+
+	return &template, nil
+}
+
+// GetOCRTemplates returns the OCR templates of an organization
+func GetOCRTemplates(organizationId int64) ([]models.OrganizationOCRTemplate, error) {
+	query := `
+		SELECT ` + templateColumns + `
+		FROM organization_ocr_template
+		WHERE "organizationId" = $1
+		ORDER BY name
+	`
+
+	rows, err := DB.Query(query, organizationId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get OCR templates: %w", err)
+	}
+	defer rows.Close()
+
+	templates := []models.OrganizationOCRTemplate{}
+	for rows.Next() {
+		template, err := scanOCRTemplate(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan OCR template: %w", err)
+		}
+		templates = append(templates, template)
+	}
+
+	return templates, rows.Err()
+}
+
+// DeleteOCRTemplate deletes one of an organization's OCR templates, returning
+// false when the template does not exist
+func DeleteOCRTemplate(id string, organizationId int64) (bool, error) {
+	query := `
+		DELETE FROM organization_ocr_template
+		WHERE id = $1 AND "organizationId" = $2
+	`
+
+	result, err := DB.Exec(query, id, organizationId)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete OCR template: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete OCR template: %w", err)
+	}
+
+	return deleted > 0, nil
+}
+
+// scanOCRTemplate scans a row of templateColumns, unmarshalling the zones. row is
+// a *sql.Row or *sql.Rows.
+func scanOCRTemplate(row interface{ Scan(dest ...any) error }) (models.OrganizationOCRTemplate, error) {
+	var template models.OrganizationOCRTemplate
+	var zonesJSON []byte
+	err := row.Scan(
+		&template.ID,
+		&template.OrganizationID,
+		&template.Name,
+		&template.OCREngine,
+		&template.Languages,
+		&zonesJSON,
+		&template.CreatedAt,
+		&template.UpdatedAt,
+	)
+	if err != nil {
+		return models.OrganizationOCRTemplate{}, err
+	}
+
+	if err := json.Unmarshal(zonesJSON, &template.Zones); err != nil {
+		return models.OrganizationOCRTemplate{}, fmt.Errorf("failed to unmarshal template zones: %w", err)
+	}
+
+	return template, nil
+}
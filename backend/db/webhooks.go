@@ -0,0 +1,262 @@
+package db
+
+import (
+	"fmt"
+	"serverless-tesseract/models"
+	"serverless-tesseract/utils"
+	"time"
+)
+
+func CreateWebhook(id string, organizationId int64, url string, secret string) (models.OrganizationWebhook, error) {
+	query := `
+		INSERT INTO organization_webhook (
+			id,
+			"organizationId",
+			url,
+			secret,
+			enabled,
+			"createdAt",
+			"updatedAt"
+		)
+		VALUES ($1, $2, $3, $4, true, $5, $5)
+	`
+
+	now := time.Now()
+	_, err := DB.Exec(query, id, organizationId, url, secret, now)
+	if err != nil {
+		return models.OrganizationWebhook{}, fmt.Errorf("failed to insert into organization_webhook: %w", err)
+	}
+
+	return models.OrganizationWebhook{
+		ID:             id,
+		OrganizationID: organizationId,
+		URL:            url,
+		Secret:         secret,
+		Enabled:        true,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}, nil
+}
+
+// GetWebhooks returns the webhooks of an organization, when enabledOnly is set
+// disabled webhooks are left out
+func GetWebhooks(organizationId int64, enabledOnly bool) ([]models.OrganizationWebhook, error) {
+	query := `
+		SELECT id, "organizationId", url, secret, enabled, "createdAt", "updatedAt"
+		FROM organization_webhook
+		WHERE "organizationId" = $1 AND (enabled OR NOT $2)
+		ORDER BY "createdAt"
+	`
+
+	rows, err := DB.Query(query, organizationId, enabledOnly)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	webhooks := []models.OrganizationWebhook{}
+	for rows.Next() {
+		var webhook models.OrganizationWebhook
+		err := rows.Scan(
+			&webhook.ID,
+			&webhook.OrganizationID,
+			&webhook.URL,
+			&webhook.Secret,
+			&webhook.Enabled,
+			&webhook.CreatedAt,
+			&webhook.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook: %w", err)
+		}
+		webhooks = append(webhooks, webhook)
+	}
+
+	return webhooks, rows.Err()
+}
+
+// DeleteWebhook deletes an organization's webhook and its delivery log,
+// returning false when the webhook does not exist
+func DeleteWebhook(id string, organizationId int64) (bool, error) {
+	query := `
+		DELETE FROM organization_webhook
+		WHERE id = $1 AND "organizationId" = $2
+	`
+
+	result, err := DB.Exec(query, id, organizationId)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete webhook: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete webhook: %w", err)
+	}
+
+	return deleted > 0, nil
+}
+
+func CreateWebhookDelivery(
+	webhookId string,
+	organizationId int64,
+	ocr_request_id int64,
+	event string,
+	payload string,
+) error {
+	query := `
+		INSERT INTO organization_webhook_delivery (
+			"webhookId",
+			"organizationId",
+			"ocrRequestId",
+			event,
+			payload,
+			status,
+			"nextAttemptAt",
+			"createdAt",
+			"updatedAt"
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $7, $7)
+	`
+
+	_, err := DB.Exec(query, webhookId, organizationId, ocr_request_id, event, payload, utils.WebhookDeliveryPending, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to insert into organization_webhook_delivery: %w", err)
+	}
+
+	return nil
+}
+
+// ClaimDueWebhookDeliveries returns pending deliveries that are due for an
+// attempt. Claimed deliveries have their next attempt pushed back by the lease
+// so other workers do not send them while the attempt is in flight.
+func ClaimDueWebhookDeliveries(limit int, lease time.Duration) ([]models.OrganizationWebhookDelivery, error) {
+	query := `
+		UPDATE organization_webhook_delivery d
+		SET "nextAttemptAt" = $1
+		FROM organization_webhook w
+		WHERE w.id = d."webhookId" AND d.id IN (
+			SELECT id
+			FROM organization_webhook_delivery
+			WHERE status = $2 AND "nextAttemptAt" <= $3
+			ORDER BY "nextAttemptAt"
+			LIMIT $4
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING d.id, d."webhookId", d."organizationId", d.event, d.payload, d.attempts, w.url, w.secret
+	`
+
+	now := time.Now()
+	rows, err := DB.Query(query, now.Add(lease), utils.WebhookDeliveryPending, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []models.OrganizationWebhookDelivery{}
+	for rows.Next() {
+		var delivery models.OrganizationWebhookDelivery
+		err := rows.Scan(
+			&delivery.ID,
+			&delivery.WebhookID,
+			&delivery.OrganizationID,
+			&delivery.Event,
+			&delivery.Payload,
+			&delivery.Attempts,
+			&delivery.URL,
+			&delivery.Secret,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, rows.Err()
+}
+
+// UpdateWebhookDeliveryAttempt records the outcome of a delivery attempt
+func UpdateWebhookDeliveryAttempt(
+	id int64,
+	status utils.WebhookDeliveryStatusType,
+	attempts int32,
+	// optional
+	response_status *int32,
+	delivery_error *string,
+	next_attempt_at time.Time,
+) error {
+	query := `
+		UPDATE organization_webhook_delivery
+		SET
+			status = $1,
+			attempts = $2,
+			"responseStatus" = $3,
+			error = $4,
+			"nextAttemptAt" = $5,
+			"updatedAt" = $6,
+			"deliveredAt" = CASE WHEN $1 = 'SUCCEEDED' THEN $6 ELSE "deliveredAt" END
+		WHERE id = $7
+	`
+
+	_, err := DB.Exec(query, status, attempts, response_status, delivery_error, next_attempt_at, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to update webhook delivery: %w", err)
+	}
+
+	return nil
+}
+
+// GetWebhookDeliveries returns the most recent deliveries of an organization's webhook
+func GetWebhookDeliveries(webhookId string, organizationId int64, limit int) ([]models.OrganizationWebhookDelivery, error) {
+	query := `
+		SELECT
+			id,
+			"webhookId",
+			"organizationId",
+			"ocrRequestId",
+			event,
+			payload,
+			status,
+			attempts,
+			"responseStatus",
+			COALESCE(error, ''),
+			"nextAttemptAt",
+			"createdAt",
+			"deliveredAt"
+		FROM organization_webhook_delivery
+		WHERE "webhookId" = $1 AND "organizationId" = $2
+		ORDER BY "createdAt" DESC
+		LIMIT $3
+	`
+
+	rows, err := DB.Query(query, webhookId, organizationId, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []models.OrganizationWebhookDelivery{}
+	for rows.Next() {
+		var delivery models.OrganizationWebhookDelivery
+		err := rows.Scan(
+			&delivery.ID,
+			&delivery.WebhookID,
+			&delivery.OrganizationID,
+			&delivery.OCRRequestID,
+			&delivery.Event,
+			&delivery.Payload,
+			&delivery.Status,
+			&delivery.Attempts,
+			&delivery.ResponseStatus,
+			&delivery.Error,
+			&delivery.NextAttemptAt,
+			&delivery.CreatedAt,
+			&delivery.DeliveredAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, rows.Err()
+}
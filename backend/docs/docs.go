@@ -19,11 +19,164 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/api/key-value-labels": {
+            "get": {
+                "description": "List the organization's label synonyms for extract=key_values",
+                "tags": [
+                    "Key-Value Labels"
+                ],
+                "summary": "List Key-Value Labels",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API Key",
+                        "name": "X-API-Key",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/utils.KeyValueLabelResponse"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ErrPermissionDeniedResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Set the label synonyms of a key for extract=key_values. Labels matching a synonym are returned under the key, and the organization's synonyms take precedence over the built in ones. Setting a key that already exists replaces its synonyms.",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Key-Value Labels"
+                ],
+                "summary": "Set Key-Value Label",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API Key",
+                        "name": "X-API-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Label",
+                        "name": "label",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/utils.KeyValueLabelRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/utils.KeyValueLabelResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ErrPermissionDeniedResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/key-value-labels/{id}": {
+            "delete": {
+                "description": "Delete a key and its label synonyms",
+                "tags": [
+                    "Key-Value Labels"
+                ],
+                "summary": "Delete Key-Value Label",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API Key",
+                        "name": "X-API-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Label ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ErrPermissionDeniedResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
         "/api/ocr": {
             "post": {
-                "description": "OCR Service for the OCR Service",
+                "description": "OCR Service for the OCR Service. A JSON body with the file as base64 is handled like /api/ocr/json.",
                 "consumes": [
-                    "multipart/form-data"
+                    "multipart/form-data",
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json",
+                    "text/html",
+                    "text/xml",
+                    "text/plain",
+                    "text/tab-separated-values",
+                    "application/zip",
+                    "application/pdf"
                 ],
                 "tags": [
                     "OCR"
@@ -52,7 +205,7 @@ const docTemplate = `{
                     },
                     {
                         "type": "string",
-                        "description": "OCR Engine (options: TESSERACT, EASYOCR, DOCTR)",
+                        "description": "OCR Engine (options: see /api/ocr/engines)",
                         "name": "engine",
                         "in": "formData"
                     },
@@ -62,6 +215,66 @@ const docTemplate = `{
                         "name": "raw",
                         "in": "formData"
                     },
+                    {
+                        "type": "string",
+                        "description": "Use the text layer of born-digital PDFs instead of OCR (options: auto, only, never)",
+                        "name": "text_layer",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Pages to OCR, e.g. 1-3,7,10- (defaults to every page)",
+                        "name": "pages",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ISO 639-1 codes of the languages in the document, comma separated or repeated, the first is the primary language, or auto to detect them per page (defaults to en, installed languages: see /api/ocr/engines)",
+                        "name": "languages",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Response format, structured returns a page, block, paragraph, line and word tree (options: flat, structured)",
+                        "name": "format",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Extract structured data from the words, comma separated or repeated, key_values returns label and value pairs using the organization's label synonyms (options: key_values)",
+                        "name": "extract",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Detect tables and return their rows and columns as JSON and CSV alongside the words (options: true, false)",
+                        "name": "tables",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Decode the QR, Code 128, EAN, UPC, Data Matrix and PDF417 barcodes on every page and return their payload, symbology and bounding box (options: true, false)",
+                        "name": "barcodes",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Output document format, searchable_pdf returns the original document with an invisible text layer (options: json, hocr, alto, pagexml, txt, tsv, searchable_pdf)",
+                        "name": "output_format",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "How a document in an output format other than json is returned, link returns an expiring download link (options: bytes, link)",
+                        "name": "delivery",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Image preprocessing steps to run before OCR, comma separated or all (options: orientation, upscale, grayscale, contrast, denoise, deskew, threshold)",
+                        "name": "preprocess",
+                        "in": "formData"
+                    },
                     {
                         "type": "string",
                         "description": "Organization ID",
@@ -72,7 +285,7 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "utils.StructuredOCRResponse when format is structured",
                         "schema": {
                             "$ref": "#/definitions/utils.OCRResponseList"
                         }
@@ -89,108 +302,2211 @@ const docTemplate = `{
                             "$ref": "#/definitions/utils.ErrPermissionDeniedResponse"
                         }
                     },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/utils.ErrorResponse"
                         }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ErrorResponse"
+                        }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "utils.BBox": {
-            "type": "object",
-            "properties": {
-                "bottomLeft": {
-                    "$ref": "#/definitions/utils.XY"
-                },
-                "bottomRight": {
-                    "$ref": "#/definitions/utils.XY"
-                },
-                "topLeft": {
-                    "$ref": "#/definitions/utils.XY"
-                },
-                "topRight": {
-                    "$ref": "#/definitions/utils.XY"
-                }
-            }
-        },
-        "utils.ErrPermissionDeniedResponse": {
-            "type": "object",
-            "properties": {
-                "error": {
-                    "type": "string",
-                    "example": "Permission denied"
-                }
-            }
-        },
-        "utils.ErrorResponse": {
-            "type": "object",
-            "properties": {
-                "error": {
-                    "type": "string",
-                    "example": "Error message"
-                }
-            }
-        },
-        "utils.OCREngineType": {
-            "type": "string",
-            "enum": [
-                "TESSERACT",
-                "EASYOCR",
-                "DOCTR"
-            ],
-            "x-enum-varnames": [
-                "EngineTesseract",
-                "EngineEasyOCR",
-                "EngineDoctoR"
-            ]
-        },
-        "utils.OCRResponse": {
-            "type": "object",
-            "properties": {
-                "bbox": {
-                    "$ref": "#/definitions/utils.BBox"
-                },
-                "confidence": {
-                    "type": "number",
-                    "example": 0.5
-                },
-                "page_number": {
-                    "type": "integer",
-                    "example": 1
-                },
-                "text": {
-                    "type": "string",
-                    "example": "hello world"
-                }
-            }
         },
-        "utils.OCRResponseList": {
-            "type": "object",
-            "properties": {
-                "cached": {
-                    "type": "boolean",
-                    "example": true
-                },
-                "engine": {
-                    "$ref": "#/definitions/utils.OCREngineType"
-                },
-                "number_of_tokens": {
-                    "type": "integer",
-                    "example": 100
-                },
-                "ocr_responses": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/utils.OCRResponse"
-                    }
-                },
-                "raw": {
-                    "type": "boolean",
-                    "example": true
+        "/api/ocr/batch": {
+            "post": {
+                "description": "OCR many files in one request. Files are uploaded as repeated files fields, and ZIP archives among them are extracted. The organization is checked once for the pages of every file, identical files are only processed once, and the results or errors are returned by filename. Documents in an output format other than json are returned as download links.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "OCR"
+                ],
+                "summary": "OCR Batch",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API Key",
+                        "name": "X-API-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "file"
+                        },
+                        "collectionFormat": "csv",
+                        "description": "Files or ZIP archives of files",
+                        "name": "files",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cache Policy (options: cache_first, no_cache, cache_only)",
+                        "name": "cache_policy",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "OCR Engine (options: see /api/ocr/engines)",
+                        "name": "engine",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Raw (options: true, false)",
+                        "name": "raw",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Use the text layer of born-digital PDFs instead of OCR (options: auto, only, never)",
+                        "name": "text_layer",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Pages to OCR in every file, e.g. 1-3,7,10- (defaults to every page)",
+                        "name": "pages",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ISO 639-1 codes of the languages in the documents, comma separated or repeated, the first is the primary language, or auto to detect them per page (defaults to en, installed languages: see /api/ocr/engines)",
+                        "name": "languages",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Response format, structured returns a page, block, paragraph, line and word tree (options: flat, structured)",
+                        "name": "format",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Extract structured data from the words, comma separated or repeated, key_values returns label and value pairs using the organization's label synonyms (options: key_values)",
+                        "name": "extract",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Detect tables and return their rows and columns as JSON and CSV alongside the words (options: true, false)",
+                        "name": "tables",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Decode the QR, Code 128, EAN, UPC, Data Matrix and PDF417 barcodes on every page and return their payload, symbology and bounding box (options: true, false)",
+                        "name": "barcodes",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Output document format, other formats than json require delivery=link (options: json, hocr, alto, pagexml, txt, tsv, searchable_pdf)",
+                        "name": "output_format",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "How a document in an output format other than json is returned, batches only support link (options: link)",
+                        "name": "delivery",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Image preprocessing steps to run before OCR, comma separated or all (options: orientation, upscale, grayscale, contrast, denoise, deskew, threshold)",
+                        "name": "preprocess",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/utils.BatchOCRResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ErrPermissionDeniedResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/ocr/engines": {
+            "get": {
+                "description": "List the OCR engines enabled in this deployment with their capabilities and installed languages",
+                "tags": [
+                    "OCR"
+                ],
+                "summary": "List OCR Engines",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API Key",
+                        "name": "X-API-Key",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/utils.OCREngineInfo"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/ocr/jobs": {
+            "post": {
+                "description": "Queue a file for asynchronous OCR. The job ID is returned immediately and the job can be polled for its status and result.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "tags": [
+                    "OCR Jobs"
+                ],
+                "summary": "Create OCR Job",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API Key",
+                        "name": "X-API-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "file",
+                        "description": "File",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cache Policy (options: cache_first, no_cache, cache_only)",
+                        "name": "cache_policy",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "OCR Engine (options: see /api/ocr/engines)",
+                        "name": "engine",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Raw (options: true, false)",
+                        "name": "raw",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Use the text layer of born-digital PDFs instead of OCR (options: auto, only, never)",
+                        "name": "text_layer",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Pages to OCR, e.g. 1-3,7,10- (defaults to every page)",
+                        "name": "pages",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ISO 639-1 codes of the languages in the document, comma separated or repeated, the first is the primary language, or auto to detect them per page (defaults to en, installed languages: see /api/ocr/engines)",
+                        "name": "languages",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Response format, structured returns a page, block, paragraph, line and word tree (options: flat, structured)",
+                        "name": "format",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Extract structured data from the words, comma separated or repeated, key_values returns label and value pairs using the organization's label synonyms (options: key_values)",
+                        "name": "extract",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Detect tables and return their rows and columns as JSON and CSV alongside the words (options: true, false)",
+                        "name": "tables",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Decode the QR, Code 128, EAN, UPC, Data Matrix and PDF417 barcodes on every page and return their payload, symbology and bounding box (options: true, false)",
+                        "name": "barcodes",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Output document format, searchable_pdf returns the original document with an invisible text layer (options: json, hocr, alto, pagexml, txt, tsv, searchable_pdf)",
+                        "name": "output_format",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Image preprocessing steps to run before OCR, comma separated or all (options: orientation, upscale, grayscale, contrast, denoise, deskew, threshold)",
+                        "name": "preprocess",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/utils.OCRJobResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ErrPermissionDeniedResponse"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/ocr/jobs/{id}": {
+            "get": {
+                "description": "Get the status and progress of an OCR job",
+                "tags": [
+                    "OCR Jobs"
+                ],
+                "summary": "Get OCR Job",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API Key",
+                        "name": "X-API-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Job ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/utils.OCRJobResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ErrPermissionDeniedResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/ocr/jobs/{id}/result": {
+            "get": {
+                "description": "Get the OCR results of a completed job, in the output format the job was created with",
+                "produces": [
+                    "application/json",
+                    "text/html",
+                    "text/xml",
+                    "text/plain",
+                    "text/tab-separated-values",
+                    "application/zip",
+                    "application/pdf"
+                ],
+                "tags": [
+                    "OCR Jobs"
+                ],
+                "summary": "Get OCR Job Result",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API Key",
+                        "name": "X-API-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Job ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "How a document in an output format other than json is returned, link returns an expiring download link (options: bytes, link)",
+                        "name": "delivery",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "utils.StructuredOCRResponse when the job was created with the structured format, utils.DownloadLinkResponse with delivery=link",
+                        "schema": {
+                            "$ref": "#/definitions/utils.OCRResponseList"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ErrPermissionDeniedResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/ocr/json": {
+            "post": {
+                "description": "OCR a document sent as base64 in a JSON body, or read from a source_url or from the key of a bucket instead of being uploaded. The URL must be https on an allowed host and the bucket must be allowed. The other fields are the options of /api/ocr, with the same validation, and invalid fields are listed in the error's fields.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json",
+                    "text/html",
+                    "text/xml",
+                    "text/plain",
+                    "text/tab-separated-values",
+                    "application/zip",
+                    "application/pdf"
+                ],
+                "tags": [
+                    "OCR"
+                ],
+                "summary": "OCR JSON",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API Key",
+                        "name": "X-API-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Document and OCR options",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/utils.OCRJSONRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "utils.StructuredOCRResponse when format is structured",
+                        "schema": {
+                            "$ref": "#/definitions/utils.OCRResponseList"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ErrPermissionDeniedResponse"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ErrorResponse"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ErrorResponse"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/ocr/stream": {
+            "post": {
+                "description": "OCR a file and stream the results of each page as soon as it is done, as server-sent events or as newline delimited JSON when the Accept header is application/x-ndjson. Every page is a \"page\" event, pages are sent in the order they finish. A \"summary\" event with the totals, whether the results were cached and the ID of the recorded request ends the stream, or an \"error\" event when OCR fails after pages were sent. Cached results are sent page by page too.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "text/event-stream",
+                    "application/x-ndjson"
+                ],
+                "tags": [
+                    "OCR"
+                ],
+                "summary": "OCR Stream",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API Key",
+                        "name": "X-API-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "file",
+                        "description": "File",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cache Policy (options: cache_first, no_cache, cache_only)",
+                        "name": "cache_policy",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "OCR Engine (options: see /api/ocr/engines)",
+                        "name": "engine",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Raw (options: true, false)",
+                        "name": "raw",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Use the text layer of born-digital PDFs instead of OCR (options: auto, only, never)",
+                        "name": "text_layer",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Pages to OCR, e.g. 1-3,7,10- (defaults to every page)",
+                        "name": "pages",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ISO 639-1 codes of the languages in the document, comma separated or repeated, the first is the primary language, or auto to detect them per page (defaults to en, installed languages: see /api/ocr/engines)",
+                        "name": "languages",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Extract structured data from the words, comma separated or repeated, key_values are returned in the summary event (options: key_values)",
+                        "name": "extract",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Detect tables and return their rows and columns as JSON and CSV alongside the words (options: true, false)",
+                        "name": "tables",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Decode the QR, Code 128, EAN, UPC, Data Matrix and PDF417 barcodes on every page and return their payload, symbology and bounding box (options: true, false)",
+                        "name": "barcodes",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Image preprocessing steps to run before OCR, comma separated or all (options: orientation, upscale, grayscale, contrast, denoise, deskew, threshold)",
+                        "name": "preprocess",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "page events, followed by a utils.OCRStreamSummary summary event",
+                        "schema": {
+                            "$ref": "#/definitions/utils.OCRStreamPage"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ErrPermissionDeniedResponse"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ErrorResponse"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/templates": {
+            "get": {
+                "description": "List the organization's OCR templates",
+                "tags": [
+                    "OCR Templates"
+                ],
+                "summary": "List OCR Templates",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API Key",
+                        "name": "X-API-Key",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/utils.TemplateResponse"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ErrPermissionDeniedResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Save a template of named zones to OCR with /api/templates/{id}/ocr. Zones are rectangles in fractions of the page's width and height from its top left corner, and can set their own engine, languages, character whitelist and a regular expression their text has to match. Zones without an engine or languages use the template's. Saving a template with the name of an existing one replaces it.",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "OCR Templates"
+                ],
+                "summary": "Save OCR Template",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API Key",
+                        "name": "X-API-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Template",
+                        "name": "template",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/utils.TemplateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/utils.TemplateResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ErrPermissionDeniedResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/templates/{id}": {
+            "get": {
+                "description": "Get one of the organization's OCR templates",
+                "tags": [
+                    "OCR Templates"
+                ],
+                "summary": "Get OCR Template",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API Key",
+                        "name": "X-API-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Template ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/utils.TemplateResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ErrPermissionDeniedResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Delete one of the organization's OCR templates",
+                "tags": [
+                    "OCR Templates"
+                ],
+                "summary": "Delete OCR Template",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API Key",
+                        "name": "X-API-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Template ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ErrPermissionDeniedResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/templates/{id}/ocr": {
+            "post": {
+                "description": "OCR only the zones of a template and return the text of each zone by name. Only the pages the zones are on are processed and billed. A field is invalid when its text does not match the zone's pattern.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "tags": [
+                    "OCR Templates"
+                ],
+                "summary": "OCR Template Zones",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API Key",
+                        "name": "X-API-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Template ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "file",
+                        "description": "File",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/utils.TemplateOCRResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ErrPermissionDeniedResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ErrorResponse"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ErrorResponse"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/webhooks": {
+            "get": {
+                "description": "List the organization's webhooks",
+                "tags": [
+                    "Webhooks"
+                ],
+                "summary": "List Webhooks",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API Key",
+                        "name": "X-API-Key",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/utils.WebhookResponse"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ErrPermissionDeniedResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Register a URL that is sent a signed POST request whenever an OCR request completes or fails. The payload is signed with HMAC-SHA256 over \"{X-Webhook-Timestamp}.{body}\" and sent in the X-Webhook-Signature header. A secret is generated when one is not provided and is only returned in this response.",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Webhooks"
+                ],
+                "summary": "Create Webhook",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API Key",
+                        "name": "X-API-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Webhook",
+                        "name": "webhook",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/utils.CreateWebhookRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/utils.WebhookResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ErrPermissionDeniedResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/webhooks/{id}": {
+            "delete": {
+                "description": "Delete a webhook and its delivery log",
+                "tags": [
+                    "Webhooks"
+                ],
+                "summary": "Delete Webhook",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API Key",
+                        "name": "X-API-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Webhook ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ErrPermissionDeniedResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/webhooks/{id}/deliveries": {
+            "get": {
+                "description": "List the 100 most recent deliveries of a webhook, including failed attempts",
+                "tags": [
+                    "Webhooks"
+                ],
+                "summary": "List Webhook Deliveries",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API Key",
+                        "name": "X-API-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Webhook ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/utils.WebhookDeliveryResponse"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ErrPermissionDeniedResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/utils.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "utils.BBox": {
+            "type": "object",
+            "properties": {
+                "bottomLeft": {
+                    "$ref": "#/definitions/utils.XY"
+                },
+                "bottomRight": {
+                    "$ref": "#/definitions/utils.XY"
+                },
+                "topLeft": {
+                    "$ref": "#/definitions/utils.XY"
+                },
+                "topRight": {
+                    "$ref": "#/definitions/utils.XY"
+                }
+            }
+        },
+        "utils.Barcode": {
+            "type": "object",
+            "properties": {
+                "bbox": {
+                    "$ref": "#/definitions/utils.BBox"
+                },
+                "page_number": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "payload": {
+                    "description": "the decoded content of the symbol",
+                    "type": "string",
+                    "example": "https://example.com/track/1Z999AA10123456784"
+                },
+                "symbology": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/utils.BarcodeSymbologyType"
+                        }
+                    ],
+                    "example": "qr_code"
+                }
+            }
+        },
+        "utils.BarcodeSymbologyType": {
+            "type": "string",
+            "enum": [
+                "qr_code",
+                "code_128",
+                "ean_13",
+                "ean_8",
+                "upc_a",
+                "upc_e",
+                "data_matrix",
+                "pdf417"
+            ],
+            "x-enum-varnames": [
+                "SymbologyQRCode",
+                "SymbologyCode128",
+                "SymbologyEAN13",
+                "SymbologyEAN8",
+                "SymbologyUPCA",
+                "SymbologyUPCE",
+                "SymbologyDataMatrix",
+                "SymbologyPDF417"
+            ]
+        },
+        "utils.BatchFileResult": {
+            "type": "object",
+            "properties": {
+                "duplicate_of": {
+                    "description": "the file with identical content the results were taken from",
+                    "type": "string",
+                    "example": "scan-001.png"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "result": {},
+                "status": {
+                    "type": "integer",
+                    "example": 200
+                }
+            }
+        },
+        "utils.BatchOCRResponse": {
+            "type": "object",
+            "properties": {
+                "failed": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "files": {
+                    "description": "results by filename, files of ZIP archives are named archive.zip/path/in/archive",
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/utils.BatchFileResult"
+                    }
+                },
+                "pages": {
+                    "description": "pages across the distinct files the organization was checked to be entitled to",
+                    "type": "integer",
+                    "example": 10
+                },
+                "succeeded": {
+                    "type": "integer",
+                    "example": 9
+                }
+            }
+        },
+        "utils.CreateWebhookRequest": {
+            "type": "object",
+            "required": [
+                "url"
+            ],
+            "properties": {
+                "secret": {
+                    "type": "string",
+                    "example": "whsec_5f2b8e0c9d7a4e3b8c1d2e3f"
+                },
+                "url": {
+                    "type": "string",
+                    "example": "https://example.com/webhooks/ocr"
+                }
+            }
+        },
+        "utils.ErrPermissionDeniedResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "Permission denied"
+                }
+            }
+        },
+        "utils.ErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "Error message"
+                },
+                "fields": {
+                    "description": "the invalid fields of a request that failed validation",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/utils.FieldError"
+                    }
+                }
+            }
+        },
+        "utils.ExtractType": {
+            "type": "string",
+            "enum": [
+                "key_values"
+            ],
+            "x-enum-varnames": [
+                "ExtractKeyValues"
+            ]
+        },
+        "utils.FieldError": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "Invalid engine"
+                },
+                "field": {
+                    "type": "string",
+                    "example": "engine"
+                }
+            }
+        },
+        "utils.KeyValue": {
+            "type": "object",
+            "properties": {
+                "bbox": {
+                    "$ref": "#/definitions/utils.BBox"
+                },
+                "confidence": {
+                    "description": "mean confidence of the label and value words",
+                    "type": "number",
+                    "example": 0.9
+                },
+                "key": {
+                    "description": "the key of the organization's or the built in label synonyms the label\nmatched, or the label in snake case when it matched none",
+                    "type": "string",
+                    "example": "invoice_number"
+                },
+                "label": {
+                    "type": "string",
+                    "example": "Invoice No"
+                },
+                "label_bbox": {
+                    "$ref": "#/definitions/utils.BBox"
+                },
+                "page_number": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "value": {
+                    "type": "string",
+                    "example": "12345"
+                },
+                "value_bbox": {
+                    "$ref": "#/definitions/utils.BBox"
+                }
+            }
+        },
+        "utils.KeyValueLabelRequest": {
+            "type": "object",
+            "required": [
+                "key",
+                "synonyms"
+            ],
+            "properties": {
+                "key": {
+                    "type": "string",
+                    "example": "invoice_number"
+                },
+                "synonyms": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "invoice no",
+                        "bill number"
+                    ]
+                }
+            }
+        },
+        "utils.KeyValueLabelResponse": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "5f2b8e0c9d7a4e3b8c1d2e3f4a5b6c7d"
+                },
+                "key": {
+                    "type": "string",
+                    "example": "invoice_number"
+                },
+                "synonyms": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "invoice no",
+                        "bill number"
+                    ]
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "utils.OCREngineInfo": {
+            "type": "object",
+            "properties": {
+                "confidence": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "layout": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "name": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/utils.OCREngineType"
+                        }
+                    ],
+                    "example": "TESSERACT"
+                },
+                "supported_languages": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "en"
+                    ]
+                },
+                "word_boxes": {
+                    "type": "boolean",
+                    "example": true
+                }
+            }
+        },
+        "utils.OCREngineType": {
+            "type": "string",
+            "enum": [
+                "TESSERACT",
+                "EASYOCR",
+                "DOCTR"
+            ],
+            "x-enum-varnames": [
+                "EngineTesseract",
+                "EngineEasyOCR",
+                "EngineDoctoR"
+            ]
+        },
+        "utils.OCRJSONRequest": {
+            "type": "object",
+            "properties": {
+                "barcodes": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "bucket": {
+                    "type": "string",
+                    "example": "documents"
+                },
+                "cache_policy": {
+                    "type": "string",
+                    "example": "cache_first"
+                },
+                "delivery": {
+                    "type": "string",
+                    "example": "bytes"
+                },
+                "engine": {
+                    "type": "string",
+                    "example": "TESSERACT"
+                },
+                "extract": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "key_values"
+                    ]
+                },
+                "file": {
+                    "type": "string",
+                    "example": "JVBERi0xLjcKJeLjz9MK..."
+                },
+                "filename": {
+                    "type": "string",
+                    "example": "invoice.pdf"
+                },
+                "format": {
+                    "type": "string",
+                    "example": "flat"
+                },
+                "key": {
+                    "type": "string",
+                    "example": "invoices/invoice.pdf"
+                },
+                "languages": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "en"
+                    ]
+                },
+                "output_format": {
+                    "type": "string",
+                    "example": "json"
+                },
+                "pages": {
+                    "type": "string",
+                    "example": "1-3,7"
+                },
+                "preprocess": {
+                    "type": "string",
+                    "example": "deskew,threshold"
+                },
+                "raw": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "source_url": {
+                    "type": "string",
+                    "example": "https://documents.example.com/invoice.pdf"
+                },
+                "tables": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "text_layer": {
+                    "type": "string",
+                    "example": "auto"
+                }
+            }
+        },
+        "utils.OCRJobResponse": {
+            "type": "object",
+            "properties": {
+                "barcodes": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "completed_at": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "engine": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/utils.OCREngineType"
+                        }
+                    ],
+                    "example": "TESSERACT"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "extract": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/utils.ExtractType"
+                    },
+                    "example": [
+                        "key_values"
+                    ]
+                },
+                "file_hash": {
+                    "type": "string"
+                },
+                "filename": {
+                    "type": "string",
+                    "example": "invoice.pdf"
+                },
+                "format": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/utils.ResponseFormatType"
+                        }
+                    ],
+                    "example": "flat"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "5f2b8e0c9d7a4e3b8c1d2e3f4a5b6c7d"
+                },
+                "languages": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "en",
+                        "de"
+                    ]
+                },
+                "output_format": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/utils.OutputFormatType"
+                        }
+                    ],
+                    "example": "json"
+                },
+                "pages": {
+                    "type": "string",
+                    "example": "1-3,7"
+                },
+                "pages_done": {
+                    "type": "integer",
+                    "example": 4
+                },
+                "pages_total": {
+                    "type": "integer",
+                    "example": 10
+                },
+                "preprocess": {
+                    "type": "string",
+                    "example": "deskew,threshold"
+                },
+                "raw": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "started_at": {
+                    "type": "string"
+                },
+                "status": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/utils.OCRJobStatusType"
+                        }
+                    ],
+                    "example": "PROCESSING"
+                },
+                "tables": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "text_layer": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/utils.TextLayerModeType"
+                        }
+                    ],
+                    "example": "never"
+                }
+            }
+        },
+        "utils.OCRJobStatusType": {
+            "type": "string",
+            "enum": [
+                "QUEUED",
+                "PROCESSING",
+                "COMPLETED",
+                "FAILED"
+            ],
+            "x-enum-varnames": [
+                "JobQueued",
+                "JobProcessing",
+                "JobCompleted",
+                "JobFailed"
+            ]
+        },
+        "utils.OCRResponse": {
+            "type": "object",
+            "properties": {
+                "bbox": {
+                    "$ref": "#/definitions/utils.BBox"
+                },
+                "block": {
+                    "description": "the 1-based block, paragraph and line of the page the word is in, for\nengines that detect layout",
+                    "type": "integer",
+                    "example": 1
+                },
+                "confidence": {
+                    "type": "number",
+                    "example": 0.5
+                },
+                "line": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "page_number": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "paragraph": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "source": {
+                    "description": "\"ocr\" or \"text_layer\" when the text was extracted from the PDF",
+                    "type": "string",
+                    "example": "ocr"
+                },
+                "text": {
+                    "type": "string",
+                    "example": "hello world"
+                }
+            }
+        },
+        "utils.OCRResponseList": {
+            "type": "object",
+            "properties": {
+                "barcodes": {
+                    "description": "the barcodes decoded on every page when barcodes are requested",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/utils.Barcode"
+                    }
+                },
+                "cached": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "engine": {
+                    "$ref": "#/definitions/utils.OCREngineType"
+                },
+                "key_values": {
+                    "description": "the label and value pairs found when extract includes key_values",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/utils.KeyValue"
+                    }
+                },
+                "languages": {
+                    "description": "ISO 639-1 codes of the languages the engine was asked to recognize, or \"auto\"",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "en",
+                        "de"
+                    ]
+                },
+                "number_of_tokens": {
+                    "type": "integer",
+                    "example": 100
+                },
+                "ocr_responses": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/utils.OCRResponse"
+                    }
+                },
+                "page_languages": {
+                    "description": "the language and script detected on each OCR'd page when languages is \"auto\"",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/utils.PageLanguage"
+                    }
+                },
+                "page_sizes": {
+                    "description": "the size of every page in pixels, bounding boxes are relative to it",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/utils.PageSize"
+                    }
+                },
+                "raw": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "tables": {
+                    "description": "the tables detected on every page when tables are requested",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/utils.Table"
+                    }
+                }
+            }
+        },
+        "utils.OCRStreamPage": {
+            "type": "object",
+            "properties": {
+                "barcodes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/utils.Barcode"
+                    }
+                },
+                "ocr_responses": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/utils.OCRResponse"
+                    }
+                },
+                "page_language": {
+                    "$ref": "#/definitions/utils.PageLanguage"
+                },
+                "page_number": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "page_size": {
+                    "$ref": "#/definitions/utils.PageSize"
+                },
+                "tables": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/utils.Table"
+                    }
+                }
+            }
+        },
+        "utils.OutputFormatType": {
+            "type": "string",
+            "enum": [
+                "json",
+                "hocr",
+                "alto",
+                "pagexml",
+                "txt",
+                "tsv",
+                "searchable_pdf"
+            ],
+            "x-enum-varnames": [
+                "OutputJSON",
+                "OutputHOCR",
+                "OutputALTO",
+                "OutputPageXML",
+                "OutputText",
+                "OutputTSV",
+                "OutputSearchablePDF"
+            ]
+        },
+        "utils.PageLanguage": {
+            "type": "object",
+            "properties": {
+                "language": {
+                    "description": "the language that best matches the recognized text",
+                    "type": "string",
+                    "example": "de"
+                },
+                "languages": {
+                    "description": "the languages the page was OCR'd with",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "en",
+                        "de",
+                        "fr"
+                    ]
+                },
+                "page_number": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "script": {
+                    "description": "script detected by tesseract's orientation and script detection, empty when\nthe page has too little text to detect it",
+                    "type": "string",
+                    "example": "Latin"
+                },
+                "script_confidence": {
+                    "type": "number",
+                    "example": 2.5
+                }
+            }
+        },
+        "utils.PageSize": {
+            "type": "object",
+            "properties": {
+                "height": {
+                    "type": "integer",
+                    "example": 3300
+                },
+                "page_number": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "width": {
+                    "type": "integer",
+                    "example": 2550
+                }
+            }
+        },
+        "utils.ResponseFormatType": {
+            "type": "string",
+            "enum": [
+                "flat",
+                "structured"
+            ],
+            "x-enum-varnames": [
+                "FormatFlat",
+                "FormatStructured"
+            ]
+        },
+        "utils.Table": {
+            "type": "object",
+            "properties": {
+                "bbox": {
+                    "$ref": "#/definitions/utils.BBox"
+                },
+                "csv": {
+                    "description": "the text of the cells as comma separated values, one record per row",
+                    "type": "string",
+                    "example": "Item,Qty,Price\nWidget,2,9.99\n"
+                },
+                "page_number": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "rows": {
+                    "type": "array",
+                    "items": {
+                        "type": "array",
+                        "items": {
+                            "$ref": "#/definitions/utils.TableCell"
+                        }
+                    }
+                },
+                "ruled": {
+                    "description": "ruled tables were found from the ruling lines of the page, the others from\nthe alignment of the words",
+                    "type": "boolean",
+                    "example": true
+                }
+            }
+        },
+        "utils.TableCell": {
+            "type": "object",
+            "properties": {
+                "bbox": {
+                    "$ref": "#/definitions/utils.BBox"
+                },
+                "text": {
+                    "type": "string",
+                    "example": "9.99"
+                }
+            }
+        },
+        "utils.TemplateField": {
+            "type": "object",
+            "properties": {
+                "bbox": {
+                    "description": "the zone in pixels of the page",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/utils.BBox"
+                        }
+                    ]
+                },
+                "confidence": {
+                    "type": "number",
+                    "example": 0.9
+                },
+                "page_number": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "valid": {
+                    "description": "false when the value does not match the zone's pattern",
+                    "type": "boolean",
+                    "example": true
+                },
+                "value": {
+                    "type": "string",
+                    "example": "12345"
+                }
+            }
+        },
+        "utils.TemplateOCRResponse": {
+            "type": "object",
+            "properties": {
+                "fields": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/utils.TemplateField"
+                    }
+                },
+                "template": {
+                    "type": "string",
+                    "example": "acme_invoice"
+                },
+                "template_id": {
+                    "type": "string",
+                    "example": "5f2b8e0c9d7a4e3b8c1d2e3f4a5b6c7d"
+                },
+                "valid": {
+                    "description": "true when every field is valid",
+                    "type": "boolean",
+                    "example": true
+                }
+            }
+        },
+        "utils.TemplateRequest": {
+            "type": "object",
+            "required": [
+                "name",
+                "zones"
+            ],
+            "properties": {
+                "engine": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/utils.OCREngineType"
+                        }
+                    ],
+                    "example": "TESSERACT"
+                },
+                "languages": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "en"
+                    ]
+                },
+                "name": {
+                    "type": "string",
+                    "example": "acme_invoice"
+                },
+                "zones": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/utils.TemplateZone"
+                    }
+                }
+            }
+        },
+        "utils.TemplateResponse": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "engine": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/utils.OCREngineType"
+                        }
+                    ],
+                    "example": "TESSERACT"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "5f2b8e0c9d7a4e3b8c1d2e3f4a5b6c7d"
+                },
+                "languages": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "en"
+                    ]
+                },
+                "name": {
+                    "type": "string",
+                    "example": "acme_invoice"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "zones": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/utils.TemplateZone"
+                    }
+                }
+            }
+        },
+        "utils.TemplateZone": {
+            "type": "object",
+            "required": [
+                "name"
+            ],
+            "properties": {
+                "engine": {
+                    "description": "the engine and languages of the zone, the template's when empty",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/utils.OCREngineType"
+                        }
+                    ],
+                    "example": "TESSERACT"
+                },
+                "height": {
+                    "type": "number",
+                    "example": 0.04
+                },
+                "languages": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "en"
+                    ]
+                },
+                "name": {
+                    "type": "string",
+                    "example": "invoice_number"
+                },
+                "page": {
+                    "description": "the page of the document the zone is on, starting at 1",
+                    "type": "integer",
+                    "example": 1
+                },
+                "pattern": {
+                    "description": "a regular expression the zone's text has to match to be valid",
+                    "type": "string",
+                    "example": "^\\d{4,}$"
+                },
+                "whitelist": {
+                    "description": "the only characters recognized in the zone",
+                    "type": "string",
+                    "example": "0123456789-"
+                },
+                "width": {
+                    "type": "number",
+                    "example": 0.3
+                },
+                "x": {
+                    "type": "number",
+                    "example": 0.6
+                },
+                "y": {
+                    "type": "number",
+                    "example": 0.05
+                }
+            }
+        },
+        "utils.TextLayerModeType": {
+            "type": "string",
+            "enum": [
+                "auto",
+                "only",
+                "never"
+            ],
+            "x-enum-varnames": [
+                "TextLayerAuto",
+                "TextLayerOnly",
+                "TextLayerNever"
+            ]
+        },
+        "utils.WebhookDeliveryResponse": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "delivered_at": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "event": {
+                    "type": "string",
+                    "example": "ocr.completed"
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "next_attempt_at": {
+                    "type": "string"
+                },
+                "payload": {
+                    "type": "string"
+                },
+                "response_status": {
+                    "type": "integer",
+                    "example": 200
+                },
+                "status": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/utils.WebhookDeliveryStatusType"
+                        }
+                    ],
+                    "example": "SUCCEEDED"
+                }
+            }
+        },
+        "utils.WebhookDeliveryStatusType": {
+            "type": "string",
+            "enum": [
+                "PENDING",
+                "SUCCEEDED",
+                "FAILED"
+            ],
+            "x-enum-varnames": [
+                "WebhookDeliveryPending",
+                "WebhookDeliverySucceeded",
+                "WebhookDeliveryFailed"
+            ]
+        },
+        "utils.WebhookResponse": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "enabled": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "id": {
+                    "type": "string",
+                    "example": "5f2b8e0c9d7a4e3b8c1d2e3f4a5b6c7d"
+                },
+                "secret": {
+                    "description": "only returned when the webhook is created",
+                    "type": "string",
+                    "example": "whsec_5f2b8e0c9d7a4e3b8c1d2e3f"
+                },
+                "url": {
+                    "type": "string",
+                    "example": "https://example.com/webhooks/ocr"
                 }
             }
         },
@@ -7,11 +7,15 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"github.com/joho/godotenv"
+	"github.com/unidoc/unipdf/v3/common/license"
 
 	authApis "serverless-tesseract/apis/auth"
 	serviceApis "serverless-tesseract/apis/service"
 
 	_ "serverless-tesseract/docs"
+	"serverless-tesseract/services/jobs"
+	"serverless-tesseract/services/webhooks"
+	"serverless-tesseract/utils"
 
 	"github.com/gin-contrib/cors"
 	swaggerFiles "github.com/swaggo/files"
@@ -31,11 +35,19 @@ func main() {
 	// Load .env file if it exists
 	_ = godotenv.Load()
 
+	// extracting the text layer of PDFs and writing searchable PDFs requires a
+	// unidoc license, rendering does not
+	if key := os.Getenv("UNIDOC_LICENSE_API_KEY"); key != "" {
+		if err := license.SetMeteredKey(key); err != nil {
+			log.Printf("Failed to set unidoc license key: %v", err)
+		}
+	}
+
 	r := gin.Default()
 
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"*"},
-		AllowMethods:     []string{"POST", "OPTIONS"},
+		AllowMethods:     []string{"GET", "POST", "DELETE", "OPTIONS"},
 		AllowHeaders:     []string{"Origin", "Content-Type", "X-API-Key"},
 		ExposeHeaders:    []string{"Content-Length"},
 		AllowCredentials: true,
@@ -48,10 +60,36 @@ func main() {
 
 	// service routes
 	service.POST("/ocr", serviceApis.OCRService2)
+	service.POST("/ocr/batch", serviceApis.OCRBatch)
+	service.POST("/ocr/json", serviceApis.OCRJSON)
+	service.POST("/ocr/stream", serviceApis.OCRStream)
+	service.GET("/ocr/engines", serviceApis.ListEngines)
+	service.POST("/ocr/jobs", serviceApis.CreateOCRJob)
+	service.GET("/ocr/jobs/:id", serviceApis.GetOCRJob)
+	service.GET("/ocr/jobs/:id/result", serviceApis.GetOCRJobResult)
+	service.POST("/webhooks", serviceApis.CreateWebhook)
+	service.GET("/webhooks", serviceApis.ListWebhooks)
+	service.DELETE("/webhooks/:id", serviceApis.DeleteWebhook)
+	service.GET("/webhooks/:id/deliveries", serviceApis.ListWebhookDeliveries)
+	service.POST("/key-value-labels", serviceApis.UpsertKeyValueLabel)
+	service.GET("/key-value-labels", serviceApis.ListKeyValueLabels)
+	service.DELETE("/key-value-labels/:id", serviceApis.DeleteKeyValueLabel)
+	service.POST("/templates", serviceApis.UpsertOCRTemplate)
+	service.GET("/templates", serviceApis.ListOCRTemplates)
+	service.GET("/templates/:id", serviceApis.GetOCRTemplate)
+	service.DELETE("/templates/:id", serviceApis.DeleteOCRTemplate)
+	service.POST("/templates/:id/ocr", serviceApis.OCRTemplate)
+
+	// deliver webhooks when OCR requests finish
+	webhooks.Start()
+
+	// start the workers that process asynchronous OCR jobs
+	jobs.Start(utils.OCR_JOB_WORKERS)
 
 	// conditionally serve swagger docs
 	if os.Getenv("ENV") == "development" {
 		log.Println("Serving swagger docs on /swagger")
+		registerEngineDocs()
 		r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 	}
 
@@ -12,19 +12,118 @@ type Organization struct {
 	CreatedAt       time.Time `json:"created_at"`
 	UpdatedAt       time.Time `json:"updated_at"`
 	PolarCustomerId string    `json:"polar_customer_id"`
+	// optional override of OCR_ORGANIZATION_PAGE_CONCURRENCY
+	OCRPageConcurrency *int32 `json:"ocr_page_concurrency"`
+}
+
+// PageConcurrency returns the number of pages the organization may OCR at once
+func (o Organization) PageConcurrency() int {
+	if o.OCRPageConcurrency != nil && *o.OCRPageConcurrency > 0 {
+		return int(*o.OCRPageConcurrency)
+	}
+	return utils.OCR_ORGANIZATION_PAGE_CONCURRENCY
 }
 
 type OrganizationOCRRequest struct {
-	ID             int64               `json:"id"`
-	CreatedAt      time.Time           `json:"created_at"`
-	CacheHit       bool                `json:"cache_hit"`
-	NumOfPages     int32               `json:"num_of_pages"`
-	OCREngine      utils.OCREngineType `json:"ocr_engine"`
-	OrganizationID int64               `json:"organization_id"`
-	Filename       string              `json:"filename"`
-	Success        bool                `json:"success"`
-	TokenCount     int64               `json:"token_count"`
-	FileHash       string              `json:"file_hash"`
-	CacheHash      string              `json:"cache_hash"`
-	Raw            bool                `json:"raw"`
+	ID             int64                   `json:"id"`
+	CreatedAt      time.Time               `json:"created_at"`
+	CacheHit       bool                    `json:"cache_hit"`
+	NumOfPages     int32                   `json:"num_of_pages"`
+	OCREngine      utils.OCREngineType     `json:"ocr_engine"`
+	OrganizationID int64                   `json:"organization_id"`
+	Filename       string                  `json:"filename"`
+	Success        bool                    `json:"success"`
+	TokenCount     int64                   `json:"token_count"`
+	FileHash       string                  `json:"file_hash"`
+	CacheHash      string                  `json:"cache_hash"`
+	Raw            bool                    `json:"raw"`
+	TextLayer      utils.TextLayerModeType `json:"text_layer"`
+	Preprocess     string                  `json:"preprocess"`
+	Languages      string                  `json:"languages"`
+	Tables         bool                    `json:"tables"`
+	Barcodes       bool                    `json:"barcodes"`
+	MimeType       string                  `json:"mime_type"`
+	JobID          string                  `json:"job_id"`
+}
+
+type OrganizationOCRJob struct {
+	ID             string                   `json:"id"`
+	OrganizationID int64                    `json:"organization_id"`
+	Status         utils.OCRJobStatusType   `json:"status"`
+	Filename       string                   `json:"filename"`
+	FileHash       string                   `json:"file_hash"`
+	UploadKey      string                   `json:"upload_key"`
+	ResultKey      string                   `json:"result_key"`
+	OCREngine      utils.OCREngineType      `json:"ocr_engine"`
+	Raw            bool                     `json:"raw"`
+	CachePolicy    utils.CachePolicyType    `json:"cache_policy"`
+	TextLayer      utils.TextLayerModeType  `json:"text_layer"`
+	Preprocess     string                   `json:"preprocess"`
+	Languages      string                   `json:"languages"`
+	Tables         bool                     `json:"tables"`
+	Barcodes       bool                     `json:"barcodes"`
+	Extract        string                   `json:"extract"`
+	Format         utils.ResponseFormatType `json:"format"`
+	OutputFormat   utils.OutputFormatType   `json:"output_format"`
+	MimeType       string                   `json:"mime_type"`
+	Pages          string                   `json:"pages"`
+	PagesTotal     int32                    `json:"pages_total"`
+	PagesDone      int32                    `json:"pages_done"`
+	Error          string                   `json:"error"`
+	CreatedAt      time.Time                `json:"created_at"`
+	UpdatedAt      time.Time                `json:"updated_at"`
+	StartedAt      *time.Time               `json:"started_at"`
+	CompletedAt    *time.Time               `json:"completed_at"`
+	// ClaimToken identifies the worker's claim on a job that is processing
+	ClaimToken string `json:"-"`
+}
+
+type OrganizationKeyValueLabel struct {
+	ID             string    `json:"id"`
+	OrganizationID int64     `json:"organization_id"`
+	Key            string    `json:"key"`
+	Synonyms       []string  `json:"synonyms"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+type OrganizationOCRTemplate struct {
+	ID             string               `json:"id"`
+	OrganizationID int64                `json:"organization_id"`
+	Name           string               `json:"name"`
+	OCREngine      utils.OCREngineType  `json:"ocr_engine"`
+	Languages      string               `json:"languages"`
+	Zones          []utils.TemplateZone `json:"zones"`
+	CreatedAt      time.Time            `json:"created_at"`
+	UpdatedAt      time.Time            `json:"updated_at"`
+}
+
+type OrganizationWebhook struct {
+	ID             string    `json:"id"`
+	OrganizationID int64     `json:"organization_id"`
+	URL            string    `json:"url"`
+	Secret         string    `json:"secret"`
+	Enabled        bool      `json:"enabled"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+type OrganizationWebhookDelivery struct {
+	ID             int64                           `json:"id"`
+	WebhookID      string                          `json:"webhook_id"`
+	OrganizationID int64                           `json:"organization_id"`
+	OCRRequestID   *int64                          `json:"ocr_request_id"`
+	Event          string                          `json:"event"`
+	Payload        string                          `json:"payload"`
+	Status         utils.WebhookDeliveryStatusType `json:"status"`
+	Attempts       int32                           `json:"attempts"`
+	ResponseStatus *int32                          `json:"response_status"`
+	Error          string                          `json:"error"`
+	NextAttemptAt  time.Time                       `json:"next_attempt_at"`
+	CreatedAt      time.Time                       `json:"created_at"`
+	DeliveredAt    *time.Time                      `json:"delivered_at"`
+
+	// joined from the webhook for delivery
+	URL    string `json:"-"`
+	Secret string `json:"-"`
 }
@@ -3,6 +3,7 @@ package polar
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log"
 	"os"
 	"serverless-tesseract/models"
@@ -58,6 +59,16 @@ func IngestMeter(
 func CanUserUseOCR(
 	ctx context.Context,
 	organization models.Organization,
+) (bool, error) {
+	return CanUserUseOCRPages(ctx, organization, 1)
+}
+
+// CanUserUseOCRPages checks that the organization can OCR the given number of
+// pages, either with an active subscription or with enough free pages left
+func CanUserUseOCRPages(
+	ctx context.Context,
+	organization models.Organization,
+	pages int,
 ) (bool, error) {
 	polarAccessToken := os.Getenv("POLAR_ACCESS_TOKEN")
 	env := os.Getenv("ENV")
@@ -138,6 +149,9 @@ func CanUserUseOCR(
 	if firstMeter.ConsumedUnits >= float64(utils.POLAR_FREE_PAGE_LIMIT) {
 		return false, errors.New("customer has used up their free pages")
 	}
+	if firstMeter.ConsumedUnits+float64(pages) > float64(utils.POLAR_FREE_PAGE_LIMIT) {
+		return false, fmt.Errorf("customer has %d free pages left", utils.POLAR_FREE_PAGE_LIMIT-int(firstMeter.ConsumedUnits))
+	}
 
 	return true, nil
 }
@@ -2,11 +2,13 @@ package r2
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"serverless-tesseract/utils"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
@@ -94,3 +96,99 @@ func GetObject(document_name string) (body *utils.OCRResponseList, err error) {
 
 	return &ocrResponseList, nil
 }
+
+// UploadFile stores raw file bytes, such as an uploaded document waiting to be processed
+func UploadFile(document_name string, body []byte, contentType string) (err error) {
+	_, err = r2Svc.PutObject(&s3.PutObjectInput{
+		Bucket:      aws.String(utils.R2_BUCKET_NAME),
+		Key:         aws.String(document_name),
+		Body:        bytes.NewReader(body),
+		ContentType: aws.String(contentType),
+	})
+	if err != nil {
+		log.Printf("failed to upload file to S3: %s", err)
+		return fmt.Errorf("failed to upload file to S3: %w", err)
+	}
+
+	return nil
+}
+
+// GetFile returns the raw bytes of an object
+func GetFile(document_name string) (body []byte, err error) {
+	result, err := r2Svc.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(utils.R2_BUCKET_NAME),
+		Key:    aws.String(document_name),
+	})
+	if err != nil {
+		log.Printf("failed to get file from S3: %s", err)
+		return nil, fmt.Errorf("failed to get file from S3: %w", err)
+	}
+	defer result.Body.Close()
+
+	bodyBytes, err := io.ReadAll(result.Body)
+	if err != nil {
+		log.Printf("failed to read file body: %s", err)
+		return nil, fmt.Errorf("failed to read file body: %w", err)
+	}
+
+	return bodyBytes, nil
+}
+
+// PresignGetURL returns a URL the object can be downloaded from without credentials until ttl passes
+func PresignGetURL(document_name string, ttl time.Duration) (string, error) {
+	req, _ := r2Svc.GetObjectRequest(&s3.GetObjectInput{
+		Bucket: aws.String(utils.R2_BUCKET_NAME),
+		Key:    aws.String(document_name),
+	})
+
+	url, err := req.Presign(ttl)
+	if err != nil {
+		log.Printf("failed to presign object URL: %s", err)
+		return "", fmt.Errorf("failed to presign object URL: %w", err)
+	}
+
+	return url, nil
+}
+
+func DeleteObject(document_name string) (err error) {
+	_, err = r2Svc.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(utils.R2_BUCKET_NAME),
+		Key:    aws.String(document_name),
+	})
+	if err != nil {
+		log.Printf("failed to delete object from S3: %s", err)
+		return fmt.Errorf("failed to delete object from S3: %w", err)
+	}
+
+	return nil
+}
+
+// GetBucketFile returns the raw bytes of an object in any bucket of the account,
+// failing with utils.ErrFileTooLarge once the object is larger than limit bytes
+func GetBucketFile(ctx context.Context, bucket string, key string, limit int) (body []byte, err error) {
+	result, err := r2Svc.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		log.Printf("failed to get file from S3: %s", err)
+		return nil, fmt.Errorf("failed to get file from S3: %w", err)
+	}
+	defer result.Body.Close()
+
+	if result.ContentLength != nil && *result.ContentLength > int64(limit) {
+		return nil, utils.ErrFileTooLarge
+	}
+
+	// the length is not trusted, stop reading once the limit is passed
+	bodyBytes, err := io.ReadAll(io.LimitReader(result.Body, int64(limit)+1))
+	if err != nil {
+		log.Printf("failed to read file body: %s", err)
+		return nil, fmt.Errorf("failed to read file body: %w", err)
+	}
+	if len(bodyBytes) > limit {
+		return nil, utils.ErrFileTooLarge
+	}
+
+	return bodyBytes, nil
+}
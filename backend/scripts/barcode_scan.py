@@ -0,0 +1,71 @@
+import sys
+import io
+import json
+from PIL import Image
+import zxingcpp
+from utils.worker import serve
+
+# zxing-cpp formats and the symbology names the service returns for them
+SYMBOLOGIES = {
+    'QRCode': 'qr_code',
+    'MicroQRCode': 'qr_code',
+    'Code128': 'code_128',
+    'EAN13': 'ean_13',
+    'EAN8': 'ean_8',
+    'UPCA': 'upc_a',
+    'UPCE': 'upc_e',
+    'DataMatrix': 'data_matrix',
+    'PDF417': 'pdf417',
+}
+
+def load_model():
+    # zxing-cpp has no model to keep loaded
+    return None
+
+def scan(image_bytes, args, model):
+    page_number = int(args[0])
+
+    image = Image.open(io.BytesIO(image_bytes)).convert('L')
+
+    barcodes = []
+    for result in zxingcpp.read_barcodes(image):
+        symbology = SYMBOLOGIES.get(result.format.name)
+        if symbology is None or not result.valid:
+            continue
+
+        # the corners follow the symbol's orientation, the box is aligned to the page
+        position = result.position
+        corners = [position.top_left, position.top_right, position.bottom_right, position.bottom_left]
+        min_x = min(corner.x for corner in corners)
+        min_y = min(corner.y for corner in corners)
+        max_x = max(corner.x for corner in corners)
+        max_y = max(corner.y for corner in corners)
+
+        barcodes.append({
+            'page_number': page_number,
+            'symbology': symbology,
+            'payload': result.text,
+            'bbox': {
+                'topLeft': {'x': min_x, 'y': min_y},
+                'topRight': {'x': max_x, 'y': min_y},
+                'bottomLeft': {'x': min_x, 'y': max_y},
+                'bottomRight': {'x': max_x, 'y': max_y},
+            },
+        })
+
+    return {'barcodes': barcodes}
+
+def main():
+    # run as a long-lived worker speaking the framed protocol
+    if sys.argv[1] == 'serve':
+        serve(load_model, scan)
+        return
+
+    result = scan(sys.stdin.buffer.read(), sys.argv[1:], load_model())
+
+    # same as return since we are using a pipe
+    print(json.dumps(result))
+
+
+if __name__ == "__main__":
+    main()
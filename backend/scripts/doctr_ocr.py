@@ -1,28 +1,20 @@
 from doctr.io import DocumentFile
-from doctr.models import kie_predictor
+from doctr.models import ocr_predictor
 import json
 import sys
-from utils.tools import compile_raw_response
+from utils.tools import compile_raw_response, parse_args
+from utils.worker import serve
 
 detection_arch = 'db_resnet50'
 recognition_arch = 'crnn_vgg16_bn'
 
-def main():
-    page_index = sys.argv[1]
-    raw = False
-    if len(sys.argv) > 2:
-        raw = sys.argv[2] == 'raw'
-    
-     # load and download the models
-    if page_index == 'load':
-        _ = kie_predictor(detection_arch, recognition_arch, pretrained=True, detect_orientation=False)
-        return
-    
-    # read image
-    image_bytes = sys.stdin.buffer.read()
-    
-    # load model
-    model = kie_predictor(detection_arch, recognition_arch, pretrained=True, detect_orientation=False)
+def load_model():
+    return ocr_predictor(detection_arch, recognition_arch, pretrained=True, detect_orientation=False)
+
+def ocr(image_bytes, args, model):
+    # the recognition model is not language specific, languages are validated by the
+    # service, which also filters results to the whitelist
+    page_index, raw, _, _ = parse_args(args)
     
     # predict
     doc = DocumentFile.from_images(image_bytes)
@@ -31,51 +23,68 @@ def main():
     # compile data
     data = []
     for page in result.pages:
-        words = page.predictions.get('words', [])
-        pageX, pageY = page.dimensions
-        for word in words:            
-            xmin, ymin = word.geometry[0]
-            xmax, ymax = word.geometry[1]
-            
-            # convert to absolute coordinates
-            xmin = xmin * pageX
-            ymin = ymin * pageY
-            xmax = xmax * pageX
-            ymax = ymax * pageY
-            
-            data.append({
-                'text': word.value,
-                'page_number': int(page_index),
-                'confidence': word.confidence,
-                'bbox': {
-                    'topLeft': {
-                        'x': int(xmin),
-                        'y': int(ymin)
-                    },
-                    'bottomLeft': {
-                        'x': int(xmin),
-                        'y': int(ymax)
-                    }, 
-                    'topRight': {
-                        'x': int(xmax),
-                        'y': int(ymin)
-                    },
-                    'bottomRight': {
-                        'x': int(xmax),
-                        'y': int(ymax)
-                    }
-                }
-            })
+        # dimensions are (height, width)
+        pageY, pageX = page.dimensions
+        for block_index, block in enumerate(page.blocks):
+            for line_index, line in enumerate(block.lines):
+                for word in line.words:
+                    xmin, ymin = word.geometry[0]
+                    xmax, ymax = word.geometry[1]
+
+                    # convert to absolute coordinates
+                    xmin = xmin * pageX
+                    ymin = ymin * pageY
+                    xmax = xmax * pageX
+                    ymax = ymax * pageY
+
+                    data.append({
+                        'text': word.value,
+                        'page_number': int(page_index),
+                        'confidence': word.confidence,
+                        'bbox': {
+                            'topLeft': {
+                                'x': int(xmin),
+                                'y': int(ymin)
+                            },
+                            'bottomLeft': {
+                                'x': int(xmin),
+                                'y': int(ymax)
+                            },
+                            'topRight': {
+                                'x': int(xmax),
+                                'y': int(ymin)
+                            },
+                            'bottomRight': {
+                                'x': int(xmax),
+                                'y': int(ymax)
+                            }
+                        },
+                        # docTR does not detect paragraphs, every block is a single paragraph
+                        'block': block_index + 1,
+                        'paragraph': 1,
+                        'line': line_index + 1
+                    })
     
     if raw:
-        combined_data = compile_raw_response(data, page_index, "DOCTR")
-        print(json.dumps(combined_data))
+        return compile_raw_response(data, page_index, "DOCTR")
+    
+    return {"ocr_responses": data, "engine": "DOCTR", "number_of_tokens": len(data)}
+
+def main():
+     # load and download the models
+    if sys.argv[1] == 'load':
+        _ = load_model()
+        return
+    
+    # run as a long-lived worker speaking the framed protocol
+    if sys.argv[1] == 'serve':
+        serve(load_model, ocr)
         return
     
     # same as return since we are using a pipe
-    print(json.dumps({"ocr_responses": data, "engine": "DOCTR", "number_of_tokens": len(data)}))
+    print(json.dumps(ocr(sys.stdin.buffer.read(), sys.argv[1:], load_model())))
     
     
     
 if __name__ == "__main__":
-    main()
\ No newline at end of file
+    main()
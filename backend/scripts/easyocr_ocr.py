@@ -1,42 +1,34 @@
+import os
 import sys
 import json
 import easyocr
 import easyocr.cli
-from utils.tools import compile_raw_response
+from utils.tools import compile_raw_response, parse_args
+from utils.worker import serve
 
 download_directory = '/tmp/models'
 gpu_enabled = False
 detect_network = 'craft'
 recog_network = 'standard'
-lang_list = ['en']
+# languages loaded up front, set OCR_LANGUAGES_EASYOCR to download more models when building the image
+lang_list = [code.strip() for code in os.environ.get('OCR_LANGUAGES_EASYOCR', 'en').split(',') if code.strip()]
 
-def main():
-    # get page index from argument
-    page_index = sys.argv[1]
-    
-    # load and download the models
-    if page_index == 'load':
-        _ = easyocr.Reader(
-            lang_list=lang_list,
-            gpu=gpu_enabled,
-            model_storage_directory=download_directory,
-            user_network_directory=download_directory,
-            detect_network=detect_network,
-            recog_network=recog_network,
-            detector=True,
-            recognizer=True,
-            verbose=True
-        )
-        return
-    
-    # check if raw argument is provided
-    raw = False
-    if len(sys.argv) > 2:
-        raw = sys.argv[2] == 'raw'
-    
-    image_bytes = sys.stdin.buffer.read()
-    reader = easyocr.Reader(
-        lang_list=lang_list,
+def load_model(download_enabled=False, verbose=False):
+    # a reader is created per combination of languages, the worker is recycled
+    # after PYTHON_WORKER_MAX_REQUESTS requests which bounds how many are kept
+    readers = {}
+    get_reader(readers, lang_list, download_enabled, verbose)
+    return readers
+
+def get_reader(readers, languages, download_enabled=False, verbose=False):
+    key = tuple(languages)
+    if key not in readers:
+        readers[key] = new_reader(languages, download_enabled, verbose)
+    return readers[key]
+
+def new_reader(languages, download_enabled=False, verbose=False):
+    return easyocr.Reader(
+        lang_list=languages,
         gpu=gpu_enabled,
         model_storage_directory=download_directory,
         user_network_directory=download_directory,
@@ -44,43 +36,62 @@ def main():
         recog_network=recog_network,
         detector=True,
         recognizer=True,
-        download_enabled=False,
-        verbose=False
+        download_enabled=download_enabled,
+        verbose=verbose
     )
-    result = reader.readtext(image_bytes, batch_size=5)
+
+def ocr(image_bytes, args, readers):
+    page_index, raw, languages, whitelist = parse_args(args)
+    
+    reader = get_reader(readers, languages or ['en'])
+    result = reader.readtext(image_bytes, batch_size=5, allowlist=whitelist or None)
     data = []
     for bbox, text, confidence in result:
         data.append({
             'text': text,
             'page_number': int(page_index),
+            # easyocr returns the corners clockwise from the top left
             'bbox': {
                 'topLeft':{
                     'x': int(bbox[0][0]),
                     'y': int(bbox[0][1])
                 },
                 'bottomLeft': {
+                    'x': int(bbox[3][0]),
+                    'y': int(bbox[3][1])
+                },
+                'topRight': {
                     'x': int(bbox[1][0]),
                     'y': int(bbox[1][1])
                 },
-                'topRight': {
+                'bottomRight': {
                     'x': int(bbox[2][0]),
                     'y': int(bbox[2][1])
-                },
-                'bottomRight': {
-                    'x': int(bbox[3][0]),
-                    'y': int(bbox[3][1])
                 }
             },
             'confidence': confidence
         })
     
     if raw:
-        combined_data = compile_raw_response(data, page_index, "EASYOCR")
-        print(json.dumps(combined_data))
+        return compile_raw_response(data, page_index, "EASYOCR")
+    
+    return {"ocr_responses": data, "engine": "EASYOCR", "number_of_tokens": len(data)}
+
+def main():
+    # load and download the models
+    if sys.argv[1] == 'load':
+        _ = load_model(download_enabled=True, verbose=True)
         return
     
+    # run as a long-lived worker speaking the framed protocol
+    if sys.argv[1] == 'serve':
+        serve(load_model, ocr)
+        return
+    
+    result = ocr(sys.stdin.buffer.read(), sys.argv[1:], load_model())
+    
     # same as return since we are using a pipe
-    print(json.dumps({"ocr_responses": data, "engine": "EASYOCR", "number_of_tokens": len(data)}))
+    print(json.dumps(result))
 
 
 if __name__ == "__main__":
@@ -1,25 +1,30 @@
 import sys
 import io
 import json
+import shlex
 from PIL import Image
 import pytesseract
 from pytesseract import Output
-from utils.tools import compile_raw_response
+from utils.tools import compile_raw_response, parse_args
+from utils.worker import serve
 
-def main():
-    # get page index from argument
-    page_index = sys.argv[1]
-    
-    # check if raw argument is provided
-    raw = False
-    if len(sys.argv) > 2:
-        raw = sys.argv[2] == 'raw'
+def load_model():
+    # tesseract is invoked as a binary per image, there is no model to keep loaded
+    return None
+
+def ocr(image_bytes, args, model):
+    page_index, raw, languages, whitelist = parse_args(args)
     
-    image_bytes = io.BytesIO(sys.stdin.buffer.read())
-    image = Image.open(image_bytes)
+    image = Image.open(io.BytesIO(image_bytes))
+
+    # tesseract combines languages with "+", the first is the primary language
+    lang = '+'.join(languages) if languages else 'eng'
+
+    # only the whitelisted characters are recognized when a whitelist is given
+    config = '-c tessedit_char_whitelist=' + shlex.quote(whitelist) if whitelist else ''
 
     data = []
-    d = pytesseract.image_to_data(image , output_type=Output.DICT)
+    d = pytesseract.image_to_data(image , lang=lang, config=config, output_type=Output.DICT)
     for i in range(len(d['text'])):
         if d['text'][i] != '':
             width = d['width'][i]
@@ -45,16 +50,28 @@ def main():
                         'y': d['top'][i] + height
                     }
                 },
-                'confidence': d['conf'][i]/100
+                'confidence': d['conf'][i]/100,
+                # tesseract numbers paragraphs within a block and lines within a paragraph
+                'block': d['block_num'][i],
+                'paragraph': d['par_num'][i],
+                'line': d['line_num'][i]
                 })
             
     if raw:
-        combined_data = compile_raw_response(data, page_index, "TESSERACT")
-        print(json.dumps(combined_data))
+        return compile_raw_response(data, page_index, "TESSERACT")
+    
+    return {"ocr_responses": data, "engine": "TESSERACT", "number_of_tokens": len(data)}
+
+def main():
+    # run as a long-lived worker speaking the framed protocol
+    if sys.argv[1] == 'serve':
+        serve(load_model, ocr)
         return
+
+    result = ocr(sys.stdin.buffer.read(), sys.argv[1:], load_model())
     
     # same as return since we are using a pipe
-    print(json.dumps({"ocr_responses": data, "engine": "TESSERACT", "number_of_tokens": len(data)}))
+    print(json.dumps(result))
 
 
 if __name__ == "__main__":
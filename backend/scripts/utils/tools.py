@@ -1,3 +1,19 @@
+def parse_args(args):
+    # args are the page index followed by optional flags: "raw", "lang=<codes>"
+    # and "whitelist=<characters>"
+    page_index = args[0]
+    raw = False
+    languages = []
+    whitelist = ''
+    for arg in args[1:]:
+        if arg == 'raw':
+            raw = True
+        elif arg.startswith('lang='):
+            languages = [code for code in arg[len('lang='):].split(',') if code]
+        elif arg.startswith('whitelist='):
+            whitelist = arg[len('whitelist='):]
+    return page_index, raw, languages, whitelist
+
 def compile_raw_response(data, page_index, engine_name) -> dict:
      # combine data into a single response
     min_x = min([d['bbox']['topLeft']['x'] for d in data])
@@ -0,0 +1,56 @@
+import json
+import struct
+import sys
+import traceback
+
+# Frames are a 4 byte big-endian length followed by that many bytes. Every
+# request is a JSON header frame, optionally followed by an image frame, and is
+# answered with a single JSON frame.
+
+
+def read_frame(stream):
+    header = stream.read(4)
+    if len(header) < 4:
+        return None
+    (length,) = struct.unpack('>I', header)
+    data = stream.read(length)
+    if len(data) < length:
+        return None
+    return data
+
+
+def write_frame(stream, data):
+    stream.write(struct.pack('>I', len(data)))
+    stream.write(data)
+    stream.flush()
+
+
+def serve(load_model, ocr):
+    stdin = sys.stdin.buffer
+    stdout = sys.stdout.buffer
+    # anything printed by the engines must not end up in the response frames
+    sys.stdout = sys.stderr
+
+    model = load_model()
+
+    while True:
+        header = read_frame(stdin)
+        if header is None:
+            return
+
+        request = json.loads(header)
+        if request.get('type') == 'ping':
+            write_frame(stdout, json.dumps({'type': 'pong'}).encode())
+            continue
+
+        image_bytes = read_frame(stdin)
+        if image_bytes is None:
+            return
+
+        try:
+            result = ocr(image_bytes, request.get('args', []), model)
+        except Exception as e:
+            traceback.print_exc()
+            result = {'error': str(e)}
+
+        write_frame(stdout, json.dumps(result).encode())
@@ -0,0 +1,46 @@
+package services
+
+import (
+	"context"
+	externalscripts "serverless-tesseract/services/external_scripts"
+	"serverless-tesseract/utils"
+	"strconv"
+	"sync"
+)
+
+// barcodeScript decodes the barcodes of a page with zxing-cpp
+const barcodeScript = "barcode_scan.py"
+
+var (
+	barcodePoolOnce sync.Once
+	barcodePool     *externalscripts.Pool
+)
+
+// detectBarcodes decodes the QR, Code 128, EAN, UPC, Data Matrix and PDF417
+// symbols on the page image. Their bounding boxes are in pixels of the image,
+// the same space as the bounding boxes of the words.
+//
+// The script runs in a pool of persistent workers like the engines do, sized with
+// PYTHON_WORKER_POOL_SIZE_BARCODES, or in a new python process per page when the
+// pool is disabled.
+func detectBarcodes(ctx context.Context, imageBytes []byte, pageNumber int) ([]utils.Barcode, error) {
+	barcodePoolOnce.Do(func() {
+		size := utils.GetEnvInt("PYTHON_WORKER_POOL_SIZE_BARCODES", utils.PYTHON_WORKER_POOL_SIZE)
+		if size > 0 {
+			barcodePool = externalscripts.NewPool(barcodeScript, size, utils.PYTHON_WORKER_MAX_REQUESTS)
+		}
+	})
+
+	var results utils.OCRResponseList
+	var err error
+	if barcodePool != nil {
+		results, err = barcodePool.Execute(ctx, imageBytes, strconv.Itoa(pageNumber))
+	} else {
+		results, err = externalscripts.ExecutePythonOCREngineScript(ctx, barcodeScript, imageBytes, strconv.Itoa(pageNumber))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return results.Barcodes, nil
+}
@@ -11,6 +11,11 @@ func GetCacheResult(
 	organizationId int64,
 	ocrEngine string,
 	raw bool,
+	textLayer utils.TextLayerModeType,
+	preprocess string,
+	languages string,
+	tables bool,
+	barcodes bool,
 ) (results *utils.OCRResponseList, cache_hit bool, err error) {
 	// if cache policy is no cache, return nil
 	if cache_policy == utils.NoCache {
@@ -18,7 +23,7 @@ func GetCacheResult(
 	}
 
 	// get the cache result from the database
-	cacheResult, err := db.GetFileHashCache(fileHash, organizationId, raw, ocrEngine)
+	cacheResult, err := db.GetFileHashCache(fileHash, organizationId, raw, ocrEngine, string(textLayer), preprocess, languages, tables, barcodes)
 	if err != nil || (cacheResult == nil && cache_policy == utils.CacheOnly) {
 		return nil, false, err
 	}
@@ -29,3 +34,52 @@ func GetCacheResult(
 
 	return cacheResult, true, nil
 }
+
+// SelectPages narrows cached results of a whole document down to the selected
+// pages. Only whole documents are cached, so any selection can be served from the cache.
+func SelectPages(results *utils.OCRResponseList, selection utils.PageSelection) *utils.OCRResponseList {
+	if results == nil || len(selection) == 0 {
+		return results
+	}
+
+	selected := *results
+	// artifacts stored next to the whole document do not apply to the selection
+	selected.DocumentKey = ""
+	selected.OCRResponses = []utils.OCRResponse{}
+	for _, response := range results.OCRResponses {
+		if selection.Includes(response.PageNumber) {
+			selected.OCRResponses = append(selected.OCRResponses, response)
+		}
+	}
+	selected.NumberOfTokens = int64(len(selected.OCRResponses))
+
+	selected.PageLanguages = nil
+	for _, pageLanguage := range results.PageLanguages {
+		if selection.Includes(pageLanguage.PageNumber) {
+			selected.PageLanguages = append(selected.PageLanguages, pageLanguage)
+		}
+	}
+
+	selected.PageSizes = nil
+	for _, pageSize := range results.PageSizes {
+		if selection.Includes(pageSize.PageNumber) {
+			selected.PageSizes = append(selected.PageSizes, pageSize)
+		}
+	}
+
+	selected.Tables = nil
+	for _, table := range results.Tables {
+		if selection.Includes(table.PageNumber) {
+			selected.Tables = append(selected.Tables, table)
+		}
+	}
+
+	selected.Barcodes = nil
+	for _, barcode := range results.Barcodes {
+		if selection.Includes(barcode.PageNumber) {
+			selected.Barcodes = append(selected.Barcodes, barcode)
+		}
+	}
+
+	return &selected
+}
@@ -0,0 +1,82 @@
+package services
+
+import (
+	"context"
+	"serverless-tesseract/utils"
+	"sync"
+)
+
+// globalPageSlots bounds the number of pages OCR'd at once across the service
+var globalPageSlots = make(chan struct{}, utils.OCR_MAX_CONCURRENT_PAGES)
+
+// organizationLimiter bounds the number of pages an organization has OCR'd at
+// once across all of its requests, so one organization cannot take every slot
+type organizationLimiter struct {
+	limit int
+	slots chan struct{}
+	// users counts the pages holding or waiting for a slot, a limiter without
+	// users is dropped so organizations that stopped sending pages are forgotten
+	users int
+}
+
+var (
+	organizationLimitersMu sync.Mutex
+	organizationLimiters   = map[int64]*organizationLimiter{}
+)
+
+// useOrganizationLimiter returns the organization's limiter for the limit, a
+// new limiter replaces the current one when the organization's limit changed
+func useOrganizationLimiter(organizationID int64, limit int) *organizationLimiter {
+	organizationLimitersMu.Lock()
+	defer organizationLimitersMu.Unlock()
+
+	limiter, ok := organizationLimiters[organizationID]
+	if !ok || limiter.limit != limit {
+		// pages holding a slot of a replaced limiter release it to the old channel
+		limiter = &organizationLimiter{limit: limit, slots: make(chan struct{}, limit)}
+		organizationLimiters[organizationID] = limiter
+	}
+	limiter.users++
+	return limiter
+}
+
+// releaseOrganizationLimiter ends a use of the limiter, dropping it once it has no users
+func releaseOrganizationLimiter(organizationID int64, limiter *organizationLimiter) {
+	organizationLimitersMu.Lock()
+	defer organizationLimitersMu.Unlock()
+
+	limiter.users--
+	if limiter.users == 0 && organizationLimiters[organizationID] == limiter {
+		delete(organizationLimiters, organizationID)
+	}
+}
+
+// acquirePageSlot waits for a slot under the organization and global limits,
+// the returned function releases it
+func acquirePageSlot(ctx context.Context, organizationID int64, limit int) (func(), error) {
+	if limit < 1 {
+		limit = 1
+	}
+	limiter := useOrganizationLimiter(organizationID, limit)
+
+	select {
+	case limiter.slots <- struct{}{}:
+	case <-ctx.Done():
+		releaseOrganizationLimiter(organizationID, limiter)
+		return nil, ctx.Err()
+	}
+
+	select {
+	case globalPageSlots <- struct{}{}:
+	case <-ctx.Done():
+		<-limiter.slots
+		releaseOrganizationLimiter(organizationID, limiter)
+		return nil, ctx.Err()
+	}
+
+	return func() {
+		<-globalPageSlots
+		<-limiter.slots
+		releaseOrganizationLimiter(organizationID, limiter)
+	}, nil
+}
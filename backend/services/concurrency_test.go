@@ -0,0 +1,82 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// withGlobalPageSlots replaces the global page limit for a test
+func withGlobalPageSlots(t *testing.T, size int) {
+	t.Helper()
+	slots := globalPageSlots
+	globalPageSlots = make(chan struct{}, size)
+	t.Cleanup(func() { globalPageSlots = slots })
+}
+
+func TestAcquirePageSlotOrganizationLimit(t *testing.T) {
+	withGlobalPageSlots(t, 4)
+	release, err := acquirePageSlot(context.Background(), 1, 1)
+	if err != nil {
+		t.Fatalf("acquirePageSlot() error = %v", err)
+	}
+
+	// the organization's only slot is taken
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := acquirePageSlot(ctx, 1, 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("acquirePageSlot() over the limit error = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	// other organizations have their own slots
+	other, err := acquirePageSlot(context.Background(), 2, 1)
+	if err != nil {
+		t.Fatalf("acquirePageSlot() for another organization error = %v", err)
+	}
+	other()
+
+	release()
+	if len(organizationLimiters) != 0 {
+		t.Errorf("%d limiters are kept after every slot was released, want 0", len(organizationLimiters))
+	}
+}
+
+func TestAcquirePageSlotLimitChange(t *testing.T) {
+	withGlobalPageSlots(t, 4)
+	release, err := acquirePageSlot(context.Background(), 1, 1)
+	if err != nil {
+		t.Fatalf("acquirePageSlot() error = %v", err)
+	}
+	defer release()
+
+	// a raised limit applies right away
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	raised, err := acquirePageSlot(ctx, 1, 2)
+	if err != nil {
+		t.Fatalf("acquirePageSlot() with a raised limit error = %v", err)
+	}
+	raised()
+}
+
+func TestAcquirePageSlotGlobalLimit(t *testing.T) {
+	withGlobalPageSlots(t, 1)
+
+	release, err := acquirePageSlot(context.Background(), 1, 4)
+	if err != nil {
+		t.Fatalf("acquirePageSlot() error = %v", err)
+	}
+
+	// the organization's limit is not reached but the service's is
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := acquirePageSlot(ctx, 2, 4); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("acquirePageSlot() over the global limit error = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	release()
+	if len(organizationLimiters) != 0 {
+		t.Errorf("%d limiters are kept after every slot was released, want 0", len(organizationLimiters))
+	}
+}
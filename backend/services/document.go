@@ -0,0 +1,436 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"serverless-tesseract/services/engines"
+	externalscripts "serverless-tesseract/services/external_scripts"
+	"serverless-tesseract/services/preprocess"
+	"serverless-tesseract/utils"
+	"sync"
+)
+
+// DocumentOptions configure how OCRDocument processes a file
+type DocumentOptions struct {
+	Engine utils.OCREngineType
+	Raw    bool
+	// Pages selects the pages to OCR, every page is processed when it is empty
+	Pages utils.PageSelection
+	// TextLayer controls whether text is read from a PDF's embedded text layer
+	// instead of being OCR'd, images have no text layer
+	TextLayer utils.TextLayerModeType
+	// Preprocess are the cleanup steps applied to every page image before OCR
+	Preprocess preprocess.Steps
+	// Languages are the ISO 639-1 codes passed to the engine, see engines.ResolveLanguages.
+	// engines.AutoLanguage detects the languages of every page, see detectPageLanguages
+	Languages []string
+	// Tables detects the tables on every page, see detectTables
+	Tables bool
+	// Barcodes decodes the barcodes on every page, see detectBarcodes
+	Barcodes bool
+
+	// pages are OCR'd concurrently, at most PageConcurrency at a time across all
+	// of the organization's requests and OCR_MAX_CONCURRENT_PAGES across the service
+	OrganizationID  int64
+	PageConcurrency int
+
+	// OnPage, when set, is called after each page completes
+	OnPage func(pagesDone int32, pagesTotal int32)
+	// OnPageResult, when set, is called with the results of each page as soon as
+	// it is OCR'd or read from its text layer, in the order the pages complete.
+	// It is called while the results are recorded and must not block.
+	OnPageResult func(pageNumber int, results utils.OCRResponseList)
+}
+
+// ValidatePageSelection checks that the selected pages exist in the file, returning
+// an error wrapping utils.ErrPageOutOfRange when they do not
+func ValidatePageSelection(fileBytes []byte, selection utils.PageSelection) error {
+	if len(selection) == 0 {
+		return nil
+	}
+
+	_, err := CountSelectedPages(fileBytes, selection)
+	return err
+}
+
+// CountSelectedPages returns the number of pages of the file the selection selects,
+// every page when it is empty. An error wrapping utils.ErrPageOutOfRange is
+// returned when the selection refers to pages the file does not have.
+func CountSelectedPages(fileBytes []byte, selection utils.PageSelection) (int, error) {
+	fileType, err := DetectFileType(fileBytes)
+	if err != nil {
+		return 0, err
+	}
+
+	numPages := 1
+	switch fileType {
+	case FileTypePDF:
+		count, err := CountPDFPages(&fileBytes)
+		if err != nil {
+			return 0, fmt.Errorf("failed to process PDF: %w", err)
+		}
+		numPages = count
+	case FileTypeTIFF:
+		count, err := CountTIFFPages(fileBytes)
+		if err != nil {
+			return 0, fmt.Errorf("failed to process TIFF: %w", err)
+		}
+		numPages = count
+	}
+
+	pages, err := selection.Resolve(numPages)
+	return len(pages), err
+}
+
+// OCRDocument runs OCR over every page of a file. The format is detected from the
+// file's content with DetectFileType. PDFs are rendered page by page with
+// StreamPDF, TIFFs are split into pages with StreamTIFF and other images are
+// treated as a single page. The number of pages that were processed is returned
+// alongside any error so that partial work can still be recorded.
+//
+// Every page must finish within OCR_PAGE_TIMEOUT and the whole document within
+// OCR_DOCUMENT_TIMEOUT, otherwise a *utils.TimeoutError is returned. Cancelling
+// the context, for example when the client disconnects, stops processing.
+func OCRDocument(
+	ctx context.Context,
+	fileBytes []byte,
+	opts DocumentOptions,
+) (utils.OCRResponseList, int32, error) {
+	ctx, cancel := context.WithTimeout(ctx, utils.OCR_DOCUMENT_TIMEOUT)
+	defer cancel()
+
+	allResults := utils.OCRResponseList{
+		Engine: opts.Engine,
+		Raw:    opts.Raw,
+	}
+
+	fileType, err := DetectFileType(fileBytes)
+	if err != nil {
+		return allResults, 0, err
+	}
+
+	if fileType != FileTypePDF && opts.TextLayer == utils.TextLayerOnly {
+		// images have no text layer
+		allResults.OCRResponses = []utils.OCRResponse{}
+		return allResults, 0, nil
+	}
+
+	pages, pagesTotal, err := renderPages(ctx, fileBytes, fileType, opts)
+	if err != nil {
+		return allResults, 0, err
+	}
+
+	pageResults, processed, err := ocrPages(ctx, pages, int32(pagesTotal), opts)
+	for _, pageResult := range pageResults {
+		allResults.OCRResponses = append(allResults.OCRResponses, pageResult.OCRResponses...)
+		allResults.NumberOfTokens += pageResult.NumberOfTokens
+		allResults.PageLanguages = append(allResults.PageLanguages, pageResult.PageLanguages...)
+		allResults.PageSizes = append(allResults.PageSizes, pageResult.PageSizes...)
+		allResults.Tables = append(allResults.Tables, pageResult.Tables...)
+		allResults.Barcodes = append(allResults.Barcodes, pageResult.Barcodes...)
+	}
+
+	return allResults, processed, err
+}
+
+// renderPages streams the selected pages of the file. PDFs are rendered page by
+// page with StreamPDF, TIFFs are split into pages with StreamTIFF and other
+// images are a single page. The number of selected pages is returned up front.
+func renderPages(ctx context.Context, fileBytes []byte, fileType FileType, opts DocumentOptions) (<-chan RenderedPage, int, error) {
+	switch fileType {
+	case FileTypePDF:
+		// render the pdf into image pages as they are needed
+		pages, numPages, err := StreamPDF(ctx, &fileBytes, opts, utils.PDF_MAX_IN_FLIGHT_PAGES)
+		if errors.Is(err, utils.ErrPageOutOfRange) {
+			return nil, 0, err
+		}
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to process PDF: %w", documentError(ctx, err))
+		}
+		return pages, numPages, nil
+	case FileTypeTIFF:
+		pages, numPages, err := StreamTIFF(ctx, fileBytes, opts, utils.PDF_MAX_IN_FLIGHT_PAGES)
+		if errors.Is(err, utils.ErrPageOutOfRange) {
+			return nil, 0, err
+		}
+		if err != nil {
+			return nil, 0, fmt.Errorf("%w: %v", utils.ErrInvalidFileType, err)
+		}
+		return pages, numPages, nil
+	default:
+		if _, err := opts.Pages.Resolve(1); err != nil {
+			return nil, 0, err
+		}
+		image, err := NormalizeImage(fileBytes, fileType)
+		if err != nil {
+			return nil, 0, fmt.Errorf("%w: %v", utils.ErrInvalidFileType, err)
+		}
+		pages := make(chan RenderedPage, 1)
+		pages <- RenderedPage{Number: 1, Image: image}
+		close(pages)
+		return pages, 1, nil
+	}
+}
+
+// ocrPages runs OCR on the pages concurrently as they arrive and returns the
+// results in page order. The first failure cancels the pages that are still
+// outstanding. The returned count includes every page OCR finished on,
+// including the page that failed, but not the pages that were cancelled.
+func ocrPages(ctx context.Context, pages <-chan RenderedPage, pagesTotal int32, opts DocumentOptions) ([]utils.OCRResponseList, int32, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	concurrency := opts.PageConcurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	var (
+		mu        sync.Mutex
+		wg        sync.WaitGroup
+		results   = make([]utils.OCRResponseList, pagesTotal)
+		processed int32
+		firstErr  error
+	)
+	sem := make(chan struct{}, concurrency)
+
+	fail := func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if firstErr == nil {
+			firstErr = err
+			cancel()
+		}
+	}
+
+receive:
+	for {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			break receive
+		}
+
+		var page RenderedPage
+		var ok bool
+		select {
+		case page, ok = <-pages:
+		case <-ctx.Done():
+		}
+		if !ok || ctx.Err() != nil {
+			page.Release()
+			break
+		}
+
+		if page.Err != nil {
+			page.Release()
+			fail(fmt.Errorf("failed to process document: %w", documentError(ctx, page.Err)))
+			break
+		}
+
+		if page.TextLayer != nil && page.Image == nil {
+			// the page was read from the text layer, there is nothing to OCR. Text
+			// layers have no page image, so only tables without rulings are found
+			if opts.Tables {
+				page.TextLayer.Tables = detectTables(page.TextLayer.OCRResponses, pageRulings{}, page.Number)
+			}
+			mu.Lock()
+			results[page.index] = *page.TextLayer
+			processed++
+			if opts.OnPage != nil && firstErr == nil {
+				opts.OnPage(processed, pagesTotal)
+			}
+			if opts.OnPageResult != nil && firstErr == nil {
+				opts.OnPageResult(page.Number, *page.TextLayer)
+			}
+			mu.Unlock()
+
+			<-sem
+			page.Release()
+			continue
+		}
+
+		wg.Add(1)
+		go func(page RenderedPage) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			defer page.Release()
+
+			var pageResults utils.OCRResponseList
+			var err error
+			if page.TextLayer != nil {
+				// the page was read from the text layer and rendered only to find its barcodes
+				pageResults, err = readTextLayerPage(ctx, page, opts)
+			} else {
+				pageResults, err = runPageOCR(ctx, page.Image, opts, page.Number)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if err != nil {
+				// pages cancelled because another page failed were not processed
+				if firstErr == nil {
+					processed++
+					if pagesTotal == 1 {
+						firstErr = fmt.Errorf("failed to OCR page: %w", err)
+					} else {
+						firstErr = fmt.Errorf("failed to OCR page %d: %w", page.Number, err)
+					}
+					cancel()
+				}
+				return
+			}
+
+			results[page.index] = pageResults
+			processed++
+			if opts.OnPage != nil && firstErr == nil {
+				opts.OnPage(processed, pagesTotal)
+			}
+			if opts.OnPageResult != nil && firstErr == nil {
+				opts.OnPageResult(page.Number, pageResults)
+			}
+		}(page)
+	}
+	wg.Wait()
+
+	if firstErr == nil && ctx.Err() != nil {
+		// the document was cancelled before every page was started
+		firstErr = documentError(ctx, ctx.Err())
+	}
+
+	return results, processed, firstErr
+}
+
+// runPageOCR preprocesses a single page and runs OCR on it under the per page
+// deadline, waiting for a slot under the organization and global page limits
+// first. The deadline starts once the page gets a worker of the engine.
+func runPageOCR(ctx context.Context, imageBytes []byte, opts DocumentOptions, pageNumber int) (utils.OCRResponseList, error) {
+	release, err := acquirePageSlot(ctx, opts.OrganizationID, opts.PageConcurrency)
+	if err != nil {
+		return utils.OCRResponseList{}, documentError(ctx, err)
+	}
+	defer release()
+
+	pageCtx, cancel := externalscripts.WithWorkerDeadline(ctx, utils.OCR_PAGE_TIMEOUT)
+	defer cancel()
+
+	// bounding boxes are reported relative to the page as it was uploaded
+	pageSize, err := imageSize(imageBytes)
+	if err != nil {
+		return utils.OCRResponseList{}, fmt.Errorf("failed to read page size: %w", err)
+	}
+	pageSize.PageNumber = pageNumber
+
+	// ruling lines are found on the page as it was uploaded, like the bounding boxes
+	var rulings pageRulings
+	if opts.Tables {
+		rulings, err = detectRulings(imageBytes)
+		if err != nil {
+			return utils.OCRResponseList{}, fmt.Errorf("failed to detect ruling lines: %w", err)
+		}
+	}
+
+	originalImage := imageBytes
+	imageBytes, transform, err := preprocess.Apply(imageBytes, opts.Preprocess)
+	if err != nil {
+		return utils.OCRResponseList{}, fmt.Errorf("failed to preprocess page: %w", err)
+	}
+
+	languages := opts.Languages
+	var detected *utils.PageLanguage
+	if len(languages) == 1 && languages[0] == engines.AutoLanguage {
+		pageLanguages, pageLanguage := detectPageLanguages(pageCtx, imageBytes, opts.Engine, pageNumber)
+		languages, detected = pageLanguages, &pageLanguage
+	}
+
+	results, err := RunOCR(pageCtx, imageBytes, opts.Engine, pageNumber, opts.Raw, languages, "")
+	results.PageSizes = []utils.PageSize{pageSize}
+	if detected != nil && err == nil {
+		detected.Language = detectTextLanguage(results, languages)
+		results.PageLanguages = []utils.PageLanguage{*detected}
+	}
+	for i := range results.OCRResponses {
+		results.OCRResponses[i].Source = utils.SourceOCR
+		// report positions on the page as it was uploaded, not the oriented,
+		// upscaled or deskewed copy
+		results.OCRResponses[i].BBox = transformBBox(results.OCRResponses[i].BBox, transform)
+	}
+	if opts.Tables && err == nil {
+		results.Tables = detectTables(results.OCRResponses, rulings, pageNumber)
+	}
+	if opts.Barcodes && err == nil {
+		// barcodes are decoded on the page as it was uploaded, like the bounding boxes
+		results.Barcodes, err = detectBarcodes(pageCtx, originalImage, pageNumber)
+		if err != nil {
+			err = fmt.Errorf("failed to detect barcodes: %w", err)
+		}
+	}
+	if err != nil && ctx.Err() == nil && errors.Is(context.Cause(pageCtx), context.DeadlineExceeded) {
+		return results, &utils.TimeoutError{Scope: "page", PageNumber: pageNumber, Timeout: utils.OCR_PAGE_TIMEOUT}
+	}
+	if err != nil {
+		return results, documentError(ctx, err)
+	}
+	return results, nil
+}
+
+// readTextLayerPage returns the words of a page read from the text layer along
+// with the barcodes decoded from its rendered image, waiting for a slot under the
+// organization and global page limits first
+func readTextLayerPage(ctx context.Context, page RenderedPage, opts DocumentOptions) (utils.OCRResponseList, error) {
+	results := *page.TextLayer
+	if opts.Tables {
+		results.Tables = detectTables(results.OCRResponses, pageRulings{}, page.Number)
+	}
+
+	release, err := acquirePageSlot(ctx, opts.OrganizationID, opts.PageConcurrency)
+	if err != nil {
+		return results, documentError(ctx, err)
+	}
+	defer release()
+
+	pageCtx, cancel := externalscripts.WithWorkerDeadline(ctx, utils.OCR_PAGE_TIMEOUT)
+	defer cancel()
+
+	results.Barcodes, err = detectBarcodes(pageCtx, page.Image, page.Number)
+	if err != nil && ctx.Err() == nil && errors.Is(context.Cause(pageCtx), context.DeadlineExceeded) {
+		return results, &utils.TimeoutError{Scope: "page", PageNumber: page.Number, Timeout: utils.OCR_PAGE_TIMEOUT}
+	}
+	if err != nil {
+		return results, documentError(ctx, fmt.Errorf("failed to detect barcodes: %w", err))
+	}
+	return results, nil
+}
+
+// documentError replaces err with a *utils.TimeoutError when the document deadline has passed
+func documentError(ctx context.Context, err error) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return &utils.TimeoutError{Scope: "document", Timeout: utils.OCR_DOCUMENT_TIMEOUT}
+	}
+	return err
+}
+
+// transformBBox maps the corners of a box on the preprocessed page to the page
+// as it was uploaded. A box that was rotated by deskewing is no longer aligned
+// to the page, so the box that encloses its corners is returned.
+func transformBBox(box utils.BBox, transform preprocess.Transform) utils.BBox {
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, corner := range []utils.XY{box.TopLeft, box.TopRight, box.BottomRight, box.BottomLeft} {
+		x, y := transform.Point(float64(corner.X), float64(corner.Y))
+		minX, minY = math.Min(minX, x), math.Min(minY, y)
+		maxX, maxY = math.Max(maxX, x), math.Max(maxY, y)
+	}
+
+	left, top := int(math.Round(minX)), int(math.Round(minY))
+	right, bottom := int(math.Round(maxX)), int(math.Round(maxY))
+	return utils.BBox{
+		TopLeft:     utils.XY{X: left, Y: top},
+		BottomLeft:  utils.XY{X: left, Y: bottom},
+		TopRight:    utils.XY{X: right, Y: top},
+		BottomRight: utils.XY{X: right, Y: bottom},
+	}
+}
@@ -0,0 +1,48 @@
+package services
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"serverless-tesseract/services/preprocess"
+	"serverless-tesseract/utils"
+	"testing"
+)
+
+func TestTransformBBox(t *testing.T) {
+	// a 100x50 page is upscaled by PREPROCESS_UPSCALE_MAX_FACTOR
+	var page bytes.Buffer
+	if err := png.Encode(&page, image.NewGray(image.Rect(0, 0, 100, 50))); err != nil {
+		t.Fatal(err)
+	}
+	_, upscaled, err := preprocess.Apply(page.Bytes(), preprocess.Steps{preprocess.Upscale})
+	if err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+
+	// the corners of a box turned by deskewing
+	turned := utils.BBox{
+		TopLeft:     utils.XY{X: 10, Y: 20},
+		TopRight:    utils.XY{X: 50, Y: 12},
+		BottomRight: utils.XY{X: 53, Y: 30},
+		BottomLeft:  utils.XY{X: 12, Y: 38},
+	}
+
+	tests := []struct {
+		name      string
+		box       utils.BBox
+		transform preprocess.Transform
+		want      utils.BBox
+	}{
+		{"no preprocessing", newBBox(10, 20, 30, 40), preprocess.Transform{}, newBBox(10, 20, 30, 40)},
+		{"upscaled", newBBox(30, 15, 90, 60), upscaled, newBBox(10, 5, 30, 20)},
+		{"rounded", newBBox(31, 16, 89, 61), upscaled, newBBox(10, 5, 30, 20)},
+		{"encloses the corners", turned, preprocess.Transform{}, newBBox(10, 12, 53, 38)},
+	}
+
+	for _, tt := range tests {
+		if got := transformBBox(tt.box, tt.transform); got != tt.want {
+			t.Errorf("%s: transformBBox(%v) = %v, want %v", tt.name, tt.box, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,17 @@
+package engines
+
+import "serverless-tesseract/utils"
+
+func init() {
+	Register(&pythonEngine{
+		name:   utils.EngineDoctoR,
+		script: "doctr_ocr.py",
+		capabilities: Capabilities{
+			WordBoxes:  true,
+			Confidence: true,
+			Layout:     true,
+		},
+		// the pretrained recognition model's vocabulary covers english and french
+		languages: []string{"en", "fr"},
+	})
+}
@@ -0,0 +1,16 @@
+package engines
+
+import "serverless-tesseract/utils"
+
+func init() {
+	Register(&pythonEngine{
+		name:   utils.EngineEasyOCR,
+		script: "easyocr_ocr.py",
+		capabilities: Capabilities{
+			WordBoxes:  true,
+			Confidence: true,
+			Layout:     false,
+		},
+		languages: []string{"en"},
+	})
+}
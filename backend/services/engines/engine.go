@@ -0,0 +1,102 @@
+package engines
+
+import (
+	"context"
+	"serverless-tesseract/utils"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Capabilities describes what an engine's results contain
+type Capabilities struct {
+	// results include a bounding box for every word
+	WordBoxes bool `json:"word_boxes"`
+	// results include a per word confidence score
+	Confidence bool `json:"confidence"`
+	// the engine detects layout such as blocks and lines
+	Layout bool `json:"layout"`
+}
+
+// Options are the per page options passed to an engine
+type Options struct {
+	PageNumber int
+	Raw        bool
+	// Languages are ISO 639-1 codes resolved with ResolveLanguages, the first
+	// is the primary language
+	Languages []string
+	// Whitelist, when set, are the only characters the engine should recognize.
+	// Engines that do not support it return all characters.
+	Whitelist string
+}
+
+// Engine is an OCR engine that can be registered with the service. Engine names
+// are stored in the "OCREngine" postgres enum, so adding an engine also requires
+// a migration adding its name to the enum.
+type Engine interface {
+	Name() utils.OCREngineType
+	Capabilities() Capabilities
+	// SupportedLanguages returns the ISO 639-1 codes of the installed languages
+	SupportedLanguages() []string
+	Recognize(ctx context.Context, image []byte, opts Options) (utils.OCRResponseList, error)
+}
+
+var (
+	mu       sync.RWMutex
+	registry = map[utils.OCREngineType]Engine{}
+)
+
+// Register makes an engine available. Engines are usually registered from an
+// init function. When OCR_ENGINES is set only the engines it lists are
+// registered, which allows deployments to disable engines they do not install.
+func Register(engine Engine) {
+	if !isEnabled(engine.Name()) {
+		return
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if _, exists := registry[engine.Name()]; exists {
+		panic("engines: engine registered twice: " + string(engine.Name()))
+	}
+	registry[engine.Name()] = engine
+	utils.OCREngineValues = append(utils.OCREngineValues, engine.Name())
+}
+
+// Get returns the registered engine with the given name
+func Get(name utils.OCREngineType) (Engine, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	engine, ok := registry[name]
+	return engine, ok
+}
+
+// All returns the registered engines sorted by name
+func All() []Engine {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	engines := make([]Engine, 0, len(registry))
+	for _, engine := range registry {
+		engines = append(engines, engine)
+	}
+	sort.Slice(engines, func(i, j int) bool {
+		return engines[i].Name() < engines[j].Name()
+	})
+	return engines
+}
+
+func isEnabled(name utils.OCREngineType) bool {
+	if utils.OCR_ENGINES == "" {
+		return true
+	}
+
+	for _, enabled := range strings.Split(utils.OCR_ENGINES, ",") {
+		if strings.EqualFold(strings.TrimSpace(enabled), string(name)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,73 @@
+package engines
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultLanguage is used when a request does not select any languages
+const DefaultLanguage = "en"
+
+// AutoLanguage selects the languages of every page from the script it is written
+// in, see DetectScript
+const AutoLanguage = "auto"
+
+// ResolveLanguages checks that the engine has every requested language installed
+// and returns them lowercased and without duplicates, keeping the order they were
+// requested in since some engines treat the first language as the primary one.
+// When no languages are requested DefaultLanguage is used, or the engine's first
+// language if it does not have DefaultLanguage. AutoLanguage must be requested on its own.
+func ResolveLanguages(engine Engine, requested []string) ([]string, error) {
+	supported := engine.SupportedLanguages()
+
+	var auto bool
+	var count int
+	for _, language := range requested {
+		language = strings.TrimSpace(language)
+		if language != "" {
+			count++
+		}
+		if strings.EqualFold(language, AutoLanguage) {
+			auto = true
+		}
+	}
+	if auto && count > 1 {
+		return nil, fmt.Errorf("%q cannot be combined with other languages", AutoLanguage)
+	}
+	if auto {
+		return []string{AutoLanguage}, nil
+	}
+
+	if len(requested) == 0 {
+		if len(supported) == 0 || contains(supported, DefaultLanguage) {
+			return []string{DefaultLanguage}, nil
+		}
+		return []string{supported[0]}, nil
+	}
+
+	var languages []string
+	for _, language := range requested {
+		language = strings.ToLower(strings.TrimSpace(language))
+		if language == "" || contains(languages, language) {
+			continue
+		}
+		if !contains(supported, language) {
+			return nil, fmt.Errorf("language %q is not installed for engine %s (installed: %s)", language, engine.Name(), strings.Join(supported, ", "))
+		}
+		languages = append(languages, language)
+	}
+
+	if len(languages) == 0 {
+		return ResolveLanguages(engine, nil)
+	}
+	return languages, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
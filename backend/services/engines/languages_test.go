@@ -0,0 +1,75 @@
+package engines
+
+import (
+	"context"
+	"serverless-tesseract/utils"
+	"slices"
+	"testing"
+)
+
+// fakeEngine is an engine with a fixed list of installed languages
+type fakeEngine struct {
+	languages []string
+}
+
+func (e fakeEngine) Name() utils.OCREngineType { return "FAKE" }
+
+func (e fakeEngine) Capabilities() Capabilities { return Capabilities{} }
+
+func (e fakeEngine) SupportedLanguages() []string { return e.languages }
+
+func (e fakeEngine) Recognize(context.Context, []byte, Options) (utils.OCRResponseList, error) {
+	return utils.OCRResponseList{}, nil
+}
+
+func TestResolveLanguages(t *testing.T) {
+	installed := fakeEngine{languages: []string{"de", "en", "fr"}}
+
+	tests := []struct {
+		name      string
+		engine    fakeEngine
+		requested []string
+		want      []string
+	}{
+		{"default", installed, nil, []string{"en"}},
+		{"default without installed languages", fakeEngine{}, nil, []string{"en"}},
+		{"first installed without default", fakeEngine{languages: []string{"ja", "ko"}}, nil, []string{"ja"}},
+		{"blank only", installed, []string{"", " "}, []string{"en"}},
+		{"keeps requested order", installed, []string{"fr", "de"}, []string{"fr", "de"}},
+		{"normalizes and deduplicates", installed, []string{" FR", "fr", "De ", ""}, []string{"fr", "de"}},
+		{"auto", installed, []string{"auto"}, []string{AutoLanguage}},
+		{"auto any case", installed, []string{" Auto "}, []string{AutoLanguage}},
+		{"auto with blanks", installed, []string{"auto", ""}, []string{AutoLanguage}},
+	}
+
+	for _, tt := range tests {
+		got, err := ResolveLanguages(tt.engine, tt.requested)
+		if err != nil {
+			t.Errorf("%s: ResolveLanguages(%q) error = %v", tt.name, tt.requested, err)
+			continue
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: ResolveLanguages(%q) = %q, want %q", tt.name, tt.requested, got, tt.want)
+		}
+	}
+}
+
+func TestResolveLanguagesInvalid(t *testing.T) {
+	installed := fakeEngine{languages: []string{"de", "en", "fr"}}
+
+	tests := []struct {
+		name      string
+		requested []string
+	}{
+		{"not installed", []string{"ja"}},
+		{"one not installed", []string{"en", "ja"}},
+		{"auto with languages", []string{"auto", "en"}},
+		{"auto twice", []string{"auto", "AUTO"}},
+	}
+
+	for _, tt := range tests {
+		if got, err := ResolveLanguages(installed, tt.requested); err == nil {
+			t.Errorf("%s: ResolveLanguages(%q) = %q, want an error", tt.name, tt.requested, got)
+		}
+	}
+}
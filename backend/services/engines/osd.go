@@ -0,0 +1,90 @@
+package engines
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// scriptLanguages maps the scripts reported by tesseract's orientation and script
+// detection to the ISO 639-1 codes of the languages written in them
+var scriptLanguages = map[string][]string{
+	"Latin":      {"en", "de", "fr", "es", "it", "pt", "nl", "pl", "cs", "sk", "sl", "hr", "hu", "ro", "sv", "da", "no", "fi", "et", "lt", "lv", "tr", "id", "vi", "af"},
+	"Cyrillic":   {"ru", "uk", "bg", "sr"},
+	"Greek":      {"el"},
+	"Arabic":     {"ar", "fa"},
+	"Hebrew":     {"he"},
+	"Devanagari": {"hi"},
+	"Thai":       {"th"},
+	"Han":        {"zh"},
+	"Japanese":   {"ja"},
+	"Korean":     {"ko"},
+	"Hangul":     {"ko"},
+}
+
+// DetectScript runs tesseract's orientation and script detection on the image and
+// returns the name of the script the page is written in, such as "Latin" or
+// "Cyrillic", and tesseract's confidence in it. Pages with too little text fail.
+func DetectScript(ctx context.Context, image []byte) (string, float64, error) {
+	cmd := exec.CommandContext(ctx, "tesseract", "stdin", "stdout", "--psm", "0")
+	cmd.Stdin = bytes.NewReader(image)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to detect script: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	var script string
+	var confidence float64
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(key) {
+		case "Script":
+			script = value
+		case "Script confidence":
+			confidence, _ = strconv.ParseFloat(value, 64)
+		}
+	}
+
+	if script == "" {
+		return "", 0, fmt.Errorf("failed to detect script: %s", strings.TrimSpace(stderr.String()))
+	}
+	return script, confidence, nil
+}
+
+// LanguagesForScript returns up to max of the engine's installed languages that are
+// written in the script, with DefaultLanguage first when it is one of them. When
+// the engine has none of them the default languages are returned.
+func LanguagesForScript(engine Engine, script string, max int) []string {
+	supported := engine.SupportedLanguages()
+
+	var languages []string
+	if contains(scriptLanguages[script], DefaultLanguage) && contains(supported, DefaultLanguage) {
+		languages = append(languages, DefaultLanguage)
+	}
+	for _, language := range scriptLanguages[script] {
+		if len(languages) >= max {
+			break
+		}
+		if contains(supported, language) && !contains(languages, language) {
+			languages = append(languages, language)
+		}
+	}
+
+	if len(languages) == 0 {
+		languages, _ = ResolveLanguages(engine, nil)
+	}
+	return languages
+}
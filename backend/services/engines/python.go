@@ -0,0 +1,110 @@
+package engines
+
+import (
+	"context"
+	"log"
+	externalscripts "serverless-tesseract/services/external_scripts"
+	"serverless-tesseract/utils"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// pythonEngine is an engine implemented by one of the OCR scripts. Pages are
+// sent to a pool of persistent workers running the script in "serve" mode, or
+// to a new python process per page when the pool is disabled.
+type pythonEngine struct {
+	name         utils.OCREngineType
+	script       string
+	capabilities Capabilities
+	languages    []string
+	// detectLanguages, when set, lists the languages installed on this machine,
+	// languages is used when it fails
+	detectLanguages func() ([]string, error)
+	// scriptLanguages, when set, translates ISO 639-1 codes to the codes the script expects
+	scriptLanguages map[string]string
+
+	languagesOnce sync.Once
+	installed     []string
+
+	poolOnce sync.Once
+	pool     *externalscripts.Pool
+}
+
+// workerPool returns the engine's pool of persistent workers, creating it on
+// first use so engines that are never used do not start any processes. nil is
+// returned when the pool is disabled.
+func (e *pythonEngine) workerPool() *externalscripts.Pool {
+	e.poolOnce.Do(func() {
+		size := utils.GetEnvInt("PYTHON_WORKER_POOL_SIZE_"+string(e.name), utils.PYTHON_WORKER_POOL_SIZE)
+		if size > 0 {
+			e.pool = externalscripts.NewPool(e.script, size, utils.PYTHON_WORKER_MAX_REQUESTS)
+		}
+	})
+	return e.pool
+}
+
+func (e *pythonEngine) Name() utils.OCREngineType {
+	return e.name
+}
+
+func (e *pythonEngine) Capabilities() Capabilities {
+	return e.capabilities
+}
+
+// SupportedLanguages returns the languages listed in OCR_LANGUAGES_<ENGINE> when
+// it is set, otherwise the languages the engine detects or its defaults
+func (e *pythonEngine) SupportedLanguages() []string {
+	e.languagesOnce.Do(func() {
+		e.installed = e.languages
+
+		if configured := utils.GetEnv("OCR_LANGUAGES_"+string(e.name), ""); configured != "" {
+			e.installed = nil
+			for _, language := range strings.Split(configured, ",") {
+				if language = strings.ToLower(strings.TrimSpace(language)); language != "" {
+					e.installed = append(e.installed, language)
+				}
+			}
+			return
+		}
+
+		if e.detectLanguages != nil {
+			detected, err := e.detectLanguages()
+			if err != nil {
+				log.Printf("Failed to detect installed languages for %s, using %v: %v", e.name, e.languages, err)
+				return
+			}
+			if len(detected) > 0 {
+				sort.Strings(detected)
+				e.installed = detected
+			}
+		}
+	})
+	return e.installed
+}
+
+func (e *pythonEngine) Recognize(ctx context.Context, image []byte, opts Options) (utils.OCRResponseList, error) {
+	args := []string{strconv.Itoa(opts.PageNumber)}
+	if opts.Raw {
+		args = append(args, "raw")
+	}
+	if len(opts.Languages) > 0 {
+		codes := make([]string, len(opts.Languages))
+		for i, language := range opts.Languages {
+			codes[i] = language
+			if code, ok := e.scriptLanguages[language]; ok {
+				codes[i] = code
+			}
+		}
+		args = append(args, "lang="+strings.Join(codes, ","))
+	}
+	if opts.Whitelist != "" {
+		args = append(args, "whitelist="+opts.Whitelist)
+	}
+
+	if pool := e.workerPool(); pool != nil {
+		return pool.Execute(ctx, image, args...)
+	}
+	return externalscripts.ExecutePythonOCREngineScript(ctx, e.script, image, args...)
+}
@@ -0,0 +1,89 @@
+package engines
+
+import (
+	"fmt"
+	"os/exec"
+	"serverless-tesseract/utils"
+	"strings"
+)
+
+// tesseractLanguageCodes maps ISO 639-1 codes to the names of tesseract's
+// traineddata files
+var tesseractLanguageCodes = map[string]string{
+	"af": "afr",
+	"ar": "ara",
+	"bg": "bul",
+	"cs": "ces",
+	"da": "dan",
+	"de": "deu",
+	"el": "ell",
+	"en": "eng",
+	"es": "spa",
+	"et": "est",
+	"fa": "fas",
+	"fi": "fin",
+	"fr": "fra",
+	"he": "heb",
+	"hi": "hin",
+	"hr": "hrv",
+	"hu": "hun",
+	"id": "ind",
+	"it": "ita",
+	"ja": "jpn",
+	"ko": "kor",
+	"lt": "lit",
+	"lv": "lav",
+	"nl": "nld",
+	"no": "nor",
+	"pl": "pol",
+	"pt": "por",
+	"ro": "ron",
+	"ru": "rus",
+	"sk": "slk",
+	"sl": "slv",
+	"sr": "srp",
+	"sv": "swe",
+	"th": "tha",
+	"tr": "tur",
+	"uk": "ukr",
+	"vi": "vie",
+	"zh": "chi_sim",
+}
+
+func init() {
+	Register(&pythonEngine{
+		name:   utils.EngineTesseract,
+		script: "tesseract_ocr.py",
+		capabilities: Capabilities{
+			WordBoxes:  true,
+			Confidence: true,
+			Layout:     true,
+		},
+		languages:       []string{"en"},
+		detectLanguages: installedTesseractLanguages,
+		scriptLanguages: tesseractLanguageCodes,
+	})
+}
+
+// installedTesseractLanguages lists the traineddata files tesseract has installed
+// and returns the ISO 639-1 codes of the ones in tesseractLanguageCodes
+func installedTesseractLanguages() ([]string, error) {
+	output, err := exec.Command("tesseract", "--list-langs").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tesseract languages: %w", err)
+	}
+
+	installed := map[string]bool{}
+	// the first line is a header naming the tessdata directory
+	for _, line := range strings.Split(string(output), "\n") {
+		installed[strings.TrimSpace(line)] = true
+	}
+
+	var languages []string
+	for iso, traineddata := range tesseractLanguageCodes {
+		if installed[traineddata] {
+			languages = append(languages, iso)
+		}
+	}
+	return languages, nil
+}
@@ -0,0 +1,67 @@
+package export
+
+import (
+	"fmt"
+	"serverless-tesseract/utils"
+	"strings"
+)
+
+// renderALTO renders the results as an ALTO 4 document measured in pixels.
+// Blocks become ComposedBlocks and paragraphs the TextBlocks inside them.
+func renderALTO(results utils.StructuredOCRResponse) []byte {
+	var b strings.Builder
+
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<alto xmlns="http://www.loc.gov/standards/alto/ns-v4#" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:schemaLocation="http://www.loc.gov/standards/alto/ns-v4# http://www.loc.gov/alto/v4/alto-4-2.xsd">
+ <Description>
+  <MeasurementUnit>pixel</MeasurementUnit>
+  <OCRProcessing ID="OCR_0">
+   <ocrProcessingStep>
+    <processingSoftware>
+`)
+	fmt.Fprintf(&b, "     <softwareName>%s</softwareName>\n", escape(string(results.Engine)))
+	b.WriteString(`    </processingSoftware>
+   </ocrProcessingStep>
+  </OCRProcessing>
+ </Description>
+ <Layout>
+`)
+
+	for _, page := range results.Pages {
+		p := page.PageNumber
+		fmt.Fprintf(&b, "  <Page ID=\"page_%d\" PHYSICAL_IMG_NR=\"%d\" WIDTH=\"%d\" HEIGHT=\"%d\">\n", p, p, page.Width, page.Height)
+		fmt.Fprintf(&b, "   <PrintSpace HPOS=\"0\" VPOS=\"0\" WIDTH=\"%d\" HEIGHT=\"%d\">\n", page.Width, page.Height)
+
+		for i, block := range page.Blocks {
+			fmt.Fprintf(&b, "    <ComposedBlock ID=\"block_%d_%d\" %s>\n", p, i+1, altoPosition(block.BBox))
+
+			for j, paragraph := range block.Paragraphs {
+				fmt.Fprintf(&b, "     <TextBlock ID=\"par_%d_%d_%d\" %s>\n", p, i+1, j+1, altoPosition(paragraph.BBox))
+
+				for k, line := range paragraph.Lines {
+					fmt.Fprintf(&b, "      <TextLine ID=\"line_%d_%d_%d_%d\" %s>\n", p, i+1, j+1, k+1, altoPosition(line.BBox))
+
+					for l, word := range line.Words {
+						if l > 0 {
+							b.WriteString("       <SP/>\n")
+						}
+						fmt.Fprintf(&b, "       <String ID=\"word_%d_%d_%d_%d_%d\" CONTENT=\"%s\" %s WC=\"%.2f\"/>\n",
+							p, i+1, j+1, k+1, l+1, escape(word.Text), altoPosition(word.BBox), word.Confidence)
+					}
+					b.WriteString("      </TextLine>\n")
+				}
+				b.WriteString("     </TextBlock>\n")
+			}
+			b.WriteString("    </ComposedBlock>\n")
+		}
+		b.WriteString("   </PrintSpace>\n  </Page>\n")
+	}
+
+	b.WriteString(" </Layout>\n</alto>\n")
+	return []byte(b.String())
+}
+
+func altoPosition(box utils.BBox) string {
+	left, top, right, bottom := bboxCorners(box)
+	return fmt.Sprintf("HPOS=\"%d\" VPOS=\"%d\" WIDTH=\"%d\" HEIGHT=\"%d\"", left, top, right-left, bottom-top)
+}
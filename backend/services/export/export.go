@@ -0,0 +1,169 @@
+package export
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"log"
+	"serverless-tesseract/r2"
+	"serverless-tesseract/services"
+	"serverless-tesseract/utils"
+	"strings"
+	"time"
+)
+
+// Document is the results rendered in one of the output formats
+type Document struct {
+	Body        []byte
+	ContentType string
+	// Key is the R2 key the document is stored at, empty when it is not stored
+	Key string
+}
+
+// ContentType returns the media type of documents rendered in the format
+func ContentType(format utils.OutputFormatType, results utils.StructuredOCRResponse) string {
+	switch format {
+	case utils.OutputHOCR:
+		return "text/html; charset=utf-8"
+	case utils.OutputALTO:
+		return "application/xml; charset=utf-8"
+	case utils.OutputPageXML:
+		if len(results.Pages) > 1 {
+			return "application/zip"
+		}
+		return "application/xml; charset=utf-8"
+	case utils.OutputText:
+		return "text/plain; charset=utf-8"
+	case utils.OutputTSV:
+		return "text/tab-separated-values; charset=utf-8"
+	case utils.OutputSearchablePDF:
+		return "application/pdf"
+	default:
+		return "application/json; charset=utf-8"
+	}
+}
+
+// Extension returns the file extension documents in the format are stored with
+func Extension(format utils.OutputFormatType) string {
+	if format == utils.OutputSearchablePDF {
+		return ".pdf"
+	}
+	return "." + string(format)
+}
+
+// Render converts the structured results to one of the document formats, except
+// for searchable PDFs which are built from the original file by Artifact. PAGE
+// XML describes a single page, so multi-page documents are rendered as a ZIP
+// with one PAGE XML file per page.
+func Render(results utils.StructuredOCRResponse, format utils.OutputFormatType) ([]byte, error) {
+	switch format {
+	case utils.OutputHOCR:
+		return renderHOCR(results), nil
+	case utils.OutputALTO:
+		return renderALTO(results), nil
+	case utils.OutputPageXML:
+		return renderPageXML(results)
+	case utils.OutputText:
+		return renderText(results), nil
+	case utils.OutputTSV:
+		return renderTSV(results), nil
+	default:
+		return nil, fmt.Errorf("unsupported output format: %s", format)
+	}
+}
+
+// Artifact returns the results rendered in the format. Results that were stored
+// in R2, whole documents in the cache and job results, keep their rendered
+// artifacts next to them, so an artifact that was rendered before is served from
+// R2 instead of being rendered again.
+//
+// Searchable PDFs are drawn over the original file, fileBytes, which is only
+// needed when the artifact has not been stored yet.
+func Artifact(ctx context.Context, results utils.OCRResponseList, format utils.OutputFormatType, fileBytes []byte) (Document, error) {
+	structured := services.StructureResults(results)
+	document := Document{
+		ContentType: ContentType(format, structured),
+		Key:         artifactKey(results.DocumentKey, format),
+	}
+
+	if document.Key != "" {
+		if artifact, err := r2.GetFile(document.Key); err == nil {
+			document.Body = artifact
+			return document, nil
+		}
+	}
+
+	var err error
+	if format == utils.OutputSearchablePDF {
+		if fileBytes == nil {
+			return Document{}, errors.New("the original file is no longer available")
+		}
+		document.Body, err = renderSearchablePDF(ctx, fileBytes, results)
+	} else {
+		document.Body, err = Render(structured, format)
+	}
+	if err != nil {
+		return Document{}, err
+	}
+
+	if document.Key != "" {
+		if err := r2.UploadFile(document.Key, document.Body, document.ContentType); err != nil {
+			log.Printf("Failed to store %s artifact %s: %v", format, document.Key, err)
+			document.Key = ""
+		}
+	}
+
+	return document, nil
+}
+
+// Store uploads a document that is not stored next to cached or job results to
+// exports/ in R2, setting its Key
+func Store(document Document, format utils.OutputFormatType, organizationID int64) (Document, error) {
+	if document.Key != "" {
+		return document, nil
+	}
+
+	key := fmt.Sprintf("exports/%d/%s%s", organizationID, utils.GenerateID(), Extension(format))
+	if err := r2.UploadFile(key, document.Body, document.ContentType); err != nil {
+		return document, err
+	}
+	document.Key = key
+	return document, nil
+}
+
+// Link returns a download link to the document that expires after EXPORT_LINK_TTL,
+// storing the document first when it is not stored yet
+func Link(document Document, format utils.OutputFormatType, organizationID int64) (string, time.Time, error) {
+	document, err := Store(document, format, organizationID)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	expiresAt := time.Now().Add(utils.EXPORT_LINK_TTL)
+	url, err := r2.PresignGetURL(document.Key, utils.EXPORT_LINK_TTL)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return url, expiresAt, nil
+}
+
+// artifactKey is the R2 key of the format's artifact of the results stored at documentKey
+func artifactKey(documentKey string, format utils.OutputFormatType) string {
+	if documentKey == "" {
+		return ""
+	}
+	return strings.TrimSuffix(documentKey, ".json") + Extension(format)
+}
+
+// escape escapes text for use in XML content and attribute values
+func escape(text string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(text))
+	return b.String()
+}
+
+// bboxCorners returns the left, top, right and bottom edges of the box
+func bboxCorners(box utils.BBox) (int, int, int, int) {
+	return box.TopLeft.X, box.TopLeft.Y, box.BottomRight.X, box.BottomRight.Y
+}
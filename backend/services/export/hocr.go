@@ -0,0 +1,64 @@
+package export
+
+import (
+	"fmt"
+	"math"
+	"serverless-tesseract/utils"
+	"strings"
+)
+
+// renderHOCR renders the results as an hOCR 1.2 XHTML document, with the
+// ocr_page, ocr_carea, ocr_par, ocr_line and ocrx_word elements Tesseract emits
+func renderHOCR(results utils.StructuredOCRResponse) []byte {
+	var b strings.Builder
+
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html PUBLIC "-//W3C//DTD XHTML 1.0 Transitional//EN" "http://www.w3.org/TR/xhtml1/DTD/xhtml1-transitional.dtd">
+<html xmlns="http://www.w3.org/1999/xhtml" xml:lang="en" lang="en">
+ <head>
+  <title></title>
+  <meta http-equiv="Content-Type" content="text/html;charset=utf-8"/>
+`)
+	fmt.Fprintf(&b, "  <meta name=\"ocr-system\" content=\"%s\"/>\n", escape(string(results.Engine)))
+	b.WriteString(`  <meta name="ocr-capabilities" content="ocr_page ocr_carea ocr_par ocr_line ocrx_word"/>
+ </head>
+ <body>
+`)
+
+	for _, page := range results.Pages {
+		p := page.PageNumber
+		fmt.Fprintf(&b, "  <div class=\"ocr_page\" id=\"page_%d\" title=\"bbox 0 0 %d %d; ppageno %d\">\n", p, page.Width, page.Height, p-1)
+
+		for i, block := range page.Blocks {
+			fmt.Fprintf(&b, "   <div class=\"ocr_carea\" id=\"block_%d_%d\" title=\"%s\">\n", p, i+1, hocrBBox(block.BBox))
+
+			for j, paragraph := range block.Paragraphs {
+				fmt.Fprintf(&b, "    <p class=\"ocr_par\" id=\"par_%d_%d_%d\" title=\"%s\">\n", p, i+1, j+1, hocrBBox(paragraph.BBox))
+
+				for k, line := range paragraph.Lines {
+					fmt.Fprintf(&b, "     <span class=\"ocr_line\" id=\"line_%d_%d_%d_%d\" title=\"%s\">", p, i+1, j+1, k+1, hocrBBox(line.BBox))
+
+					for l, word := range line.Words {
+						if l > 0 {
+							b.WriteString(" ")
+						}
+						fmt.Fprintf(&b, "<span class=\"ocrx_word\" id=\"word_%d_%d_%d_%d_%d\" title=\"%s; x_wconf %d\">%s</span>",
+							p, i+1, j+1, k+1, l+1, hocrBBox(word.BBox), int(math.Round(word.Confidence*100)), escape(word.Text))
+					}
+					b.WriteString("</span>\n")
+				}
+				b.WriteString("    </p>\n")
+			}
+			b.WriteString("   </div>\n")
+		}
+		b.WriteString("  </div>\n")
+	}
+
+	b.WriteString(" </body>\n</html>\n")
+	return []byte(b.String())
+}
+
+func hocrBBox(box utils.BBox) string {
+	left, top, right, bottom := bboxCorners(box)
+	return fmt.Sprintf("bbox %d %d %d %d", left, top, right, bottom)
+}
@@ -0,0 +1,88 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"serverless-tesseract/utils"
+	"strings"
+	"time"
+)
+
+// renderPageXML renders every page as a PAGE XML (2019-07-15) document with a
+// TextRegion per paragraph. A single page is returned as is, more pages are
+// returned as a ZIP of page_0001.xml, page_0002.xml and so on.
+func renderPageXML(results utils.StructuredOCRResponse) ([]byte, error) {
+	created := time.Now().UTC().Format(time.RFC3339)
+
+	if len(results.Pages) == 1 {
+		return renderPageXMLPage(results.Pages[0], results.Engine, created), nil
+	}
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for _, page := range results.Pages {
+		file, err := archive.Create(fmt.Sprintf("page_%04d.xml", page.PageNumber))
+		if err != nil {
+			return nil, fmt.Errorf("failed to add page %d to archive: %w", page.PageNumber, err)
+		}
+		if _, err := file.Write(renderPageXMLPage(page, results.Engine, created)); err != nil {
+			return nil, fmt.Errorf("failed to add page %d to archive: %w", page.PageNumber, err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write archive: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func renderPageXMLPage(page utils.StructuredPage, engine utils.OCREngineType, created string) []byte {
+	var b strings.Builder
+	p := page.PageNumber
+
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<PcGts xmlns="http://schema.primaresearch.org/PAGE/gts/pagecontent/2019-07-15" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:schemaLocation="http://schema.primaresearch.org/PAGE/gts/pagecontent/2019-07-15 http://schema.primaresearch.org/PAGE/gts/pagecontent/2019-07-15/pagecontent.xsd">
+ <Metadata>
+`)
+	fmt.Fprintf(&b, "  <Creator>%s</Creator>\n  <Created>%s</Created>\n  <LastChange>%s</LastChange>\n", escape(string(engine)), created, created)
+	b.WriteString(" </Metadata>\n")
+	fmt.Fprintf(&b, " <Page imageFilename=\"page_%d\" imageWidth=\"%d\" imageHeight=\"%d\">\n", p, page.Width, page.Height)
+
+	for i, block := range page.Blocks {
+		for j, paragraph := range block.Paragraphs {
+			fmt.Fprintf(&b, "  <TextRegion id=\"r_%d_%d\" type=\"paragraph\">\n", i+1, j+1)
+			fmt.Fprintf(&b, "   <Coords points=\"%s\"/>\n", pagePoints(paragraph.BBox))
+
+			for k, line := range paragraph.Lines {
+				fmt.Fprintf(&b, "   <TextLine id=\"l_%d_%d_%d\">\n", i+1, j+1, k+1)
+				fmt.Fprintf(&b, "    <Coords points=\"%s\"/>\n", pagePoints(line.BBox))
+
+				for l, word := range line.Words {
+					fmt.Fprintf(&b, "    <Word id=\"w_%d_%d_%d_%d\">\n", i+1, j+1, k+1, l+1)
+					fmt.Fprintf(&b, "     <Coords points=\"%s\"/>\n", pagePoints(word.BBox))
+					fmt.Fprintf(&b, "     <TextEquiv conf=\"%.2f\">\n      <Unicode>%s</Unicode>\n     </TextEquiv>\n", word.Confidence, escape(word.Text))
+					b.WriteString("    </Word>\n")
+				}
+
+				fmt.Fprintf(&b, "    <TextEquiv conf=\"%.2f\">\n     <Unicode>%s</Unicode>\n    </TextEquiv>\n", line.Confidence, escape(line.Text))
+				b.WriteString("   </TextLine>\n")
+			}
+
+			fmt.Fprintf(&b, "   <TextEquiv conf=\"%.2f\">\n    <Unicode>%s</Unicode>\n   </TextEquiv>\n", paragraph.Confidence, escape(paragraph.Text))
+			b.WriteString("  </TextRegion>\n")
+		}
+	}
+
+	b.WriteString(" </Page>\n</PcGts>\n")
+	return []byte(b.String())
+}
+
+// pagePoints returns the corners of the box as a PAGE XML point list
+func pagePoints(box utils.BBox) string {
+	return fmt.Sprintf("%d,%d %d,%d %d,%d %d,%d",
+		box.TopLeft.X, box.TopLeft.Y,
+		box.TopRight.X, box.TopRight.Y,
+		box.BottomRight.X, box.BottomRight.Y,
+		box.BottomLeft.X, box.BottomLeft.Y)
+}
@@ -0,0 +1,265 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"serverless-tesseract/services"
+	"serverless-tesseract/utils"
+
+	"github.com/unidoc/unipdf/v3/contentstream"
+	"github.com/unidoc/unipdf/v3/core"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// searchableFontName is the resource name of the font the invisible text is drawn with
+const searchableFontName = core.PdfObjectName("OCRText")
+
+// renderSearchablePDF returns the original document with an invisible text layer
+// drawn over every OCR'd page, so the text can be selected and searched. PDF pages
+// are kept as they are, images become pages of their own sized at PDF_RENDER_DPI.
+// Words are drawn in Helvetica, characters outside its encoding are not searchable.
+func renderSearchablePDF(ctx context.Context, fileBytes []byte, results utils.OCRResponseList) ([]byte, error) {
+	fileType, err := services.DetectFileType(fileBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	font, err := model.NewStandard14Font(model.HelveticaName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load font: %w", err)
+	}
+
+	pageWords := map[int][]utils.OCRResponse{}
+	for _, response := range results.OCRResponses {
+		// pages read from the text layer are already searchable
+		if response.Source == utils.SourceTextLayer {
+			continue
+		}
+		pageWords[response.PageNumber] = append(pageWords[response.PageNumber], response)
+	}
+	pageSizes := map[int]utils.PageSize{}
+	for _, size := range results.PageSizes {
+		pageSizes[size.PageNumber] = size
+	}
+
+	var pages []*model.PdfPage
+	switch fileType {
+	case services.FileTypePDF:
+		pages, err = pdfPages(fileBytes)
+	case services.FileTypeTIFF:
+		pages, err = tiffImagePages(ctx, fileBytes)
+	default:
+		pages, err = imagePages(fileBytes, fileType)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	writer := model.NewPdfWriter()
+	for i, page := range pages {
+		pageNumber := i + 1
+		if words := pageWords[pageNumber]; len(words) > 0 {
+			if err := addTextLayer(page, font, words, pageSizes[pageNumber]); err != nil {
+				return nil, fmt.Errorf("failed to add text to page %d: %w", pageNumber, err)
+			}
+		}
+		if err := writer.AddPage(page); err != nil {
+			return nil, fmt.Errorf("failed to add page %d: %w", pageNumber, err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := writer.Write(&buf); err != nil {
+		return nil, fmt.Errorf("failed to write PDF: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// addTextLayer draws the words over the page in render mode 3, which is neither
+// filled nor stroked. Bounding boxes are in pixels of the page at size, which is
+// mapped onto the page as it is displayed, cropped to its CropBox and turned by
+// its /Rotate, and every word is scaled to fill its box and written in the
+// direction it is read on the displayed page.
+func addTextLayer(page *model.PdfPage, font *model.PdfFont, words []utils.OCRResponse, size utils.PageSize) error {
+	geometry, err := services.NewPageGeometry(page)
+	if err != nil {
+		return err
+	}
+
+	scaleX := 72.0 / utils.PDF_RENDER_DPI
+	scaleY := scaleX
+	if size.Width > 0 && size.Height > 0 {
+		width, height := geometry.Size()
+		scaleX = width / float64(size.Width)
+		scaleY = height / float64(size.Height)
+	}
+
+	// the directions of the displayed page's x axis and of up in user space
+	originX, originY := geometry.ToUserSpace(0, 0)
+	rightX, rightY := geometry.ToUserSpace(1, 0)
+	upX, upY := geometry.ToUserSpace(0, -1)
+	rightX, rightY = rightX-originX, rightY-originY
+	upX, upY = upX-originX, upY-originY
+
+	if page.Resources == nil {
+		page.Resources = model.NewPdfPageResources()
+	}
+	if err := page.Resources.SetFontByName(searchableFontName, font.ToPdfObject()); err != nil {
+		return err
+	}
+
+	cc := contentstream.NewContentCreator()
+	cc.Add_q()
+	cc.Add_BT()
+	cc.Add_Tr(3)
+	for _, word := range words {
+		encoded, _ := font.StringToCharcodeBytes(word.Text)
+		if len(encoded) == 0 {
+			continue
+		}
+
+		// edges of the word on the displayed page, in points
+		left := float64(word.BBox.TopLeft.X) * scaleX
+		right := float64(word.BBox.BottomRight.X) * scaleX
+		top := float64(word.BBox.TopLeft.Y) * scaleY
+		bottom := float64(word.BBox.BottomRight.Y) * scaleY
+
+		fontSize := bottom - top
+		if fontSize <= 0 {
+			continue
+		}
+
+		// stretch the word horizontally to the width of its box
+		var textWidth float64
+		for _, r := range word.Text {
+			if metrics, ok := font.GetRuneMetrics(r); ok {
+				textWidth += metrics.Wx
+			}
+		}
+		textWidth = textWidth * fontSize / 1000
+
+		cc.Add_Tf(searchableFontName, fontSize)
+		if textWidth > 0 {
+			cc.Add_Tz(100 * (right - left) / textWidth)
+		}
+		x, y := geometry.ToUserSpace(left, bottom)
+		cc.Add_Tm(rightX, rightY, upX, upY, x, y)
+		cc.Add_Tj(*core.MakeStringFromBytes(encoded))
+	}
+	cc.Add_ET()
+	cc.Add_Q()
+
+	// the page's own content is wrapped in q and Q so graphics state it leaves
+	// behind does not move the text
+	streams, err := page.GetContentStreams()
+	if err != nil {
+		return err
+	}
+	streams = append(append([]string{"q"}, streams...), "Q", cc.String())
+	return page.SetContentStreams(streams, core.NewFlateEncoder())
+}
+
+// pdfPages returns the pages of the PDF as they are
+func pdfPages(pdfBytes []byte) ([]*model.PdfPage, error) {
+	reader, err := model.NewPdfReader(bytes.NewReader(pdfBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create PDF reader: %w", err)
+	}
+
+	numPages, err := reader.GetNumPages()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get number of pages: %w", err)
+	}
+
+	pages := make([]*model.PdfPage, 0, numPages)
+	for i := 1; i <= numPages; i++ {
+		page, err := reader.GetPage(i)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get page %d: %w", i, err)
+		}
+		pages = append(pages, page)
+	}
+	return pages, nil
+}
+
+// tiffImagePages returns a page for every image in the TIFF
+func tiffImagePages(ctx context.Context, tiffBytes []byte) ([]*model.PdfPage, error) {
+	rendered, _, err := services.StreamTIFF(ctx, tiffBytes, services.DocumentOptions{}, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	var pages []*model.PdfPage
+	var pageErr error
+	for page := range rendered {
+		if page.Err != nil && pageErr == nil {
+			pageErr = page.Err
+		}
+		if page.Err == nil && pageErr == nil {
+			pdfPage, err := newImagePage(page.Image)
+			if err != nil {
+				pageErr = err
+			}
+			pages = append(pages, pdfPage)
+		}
+		page.Release()
+	}
+	if pageErr != nil {
+		return nil, pageErr
+	}
+	return pages, nil
+}
+
+// imagePages returns a single page holding the image
+func imagePages(imageBytes []byte, fileType services.FileType) ([]*model.PdfPage, error) {
+	normalized, err := services.NormalizeImage(imageBytes, fileType)
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := newImagePage(normalized)
+	if err != nil {
+		return nil, err
+	}
+	return []*model.PdfPage{page}, nil
+}
+
+// newImagePage creates a page sized to show the PNG or JPEG image at PDF_RENDER_DPI
+func newImagePage(imageBytes []byte) (*model.PdfPage, error) {
+	goImage, _, err := image.Decode(bytes.NewReader(imageBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	img, err := model.ImageHandling.NewImageFromGoImage(goImage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert image: %w", err)
+	}
+
+	xObject, err := model.NewXObjectImageFromImage(img, nil, core.NewFlateEncoder())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create image object: %w", err)
+	}
+
+	width := float64(goImage.Bounds().Dx()) * 72 / utils.PDF_RENDER_DPI
+	height := float64(goImage.Bounds().Dy()) * 72 / utils.PDF_RENDER_DPI
+
+	page := model.NewPdfPage()
+	page.MediaBox = &model.PdfRectangle{Llx: 0, Lly: 0, Urx: width, Ury: height}
+	if err := page.AddImageResource("Page", xObject); err != nil {
+		return nil, fmt.Errorf("failed to add image: %w", err)
+	}
+
+	cc := contentstream.NewContentCreator()
+	cc.Add_q()
+	cc.Add_cm(width, 0, 0, height, 0, 0)
+	cc.Add_Do("Page")
+	cc.Add_Q()
+	if err := page.AddContentStreamByString(cc.String()); err != nil {
+		return nil, fmt.Errorf("failed to draw image: %w", err)
+	}
+
+	return page, nil
+}
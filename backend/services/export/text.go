@@ -0,0 +1,64 @@
+package export
+
+import (
+	"fmt"
+	"math"
+	"serverless-tesseract/utils"
+	"strings"
+)
+
+// renderText renders the text of every page, with a blank line between
+// paragraphs and a form feed after every page like Tesseract's txt output
+func renderText(results utils.StructuredOCRResponse) []byte {
+	var b strings.Builder
+	for _, page := range results.Pages {
+		if page.Text != "" {
+			b.WriteString(page.Text)
+			b.WriteString("\n")
+		}
+		b.WriteString("\f")
+	}
+	return []byte(b.String())
+}
+
+// renderTSV renders the results in Tesseract's TSV format, a row per page,
+// block, paragraph, line and word. Only words carry a confidence, from 0 to 100.
+func renderTSV(results utils.StructuredOCRResponse) []byte {
+	var b strings.Builder
+	b.WriteString("level\tpage_num\tblock_num\tpar_num\tline_num\tword_num\tleft\ttop\twidth\theight\tconf\ttext\n")
+
+	row := func(level, page, block, paragraph, line, word int, box utils.BBox, conf string, text string) {
+		left, top, right, bottom := bboxCorners(box)
+		fmt.Fprintf(&b, "%d\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t%s\t%s\n",
+			level, page, block, paragraph, line, word, left, top, right-left, bottom-top, conf, text)
+	}
+
+	for _, page := range results.Pages {
+		p := page.PageNumber
+		row(1, p, 0, 0, 0, 0, utils.BBox{BottomRight: utils.XY{X: page.Width, Y: page.Height}}, "-1", "")
+
+		for i, block := range page.Blocks {
+			row(2, p, i+1, 0, 0, 0, block.BBox, "-1", "")
+
+			for j, paragraph := range block.Paragraphs {
+				row(3, p, i+1, j+1, 0, 0, paragraph.BBox, "-1", "")
+
+				for k, line := range paragraph.Lines {
+					row(4, p, i+1, j+1, k+1, 0, line.BBox, "-1", "")
+
+					for l, word := range line.Words {
+						conf := fmt.Sprintf("%.2f", math.Min(100, word.Confidence*100))
+						row(5, p, i+1, j+1, k+1, l+1, word.BBox, conf, tsvField(word.Text))
+					}
+				}
+			}
+		}
+	}
+
+	return []byte(b.String())
+}
+
+// tsvField replaces the tabs and line breaks that would break the row
+func tsvField(text string) string {
+	return strings.NewReplacer("\t", " ", "\n", " ", "\r", " ").Replace(text)
+}
@@ -0,0 +1,55 @@
+package externalscripts
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type workerDeadlineKey struct{}
+
+// workerDeadline is a timeout that only starts counting once a script runs
+type workerDeadline struct {
+	once    sync.Once
+	timeout time.Duration
+	cancel  context.CancelCauseFunc
+	timer   *time.Timer
+}
+
+// WithWorkerDeadline returns a context that ends timeout after the first script
+// run with it gets a worker, so the time spent waiting for a free worker behind
+// other pages does not count against the deadline. When the timeout passes the
+// context is cancelled with context.DeadlineExceeded as its cause.
+func WithWorkerDeadline(parent context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(parent)
+	deadline := &workerDeadline{timeout: timeout, cancel: cancel}
+	ctx = context.WithValue(ctx, workerDeadlineKey{}, deadline)
+
+	return ctx, func() {
+		deadline.once.Do(func() {})
+		if deadline.timer != nil {
+			deadline.timer.Stop()
+		}
+		cancel(context.Canceled)
+	}
+}
+
+// startWorkerDeadline starts the deadline of a context made by WithWorkerDeadline,
+// it does nothing for other contexts or when the deadline already started
+func startWorkerDeadline(ctx context.Context) {
+	deadline, ok := ctx.Value(workerDeadlineKey{}).(*workerDeadline)
+	if !ok {
+		return
+	}
+	deadline.once.Do(func() {
+		deadline.timer = time.AfterFunc(deadline.timeout, func() {
+			deadline.cancel(context.DeadlineExceeded)
+		})
+	})
+}
+
+// contextErr is the error a script returns when its context is done, the cause
+// tells a passed worker deadline apart from a cancelled request
+func contextErr(ctx context.Context) error {
+	return context.Cause(ctx)
+}
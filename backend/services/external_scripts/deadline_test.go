@@ -0,0 +1,61 @@
+package externalscripts
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWorkerDeadlineStartsWithWorker(t *testing.T) {
+	ctx, cancel := WithWorkerDeadline(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	// waiting for a worker does not count against the deadline
+	time.Sleep(50 * time.Millisecond)
+	if ctx.Err() != nil {
+		t.Fatalf("context ended before the deadline started: %v", ctx.Err())
+	}
+
+	startWorkerDeadline(ctx)
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("context did not end after the deadline started")
+	}
+	if err := contextErr(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("contextErr() = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestWorkerDeadlineStartsOnce(t *testing.T) {
+	ctx, cancel := WithWorkerDeadline(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	startWorkerDeadline(ctx)
+	time.Sleep(60 * time.Millisecond)
+	// a later script of the same page does not restart the deadline
+	startWorkerDeadline(ctx)
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(80 * time.Millisecond):
+		t.Fatal("the deadline was restarted")
+	}
+}
+
+func TestWorkerDeadlineCancel(t *testing.T) {
+	parent, cancelParent := context.WithCancel(context.Background())
+	ctx, cancel := WithWorkerDeadline(parent, time.Hour)
+	defer cancel()
+
+	startWorkerDeadline(ctx)
+	cancelParent()
+	<-ctx.Done()
+	if err := contextErr(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("contextErr() = %v, want %v", err, context.Canceled)
+	}
+
+	// contexts without a worker deadline are left alone
+	startWorkerDeadline(context.Background())
+}
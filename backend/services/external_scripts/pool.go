@@ -0,0 +1,340 @@
+package externalscripts
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os/exec"
+	"serverless-tesseract/utils"
+	"sync"
+	"time"
+)
+
+// Pool is a supervised set of long-lived python workers running one OCR script
+// in "serve" mode, so models are loaded once per worker instead of once per page.
+//
+// Workers speak a framed protocol over stdin/stdout: every frame is a 4 byte
+// big-endian length followed by the payload. A request is a JSON header frame
+// followed by an image frame and is answered by a single JSON frame.
+//
+// Crashed workers are restarted, and workers are recycled after maxRequests
+// requests to bound memory leaks in the engines.
+type Pool struct {
+	script      string
+	maxRequests int
+	// slots holds one entry per worker, nil entries are started on demand
+	slots chan *poolWorker
+}
+
+type poolWorker struct {
+	cmd      *exec.Cmd
+	stdin    io.WriteCloser
+	stdout   *bufio.Reader
+	stderr   *tailBuffer
+	exited   chan struct{}
+	requests int
+}
+
+type workerRequest struct {
+	Type string   `json:"type"`
+	Args []string `json:"args,omitempty"`
+}
+
+// errScript is returned when the worker is healthy but the script failed to process the image
+type errScript struct {
+	message string
+}
+
+func (e errScript) Error() string {
+	return e.message
+}
+
+// NewPool creates a pool of size workers for the script
+func NewPool(scriptPath string, size int, maxRequests int) *Pool {
+	p := &Pool{
+		script:      scriptPath,
+		maxRequests: maxRequests,
+		slots:       make(chan *poolWorker, size),
+	}
+	for i := 0; i < size; i++ {
+		p.slots <- nil
+	}
+
+	go p.supervise()
+	return p
+}
+
+// Execute sends the image to an idle worker, waiting for one to become available.
+// A deadline set with WithWorkerDeadline starts once the worker is acquired.
+// When the context is done before the worker responds the worker is killed, so
+// a hung script does not keep running, and restarted on the next request.
+func (p *Pool) Execute(ctx context.Context, imageBytes []byte, args ...string) (utils.OCRResponseList, error) {
+	var w *poolWorker
+	select {
+	case w = <-p.slots:
+	case <-ctx.Done():
+		return utils.OCRResponseList{}, contextErr(ctx)
+	}
+	defer func() {
+		p.slots <- w
+	}()
+	startWorkerDeadline(ctx)
+
+	if w == nil || w.hasExited() {
+		started, err := startWorker(p.script)
+		if err != nil {
+			return utils.OCRResponseList{}, err
+		}
+		w = started
+	}
+
+	type outcome struct {
+		result utils.OCRResponseList
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func(w *poolWorker) {
+		result, err := w.execute(imageBytes, args)
+		done <- outcome{result, err}
+	}(w)
+
+	var result utils.OCRResponseList
+	var err error
+	select {
+	case o := <-done:
+		result, err = o.result, o.err
+	case <-ctx.Done():
+		w.stop()
+		<-done
+		w = nil
+		return utils.OCRResponseList{}, contextErr(ctx)
+	}
+
+	var scriptErr errScript
+	if err != nil && !errors.As(err, &scriptErr) {
+		// the worker is in an unknown state, replace it on the next request
+		log.Printf("Python worker for %s failed, restarting: %v", p.script, err)
+		w.stop()
+		w = nil
+		return utils.OCRResponseList{}, err
+	}
+
+	w.requests++
+	if p.maxRequests > 0 && w.requests >= p.maxRequests {
+		log.Printf("Recycling python worker for %s after %d requests", p.script, w.requests)
+		w.stop()
+		w = nil
+	}
+
+	return result, err
+}
+
+// supervise periodically health checks the idle workers, restarting workers
+// that crashed or stopped responding and starting workers in empty slots so
+// the pool stays warm
+func (p *Pool) supervise() {
+	ticker := time.NewTicker(utils.PYTHON_WORKER_HEALTH_INTERVAL)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		for i := 0; i < cap(p.slots); i++ {
+			select {
+			case w := <-p.slots:
+				p.slots <- p.checkWorker(w)
+			default:
+				// every other worker is busy
+			}
+		}
+	}
+}
+
+// checkWorker returns the worker if it is healthy, or a replacement otherwise
+func (p *Pool) checkWorker(w *poolWorker) *poolWorker {
+	if w != nil {
+		if !w.hasExited() && w.ping() == nil {
+			return w
+		}
+		log.Printf("Python worker for %s is unhealthy, restarting: %s", p.script, w.stderr.String())
+		w.stop()
+	}
+
+	started, err := startWorker(p.script)
+	if err != nil {
+		log.Printf("Failed to start python worker for %s: %v", p.script, err)
+		return nil
+	}
+	return started
+}
+
+func startWorker(scriptPath string) (*poolWorker, error) {
+	fullPath := SCRIPT_PATH + scriptPath
+	log.Println("Starting python worker: ", fullPath)
+	cmd := exec.Command("python3", fullPath, "serve")
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open stdin pipe: %v", err)
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open stdout pipe: %v", err)
+	}
+
+	stderr := &tailBuffer{}
+	cmd.Stderr = stderr
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start worker: %v", err)
+	}
+
+	w := &poolWorker{
+		cmd:    cmd,
+		stdin:  stdin,
+		stdout: bufio.NewReader(stdout),
+		stderr: stderr,
+		exited: make(chan struct{}),
+	}
+
+	go func() {
+		_ = cmd.Wait()
+		close(w.exited)
+	}()
+
+	return w, nil
+}
+
+func (w *poolWorker) execute(imageBytes []byte, args []string) (utils.OCRResponseList, error) {
+	response, err := w.call(workerRequest{Type: "ocr", Args: args}, imageBytes)
+	if err != nil {
+		return utils.OCRResponseList{}, err
+	}
+
+	var scriptError struct {
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(response, &scriptError); err == nil && scriptError.Error != "" {
+		return utils.OCRResponseList{}, errScript{message: fmt.Sprintf("script failed: %s", scriptError.Error)}
+	}
+
+	var result utils.OCRResponseList
+	if err := json.Unmarshal(response, &result); err != nil {
+		return utils.OCRResponseList{}, errScript{message: fmt.Sprintf("failed to parse JSON: %v\noutput: %s", err, response)}
+	}
+
+	return result, nil
+}
+
+func (w *poolWorker) ping() error {
+	done := make(chan error, 1)
+	go func() {
+		_, err := w.call(workerRequest{Type: "ping"}, nil)
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(utils.PYTHON_WORKER_PING_TIMEOUT):
+		// killing the process unblocks the pending read
+		w.stop()
+		return errors.New("ping timed out")
+	}
+}
+
+// call writes a request and reads the response frame
+func (w *poolWorker) call(request workerRequest, imageBytes []byte) ([]byte, error) {
+	header, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %v", err)
+	}
+
+	if err := writeFrame(w.stdin, header); err != nil {
+		return nil, fmt.Errorf("failed to write to worker: %v\nstderr: %s", err, w.stderr.String())
+	}
+
+	if request.Type != "ping" {
+		if err := writeFrame(w.stdin, imageBytes); err != nil {
+			return nil, fmt.Errorf("failed to write to worker: %v\nstderr: %s", err, w.stderr.String())
+		}
+	}
+
+	response, err := readFrame(w.stdout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from worker: %v\nstderr: %s", err, w.stderr.String())
+	}
+
+	return response, nil
+}
+
+func (w *poolWorker) hasExited() bool {
+	select {
+	case <-w.exited:
+		return true
+	default:
+		return false
+	}
+}
+
+func (w *poolWorker) stop() {
+	_ = w.stdin.Close()
+	if w.cmd.Process != nil {
+		_ = w.cmd.Process.Kill()
+	}
+}
+
+func writeFrame(writer io.Writer, payload []byte) error {
+	header := make([]byte, 4)
+	binary.BigEndian.PutUint32(header, uint32(len(payload)))
+	if _, err := writer.Write(header); err != nil {
+		return err
+	}
+	_, err := writer.Write(payload)
+	return err
+}
+
+func readFrame(reader io.Reader) ([]byte, error) {
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return nil, err
+	}
+
+	payload := make([]byte, binary.BigEndian.Uint32(header))
+	if _, err := io.ReadFull(reader, payload); err != nil {
+		return nil, err
+	}
+
+	return payload, nil
+}
+
+// tailBuffer keeps the last few kilobytes written to it, it is used to surface
+// a worker's stderr without growing unbounded over its lifetime
+type tailBuffer struct {
+	mu  sync.Mutex
+	buf []byte
+}
+
+const tailBufferSize = 4096
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.buf = append(b.buf, p...)
+	if len(b.buf) > tailBufferSize {
+		b.buf = b.buf[len(b.buf)-tailBufferSize:]
+	}
+	return len(p), nil
+}
+
+func (b *tailBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return string(b.buf)
+}
@@ -2,6 +2,7 @@ package externalscripts
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -13,11 +14,15 @@ const (
 	SCRIPT_PATH = "/usr/local/bin/scripts/"
 )
 
-func ExecutePythonOCREngineScript(scriptPath string, imageBytes []byte, args ...string) (utils.OCRResponseList, error) {
+// ExecutePythonOCREngineScript runs the script in a new python process, the process
+// is killed when the context is done
+func ExecutePythonOCREngineScript(ctx context.Context, scriptPath string, imageBytes []byte, args ...string) (utils.OCRResponseList, error) {
+	startWorkerDeadline(ctx)
+
 	// Construct full command with Python script and args
 	fullPath := SCRIPT_PATH + scriptPath
 	log.Println("Executing python with the following args: ", append([]string{fullPath}, args...))
-	cmd := exec.Command("python3", append([]string{fullPath}, args...)...)
+	cmd := exec.CommandContext(ctx, "python3", append([]string{fullPath}, args...)...)
 
 	// Create pipes for stdin and capture stdout/stderr
 	stdin, err := cmd.StdinPipe()
@@ -46,6 +51,9 @@ func ExecutePythonOCREngineScript(scriptPath string, imageBytes []byte, args ...
 
 	// Wait for completion
 	if err := cmd.Wait(); err != nil {
+		if ctx.Err() != nil {
+			return utils.OCRResponseList{}, contextErr(ctx)
+		}
 		log.Println("Failed to wait for completion: ", err)
 		return utils.OCRResponseList{}, fmt.Errorf("script failed: %v\nstderr: %s", err, errBuf.String())
 	}
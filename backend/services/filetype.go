@@ -0,0 +1,95 @@
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+	"serverless-tesseract/utils"
+	"slices"
+)
+
+// FileType is a file format OCRDocument can process
+type FileType struct {
+	MediaType string
+	// Extension is the canonical extension of the format, it is used to name stored uploads
+	Extension string
+}
+
+var (
+	FileTypePDF  = FileType{MediaType: "application/pdf", Extension: ".pdf"}
+	FileTypePNG  = FileType{MediaType: "image/png", Extension: ".png"}
+	FileTypeJPEG = FileType{MediaType: "image/jpeg", Extension: ".jpg"}
+	FileTypeTIFF = FileType{MediaType: "image/tiff", Extension: ".tiff"}
+	FileTypeWebP = FileType{MediaType: "image/webp", Extension: ".webp"}
+	FileTypeBMP  = FileType{MediaType: "image/bmp", Extension: ".bmp"}
+	FileTypeGIF  = FileType{MediaType: "image/gif", Extension: ".gif"}
+	FileTypeHEIC = FileType{MediaType: "image/heic", Extension: ".heic"}
+)
+
+// heifBrands are the ISO base media file brands used by HEIC and HEIF images
+var heifBrands = []string{"heic", "heix", "hevc", "hevx", "heim", "heis", "mif1", "msf1"}
+
+// DetectFileType identifies the format of a file from its magic bytes, the
+// filename is not trusted. utils.ErrUnsupportedMediaType is returned for
+// formats that cannot be processed. Images are matched on their exact leading
+// bytes first, so an image that happens to contain "%PDF-" is not taken for a
+// PDF by the lenient header scan, which runs last.
+func DetectFileType(fileBytes []byte) (FileType, error) {
+	switch {
+	case bytes.HasPrefix(fileBytes, []byte("\x89PNG\r\n\x1a\n")):
+		return FileTypePNG, nil
+	case bytes.HasPrefix(fileBytes, []byte("\xff\xd8\xff")):
+		return FileTypeJPEG, nil
+	case bytes.HasPrefix(fileBytes, []byte("II*\x00")), bytes.HasPrefix(fileBytes, []byte("MM\x00*")):
+		return FileTypeTIFF, nil
+	case len(fileBytes) >= 12 && string(fileBytes[0:4]) == "RIFF" && string(fileBytes[8:12]) == "WEBP":
+		return FileTypeWebP, nil
+	case isBMP(fileBytes):
+		return FileTypeBMP, nil
+	case bytes.HasPrefix(fileBytes, []byte("GIF87a")), bytes.HasPrefix(fileBytes, []byte("GIF89a")):
+		return FileTypeGIF, nil
+	case isHEIF(fileBytes):
+		return FileTypeHEIC, nil
+	case hasPDFHeader(fileBytes):
+		return FileTypePDF, nil
+	}
+
+	return FileType{}, utils.ErrUnsupportedMediaType
+}
+
+// hasPDFHeader checks for the %PDF- header, which readers accept anywhere in the
+// first kilobyte of the file
+func hasPDFHeader(fileBytes []byte) bool {
+	head := fileBytes
+	if len(head) > 1024 {
+		head = head[:1024]
+	}
+	return bytes.Contains(head, []byte("%PDF-"))
+}
+
+// bmpHeaderSizes are the sizes of the DIB headers BMP files are written with,
+// from the OS/2 BITMAPCOREHEADER to BITMAPV5HEADER
+var bmpHeaderSizes = []uint32{12, 40, 52, 56, 64, 108, 124}
+
+// isBMP checks the "BM" signature and the size of the DIB header that follows
+// the 14 byte file header, two letters alone match too many text files
+func isBMP(fileBytes []byte) bool {
+	if len(fileBytes) < 18 || !bytes.HasPrefix(fileBytes, []byte("BM")) {
+		return false
+	}
+	return slices.Contains(bmpHeaderSizes, binary.LittleEndian.Uint32(fileBytes[14:18]))
+}
+
+// isHEIF checks the major brand of the leading "ftyp" box
+func isHEIF(fileBytes []byte) bool {
+	if len(fileBytes) < 12 || string(fileBytes[4:8]) != "ftyp" {
+		return false
+	}
+
+	brand := string(fileBytes[8:12])
+	for _, heifBrand := range heifBrands {
+		if brand == heifBrand {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+package services
+
+import (
+	"errors"
+	"serverless-tesseract/utils"
+	"testing"
+)
+
+// bmpHeader is a BMP file header followed by the size of a DIB header
+func bmpHeader(dibSize byte) []byte {
+	header := append([]byte("BM"), make([]byte, 12)...)
+	return append(header, dibSize, 0, 0, 0)
+}
+
+func TestDetectFileType(t *testing.T) {
+	tests := []struct {
+		name string
+		file []byte
+		want FileType
+	}{
+		{"pdf", []byte("%PDF-1.7\n"), FileTypePDF},
+		{"pdf with leading junk", append([]byte("\x00\x01junk before the header\n"), []byte("%PDF-1.4\n")...), FileTypePDF},
+		{"png", []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), FileTypePNG},
+		{"png containing a pdf header", []byte("\x89PNG\r\n\x1a\n tEXt %PDF-1.4"), FileTypePNG},
+		{"jpeg", []byte("\xff\xd8\xff\xe0\x00\x10JFIF"), FileTypeJPEG},
+		{"little endian tiff", []byte("II*\x00\x08\x00\x00\x00"), FileTypeTIFF},
+		{"big endian tiff", []byte("MM\x00*\x00\x00\x00\x08"), FileTypeTIFF},
+		{"webp", []byte("RIFF\x24\x00\x00\x00WEBPVP8 "), FileTypeWebP},
+		{"bmp", bmpHeader(40), FileTypeBMP},
+		{"os/2 bmp", bmpHeader(12), FileTypeBMP},
+		{"v5 bmp", bmpHeader(124), FileTypeBMP},
+		{"gif87a", []byte("GIF87a\x01\x00\x01\x00"), FileTypeGIF},
+		{"gif89a", []byte("GIF89a\x01\x00\x01\x00"), FileTypeGIF},
+		{"heic", []byte("\x00\x00\x00\x18ftypheic\x00\x00\x00\x00"), FileTypeHEIC},
+		{"heif", []byte("\x00\x00\x00\x18ftypmif1\x00\x00\x00\x00"), FileTypeHEIC},
+	}
+
+	for _, test := range tests {
+		got, err := DetectFileType(test.file)
+		if err != nil {
+			t.Errorf("%s: DetectFileType() error = %v", test.name, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("%s: DetectFileType() = %s, want %s", test.name, got.MediaType, test.want.MediaType)
+		}
+	}
+}
+
+func TestDetectFileTypeUnsupported(t *testing.T) {
+	tests := []struct {
+		name string
+		file []byte
+	}{
+		{"empty", nil},
+		{"text", []byte("hello world")},
+		{"text starting with BM", []byte("BMW owners manual, chapter 1")},
+		{"truncated bmp", bmpHeader(40)[:16]},
+		{"bmp with an unknown header size", bmpHeader(41)},
+		{"riff that is not webp", []byte("RIFF\x24\x00\x00\x00WAVEfmt ")},
+		{"mp4", []byte("\x00\x00\x00\x18ftypisom\x00\x00\x00\x00")},
+		{"pdf header past the first kilobyte", append(make([]byte, 1024), []byte("%PDF-1.4")...)},
+	}
+
+	for _, test := range tests {
+		if got, err := DetectFileType(test.file); !errors.Is(err, utils.ErrUnsupportedMediaType) {
+			t.Errorf("%s: DetectFileType() = %s, %v, want %v", test.name, got.MediaType, err, utils.ErrUnsupportedMediaType)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"log"
+	"serverless-tesseract/utils"
+
+	"github.com/gen2brain/heic"
+	"golang.org/x/image/bmp"
+	"golang.org/x/image/tiff"
+	"golang.org/x/image/webp"
+)
+
+// imageDecoders decode the image formats that are converted to PNG before OCR,
+// PNG and JPEG images are passed to the engines unchanged
+var imageDecoders = map[FileType]func(io.Reader) (image.Image, error){
+	FileTypeTIFF: tiff.Decode,
+	FileTypeWebP: webp.Decode,
+	FileTypeBMP:  bmp.Decode,
+	FileTypeGIF:  gif.Decode,
+	FileTypeHEIC: heic.Decode,
+}
+
+// imageConfigDecoders read the dimensions of an image without decoding its pixels
+var imageConfigDecoders = map[FileType]func(io.Reader) (image.Config, error){
+	FileTypePNG:  png.DecodeConfig,
+	FileTypeJPEG: jpeg.DecodeConfig,
+	FileTypeTIFF: tiff.DecodeConfig,
+	FileTypeWebP: webp.DecodeConfig,
+	FileTypeBMP:  bmp.DecodeConfig,
+	FileTypeGIF:  gif.DecodeConfig,
+	FileTypeHEIC: heic.DecodeConfig,
+}
+
+// NormalizeImage converts an image to a PNG the engines can read. Transparent
+// pixels are flattened onto white, otherwise most engines read them as black.
+// Only the first frame of an animated GIF is kept. Images of more than
+// IMAGE_MAX_PIXELS pixels are rejected before they are decoded.
+func NormalizeImage(imageBytes []byte, fileType FileType) ([]byte, error) {
+	decodeConfig, ok := imageConfigDecoders[fileType]
+	if !ok {
+		return nil, utils.ErrUnsupportedMediaType
+	}
+	config, err := decodeConfig(bytes.NewReader(imageBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s image: %w", fileType.MediaType, err)
+	}
+	if err := checkImagePixels(config); err != nil {
+		return nil, err
+	}
+
+	if fileType == FileTypePNG || fileType == FileTypeJPEG {
+		return imageBytes, nil
+	}
+
+	decode := imageDecoders[fileType]
+
+	img, err := decode(bytes.NewReader(imageBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s image: %w", fileType.MediaType, err)
+	}
+
+	return encodePNG(img)
+}
+
+// checkImagePixels returns utils.ErrImageTooLarge for an image of more than
+// IMAGE_MAX_PIXELS pixels, a small file can hold an image too large to decode
+func checkImagePixels(config image.Config) error {
+	if int64(config.Width)*int64(config.Height) > int64(utils.IMAGE_MAX_PIXELS) {
+		return fmt.Errorf("%w: %dx%d pixels, the limit is %d pixels", utils.ErrImageTooLarge, config.Width, config.Height, utils.IMAGE_MAX_PIXELS)
+	}
+	return nil
+}
+
+func encodePNG(img image.Image) ([]byte, error) {
+	if opaque, ok := img.(interface{ Opaque() bool }); !ok || !opaque.Opaque() {
+		flattened := image.NewRGBA(img.Bounds())
+		draw.Draw(flattened, flattened.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+		draw.Draw(flattened, flattened.Bounds(), img, img.Bounds().Min, draw.Over)
+		img = flattened
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("failed to encode image as PNG: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// imageSize reads the dimensions of a PNG or JPEG page without decoding it
+func imageSize(imageBytes []byte) (utils.PageSize, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(imageBytes))
+	if err != nil {
+		return utils.PageSize{}, err
+	}
+	return utils.PageSize{Width: config.Width, Height: config.Height}, nil
+}
+
+// StreamTIFF splits a multi-page TIFF into PNG pages the same way StreamPDF
+// renders a PDF, one page at a time with at most maxInFlight pages held in memory
+func StreamTIFF(ctx context.Context, tiffBytes []byte, opts DocumentOptions, maxInFlight int) (<-chan RenderedPage, int, error) {
+	offsets, err := tiffPageOffsets(tiffBytes)
+	if err != nil {
+		log.Printf("Failed to read TIFF pages: %v", err)
+		return nil, 0, fmt.Errorf("failed to read TIFF pages: %w", err)
+	}
+
+	pageNumbers, err := opts.Pages.Resolve(len(offsets))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return streamPages(ctx, pageNumbers, maxInFlight, func(i int) ([]byte, *utils.OCRResponseList, error) {
+		config, err := tiff.DecodeConfig(&tiffPage{data: tiffBytes, ifdOffset: offsets[i-1]})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to decode TIFF page %d: %w", i, err)
+		}
+		if err := checkImagePixels(config); err != nil {
+			return nil, nil, fmt.Errorf("TIFF page %d: %w", i, err)
+		}
+
+		img, err := tiff.Decode(&tiffPage{data: tiffBytes, ifdOffset: offsets[i-1]})
+		if err != nil {
+			log.Printf("Failed to decode TIFF page %d: %v", i, err)
+			return nil, nil, fmt.Errorf("failed to decode TIFF page %d: %w", i, err)
+		}
+
+		page, err := encodePNG(img)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to encode TIFF page %d: %w", i, err)
+		}
+		return page, nil, nil
+	}), len(pageNumbers), nil
+}
+
+// CountTIFFPages returns the number of pages in the TIFF
+func CountTIFFPages(tiffBytes []byte) (int, error) {
+	offsets, err := tiffPageOffsets(tiffBytes)
+	return len(offsets), err
+}
+
+// tiffPageOffsets walks the chain of image file directories, one per page, and
+// returns their offsets
+func tiffPageOffsets(data []byte) ([]uint32, error) {
+	if len(data) < 8 {
+		return nil, errors.New("malformed header")
+	}
+
+	var byteOrder binary.ByteOrder
+	switch string(data[0:4]) {
+	case "II*\x00":
+		byteOrder = binary.LittleEndian
+	case "MM\x00*":
+		byteOrder = binary.BigEndian
+	default:
+		return nil, errors.New("malformed header")
+	}
+
+	var offsets []uint32
+	seen := map[uint32]bool{}
+	offset := byteOrder.Uint32(data[4:8])
+	for offset != 0 {
+		// a directory is a 2 byte entry count, 12 bytes per entry and the 4 byte offset of the next directory
+		if seen[offset] || int(offset)+2 > len(data) {
+			return nil, fmt.Errorf("malformed image file directory at offset %d", offset)
+		}
+		seen[offset] = true
+		offsets = append(offsets, offset)
+
+		next := int(offset) + 2 + int(byteOrder.Uint16(data[offset:]))*12
+		if next+4 > len(data) {
+			return nil, fmt.Errorf("malformed image file directory at offset %d", offset)
+		}
+		offset = byteOrder.Uint32(data[next:])
+	}
+
+	if len(offsets) == 0 {
+		return nil, errors.New("no pages found")
+	}
+	return offsets, nil
+}
+
+// tiffPage presents a TIFF as if the page at ifdOffset were its first page, so
+// the standard decoder, which only reads the first page, can decode any page
+// without copying the file
+type tiffPage struct {
+	data      []byte
+	ifdOffset uint32
+	pos       int64
+}
+
+func (t *tiffPage) ReadAt(p []byte, off int64) (int, error) {
+	if off >= int64(len(t.data)) {
+		return 0, io.EOF
+	}
+
+	n := copy(p, t.data[off:])
+	// patch the offset of the first image file directory in the header
+	for i := int64(4); i < 8; i++ {
+		if i >= off && i < off+int64(n) {
+			p[i-off] = t.headerByte(i)
+		}
+	}
+
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (t *tiffPage) Read(p []byte) (int, error) {
+	n, err := t.ReadAt(p, t.pos)
+	t.pos += int64(n)
+	return n, err
+}
+
+func (t *tiffPage) headerByte(i int64) byte {
+	header := make([]byte, 4)
+	if t.data[0] == 'I' {
+		binary.LittleEndian.PutUint32(header, t.ifdOffset)
+	} else {
+		binary.BigEndian.PutUint32(header, t.ifdOffset)
+	}
+	return header[i-4]
+}
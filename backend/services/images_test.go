@@ -0,0 +1,203 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image/png"
+	"serverless-tesseract/utils"
+	"slices"
+	"testing"
+)
+
+// testTIFFPage is a page of a test TIFF, an 8 bit grayscale image filled with value
+type testTIFFPage struct {
+	width, height int
+	value         byte
+}
+
+// buildTIFF writes an uncompressed little endian TIFF with a directory per page.
+// When next is set it replaces the offset of the directory after the last page.
+func buildTIFF(pages []testTIFFPage, next func(ifdOffsets []uint32) uint32) []byte {
+	buf := bytes.NewBuffer([]byte("II*\x00\x00\x00\x00\x00"))
+	put16 := func(v uint16) { _ = binary.Write(buf, binary.LittleEndian, v) }
+	put32 := func(v uint32) { _ = binary.Write(buf, binary.LittleEndian, v) }
+
+	var ifdOffsets []uint32
+	var nextFields []int
+	for _, page := range pages {
+		// the pixels first, then the directory pointing at them
+		stripOffset := uint32(buf.Len())
+		pixels := page.width * page.height
+		if pixels > 1<<20 {
+			// pages claiming huge dimensions carry no pixels
+			pixels = 0
+		}
+		buf.Write(bytes.Repeat([]byte{page.value}, pixels))
+		if buf.Len()%2 == 1 {
+			buf.WriteByte(0)
+		}
+
+		ifdOffsets = append(ifdOffsets, uint32(buf.Len()))
+		entries := []struct {
+			tag, kind uint16
+			value     uint32
+		}{
+			{256, 4, uint32(page.width)},
+			{257, 4, uint32(page.height)},
+			{258, 3, 8},
+			{259, 3, 1},
+			{262, 3, 1},
+			{273, 4, stripOffset},
+			{277, 3, 1},
+			{278, 4, uint32(page.height)},
+			{279, 4, uint32(pixels)},
+		}
+		put16(uint16(len(entries)))
+		for _, entry := range entries {
+			put16(entry.tag)
+			put16(entry.kind)
+			put32(1)
+			if entry.kind == 3 {
+				put16(uint16(entry.value))
+				put16(0)
+			} else {
+				put32(entry.value)
+			}
+		}
+		nextFields = append(nextFields, buf.Len())
+		put32(0)
+	}
+
+	data := buf.Bytes()
+	binary.LittleEndian.PutUint32(data[4:], ifdOffsets[0])
+	for i := 0; i < len(nextFields)-1; i++ {
+		binary.LittleEndian.PutUint32(data[nextFields[i]:], ifdOffsets[i+1])
+	}
+	if next != nil {
+		binary.LittleEndian.PutUint32(data[nextFields[len(nextFields)-1]:], next(ifdOffsets))
+	}
+	return data
+}
+
+// pngHeader is the start of a PNG claiming the dimensions, without image data
+func pngHeader(width, height uint32) []byte {
+	ihdr := make([]byte, 17)
+	copy(ihdr, "IHDR")
+	binary.BigEndian.PutUint32(ihdr[4:], width)
+	binary.BigEndian.PutUint32(ihdr[8:], height)
+	ihdr[12], ihdr[13] = 8, 2 // 8 bit truecolor
+
+	var buf bytes.Buffer
+	buf.WriteString("\x89PNG\r\n\x1a\n")
+	_ = binary.Write(&buf, binary.BigEndian, uint32(13))
+	buf.Write(ihdr)
+	_ = binary.Write(&buf, binary.BigEndian, crc32.ChecksumIEEE(ihdr))
+	return buf.Bytes()
+}
+
+func TestTIFFPageOffsets(t *testing.T) {
+	tiff := buildTIFF([]testTIFFPage{{4, 3, 10}, {5, 2, 20}, {2, 2, 30}}, nil)
+
+	count, err := CountTIFFPages(tiff)
+	if err != nil || count != 3 {
+		t.Fatalf("CountTIFFPages() = %d, %v, want 3", count, err)
+	}
+
+	malformed := map[string][]byte{
+		"cyclic chain": buildTIFF([]testTIFFPage{{2, 2, 0}, {2, 2, 0}}, func(ifdOffsets []uint32) uint32 {
+			return ifdOffsets[0]
+		}),
+		"self referencing directory": buildTIFF([]testTIFFPage{{2, 2, 0}}, func(ifdOffsets []uint32) uint32 {
+			return ifdOffsets[0]
+		}),
+		"directory past the end": buildTIFF([]testTIFFPage{{2, 2, 0}}, func([]uint32) uint32 {
+			return 1 << 30
+		}),
+		"truncated directory": tiff[:len(tiff)-6],
+		"short header":        []byte("II*\x00"),
+		"bad byte order":      []byte("XX*\x00\x08\x00\x00\x00"),
+		"no pages":            []byte("II*\x00\x00\x00\x00\x00"),
+	}
+	for name, data := range malformed {
+		if offsets, err := tiffPageOffsets(data); err == nil {
+			t.Errorf("%s: tiffPageOffsets() = %v, want an error", name, offsets)
+		}
+	}
+}
+
+func TestStreamTIFF(t *testing.T) {
+	pages := []testTIFFPage{{4, 3, 10}, {5, 2, 20}, {2, 2, 30}}
+	tiff := buildTIFF(pages, nil)
+
+	selection, err := utils.ParsePageSelection("1,3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rendered, numPages, err := StreamTIFF(context.Background(), tiff, DocumentOptions{Pages: selection}, 1)
+	if err != nil {
+		t.Fatalf("StreamTIFF() error = %v", err)
+	}
+	if numPages != 2 {
+		t.Errorf("StreamTIFF() selected %d pages, want 2", numPages)
+	}
+
+	var numbers []int
+	for page := range rendered {
+		if page.Err != nil {
+			t.Fatalf("page %d error = %v", page.Number, page.Err)
+		}
+		img, err := png.Decode(bytes.NewReader(page.Image))
+		if err != nil {
+			t.Fatalf("page %d is not a PNG: %v", page.Number, err)
+		}
+
+		want := pages[page.Number-1]
+		if size := img.Bounds().Size(); size.X != want.width || size.Y != want.height {
+			t.Errorf("page %d is %v, want %dx%d", page.Number, size, want.width, want.height)
+		}
+		if r, _, _, _ := img.At(0, 0).RGBA(); byte(r>>8) != want.value {
+			t.Errorf("page %d has value %d, want %d", page.Number, r>>8, want.value)
+		}
+		numbers = append(numbers, page.Number)
+		page.Release()
+	}
+	if !slices.Equal(numbers, []int{1, 3}) {
+		t.Errorf("StreamTIFF() rendered pages %v, want [1 3]", numbers)
+	}
+}
+
+func TestImageMaxPixels(t *testing.T) {
+	huge := uint32(100_000)
+
+	if _, err := NormalizeImage(pngHeader(huge, huge), FileTypePNG); !errors.Is(err, utils.ErrImageTooLarge) {
+		t.Errorf("NormalizeImage() of a huge PNG error = %v, want %v", err, utils.ErrImageTooLarge)
+	}
+	if _, err := NormalizeImage(buildTIFF([]testTIFFPage{{int(huge), int(huge), 0}}, nil), FileTypeTIFF); !errors.Is(err, utils.ErrImageTooLarge) {
+		t.Errorf("NormalizeImage() of a huge TIFF error = %v, want %v", err, utils.ErrImageTooLarge)
+	}
+
+	// PNG and JPEG images under the limit are passed on unchanged
+	small := pngHeader(10, 10)
+	if got, err := NormalizeImage(small, FileTypePNG); err != nil || !bytes.Equal(got, small) {
+		t.Errorf("NormalizeImage() of a small PNG = %d bytes, %v", len(got), err)
+	}
+
+	// only the page over the limit fails
+	tiff := buildTIFF([]testTIFFPage{{2, 2, 0}, {int(huge), int(huge), 0}}, nil)
+	rendered, _, err := StreamTIFF(context.Background(), tiff, DocumentOptions{}, 1)
+	if err != nil {
+		t.Fatalf("StreamTIFF() error = %v", err)
+	}
+	for page := range rendered {
+		if page.Number == 1 && page.Err != nil {
+			t.Errorf("page 1 error = %v", page.Err)
+		}
+		if page.Number == 2 && !errors.Is(page.Err, utils.ErrImageTooLarge) {
+			t.Errorf("page 2 error = %v, want %v", page.Err, utils.ErrImageTooLarge)
+		}
+		page.Release()
+	}
+}
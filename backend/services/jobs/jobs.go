@@ -0,0 +1,299 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"serverless-tesseract/db"
+	"serverless-tesseract/models"
+	"serverless-tesseract/r2"
+	"serverless-tesseract/services"
+	"serverless-tesseract/services/cache"
+	"serverless-tesseract/services/export"
+	"serverless-tesseract/services/preprocess"
+	"serverless-tesseract/utils"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+var wake = make(chan struct{}, 1)
+
+// Start launches the background workers that process queued OCR jobs. Job state
+// lives in postgres, so jobs queued before a restart are picked up again.
+func Start(workers int) {
+	go requeueStaleJobs()
+
+	for i := 0; i < workers; i++ {
+		go worker()
+	}
+
+	log.Printf("Started %d OCR job workers", workers)
+}
+
+// Notify wakes an idle worker so a newly queued job starts without waiting for
+// the next poll
+func Notify() {
+	select {
+	case wake <- struct{}{}:
+	default:
+	}
+}
+
+func worker() {
+	ticker := time.NewTicker(utils.OCR_JOB_POLL_INTERVAL)
+	defer ticker.Stop()
+
+	for {
+		job, err := db.ClaimNextOCRJob()
+		if err != nil {
+			log.Printf("Failed to claim OCR job: %v", err)
+		}
+
+		if job == nil {
+			select {
+			case <-wake:
+			case <-ticker.C:
+			}
+			continue
+		}
+
+		process(*job)
+	}
+}
+
+// requeueStaleJobs periodically returns jobs that were orphaned by a crashed
+// worker to the queue
+func requeueStaleJobs() {
+	for {
+		requeued, err := db.RequeueStaleOCRJobs(utils.OCR_JOB_STALE_TIMEOUT)
+		if err != nil {
+			log.Printf("Failed to requeue stale OCR jobs: %v", err)
+		} else if requeued > 0 {
+			log.Printf("Requeued %d stale OCR jobs", requeued)
+			Notify()
+		}
+		time.Sleep(utils.OCR_JOB_STALE_TIMEOUT / 2)
+	}
+}
+
+func process(job models.OrganizationOCRJob) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	engine := string(job.OCREngine)
+
+	log.Printf("Processing OCR job %s", job.ID)
+
+	// the claim is refreshed while the job runs, OCR stops if it is lost
+	var lost atomic.Bool
+	go keepClaim(ctx, job, &lost, cancel)
+
+	defer func() {
+		// a job that was requeued and claimed again still needs its upload
+		if !lost.Load() {
+			removeUpload(job)
+		}
+	}()
+
+	fileBytes, err := r2.GetFile(job.UploadKey)
+	if err != nil {
+		failJob(job, &lost, 0, fmt.Errorf("failed to read upload: %w", err))
+		return
+	}
+
+	pages, err := utils.ParsePageSelection(job.Pages)
+	if err != nil {
+		failJob(job, &lost, 0, fmt.Errorf("invalid pages: %w", err))
+		return
+	}
+
+	steps, err := preprocess.Parse(job.Preprocess)
+	if err != nil {
+		failJob(job, &lost, 0, fmt.Errorf("invalid preprocess: %w", err))
+		return
+	}
+	languages := strings.Split(job.Languages, ",")
+
+	results, cache_hit, err := cache.GetCacheResult(
+		job.FileHash,
+		job.CachePolicy,
+		job.OrganizationID,
+		engine,
+		job.Raw,
+		job.TextLayer,
+		job.Preprocess,
+		job.Languages,
+		job.Tables,
+		job.Barcodes,
+	)
+	if err != nil {
+		failJob(job, &lost, 0, fmt.Errorf("failed to get cache result: %w", err))
+		return
+	}
+	results = cache.SelectPages(results, pages)
+
+	var number_of_pages int32
+	if job.CachePolicy == utils.CacheOnly || (cache_hit && job.CachePolicy == utils.CacheFirst) {
+		if results == nil {
+			recordRequest(ctx, job, 1, false, false, 0)
+			failJob(job, &lost, 0, errors.New("no cache results found"))
+			return
+		}
+		number_of_pages = 1
+	} else {
+		cache_hit = false
+		organization, err := db.GetOrganization(job.OrganizationID)
+		if err != nil {
+			failJob(job, &lost, 0, fmt.Errorf("failed to get organization: %w", err))
+			return
+		}
+
+		allResults, processed, err := services.OCRDocument(ctx, fileBytes, services.DocumentOptions{
+			Engine:          job.OCREngine,
+			Raw:             job.Raw,
+			Pages:           pages,
+			TextLayer:       job.TextLayer,
+			Languages:       languages,
+			Tables:          job.Tables,
+			Barcodes:        job.Barcodes,
+			Preprocess:      steps,
+			OrganizationID:  job.OrganizationID,
+			PageConcurrency: organization.PageConcurrency(),
+			OnPage: func(pagesDone int32, pagesTotal int32) {
+				if err := db.UpdateOCRJobProgress(job.ID, job.ClaimToken, pagesDone, pagesTotal); err != nil {
+					log.Printf("Failed to update progress of OCR job %s: %v", job.ID, err)
+				}
+			},
+		})
+		if err != nil {
+			recordRequest(ctx, job, processed, false, false, allResults.NumberOfTokens)
+			failJob(job, &lost, processed, err)
+			return
+		}
+
+		// only whole documents are cached
+		if len(pages) == 0 {
+			_, err = db.SaveFileHashCache(job.FileHash, allResults, job.OrganizationID, engine, job.Raw, string(job.TextLayer), job.Preprocess, job.Languages, job.Tables, job.Barcodes)
+			if err != nil {
+				recordRequest(ctx, job, processed, false, false, 0)
+				failJob(job, &lost, processed, fmt.Errorf("failed to save cache result: %w", err))
+				return
+			}
+		}
+
+		results = &allResults
+		number_of_pages = processed
+	}
+
+	results.Cached = cache_hit
+	results.Raw = job.Raw
+	results.Engine = job.OCREngine
+	results.Languages = languages
+
+	resultKey := fmt.Sprintf("jobs/%d/%s.json", job.OrganizationID, job.ID)
+	if err := r2.UploadObject(resultKey, *results); err != nil {
+		recordRequest(ctx, job, number_of_pages, cache_hit, false, 0)
+		failJob(job, &lost, number_of_pages, fmt.Errorf("failed to store result: %w", err))
+		return
+	}
+
+	// searchable PDFs are drawn over the upload, so they are rendered before it is deleted
+	if job.OutputFormat == utils.OutputSearchablePDF {
+		results.DocumentKey = resultKey
+		if _, err := export.Artifact(ctx, *results, job.OutputFormat, fileBytes); err != nil {
+			recordRequest(ctx, job, number_of_pages, cache_hit, false, 0)
+			failJob(job, &lost, number_of_pages, fmt.Errorf("failed to render searchable PDF: %w", err))
+			return
+		}
+	}
+
+	if err := db.CompleteOCRJob(job.ID, job.ClaimToken, resultKey, number_of_pages); err != nil {
+		if errors.Is(err, utils.ErrJobClaimLost) {
+			lost.Store(true)
+		}
+		log.Printf("Failed to complete OCR job %s: %v", job.ID, err)
+		return
+	}
+
+	recordRequest(ctx, job, number_of_pages, cache_hit, true, results.NumberOfTokens)
+	log.Printf("Completed OCR job %s", job.ID)
+}
+
+// recordRequest records the job in organization_ocr_request so it is billed and
+// reported the same way as a synchronous request
+func recordRequest(ctx context.Context, job models.OrganizationOCRJob, pages int32, cache_hit bool, success bool, token_count int64) {
+	// results of a page selection are not cached unless they were served from the cache
+	var cacheHash *string
+	if success && (cache_hit || job.Pages == "") {
+		cacheHash = &job.FileHash
+	}
+
+	_, err := db.CreateOCRRequest(
+		ctx,
+		pages,
+		cache_hit,
+		string(job.OCREngine),
+		job.OrganizationID,
+		job.Filename,
+		success,
+		token_count,
+		job.FileHash,
+		job.Raw,
+		string(job.TextLayer),
+		job.Preprocess,
+		job.Languages,
+		job.Tables,
+		job.Barcodes,
+		job.MimeType,
+		cacheHash,
+		&job.ID,
+	)
+	if err != nil {
+		log.Printf("Failed to record OCR request for job %s: %v", job.ID, err)
+	}
+}
+
+func failJob(job models.OrganizationOCRJob, lost *atomic.Bool, pages int32, jobErr error) {
+	log.Printf("OCR job %s failed: %v", job.ID, jobErr)
+	if err := db.FailOCRJob(job.ID, job.ClaimToken, jobErr.Error(), pages); err != nil {
+		if errors.Is(err, utils.ErrJobClaimLost) {
+			lost.Store(true)
+		}
+		log.Printf("Failed to mark OCR job %s as failed: %v", job.ID, err)
+	}
+}
+
+// keepClaim sends the job's heartbeat until ctx is done. When the job was
+// requeued and is no longer claimed by this worker, lost is set and the job's
+// context is cancelled so it is not processed and billed twice.
+func keepClaim(ctx context.Context, job models.OrganizationOCRJob, lost *atomic.Bool, cancel context.CancelFunc) {
+	ticker := time.NewTicker(utils.OCR_JOB_HEARTBEAT_INTERVAL)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		err := db.HeartbeatOCRJob(job.ID, job.ClaimToken)
+		if errors.Is(err, utils.ErrJobClaimLost) {
+			log.Printf("OCR job %s was claimed by another worker, stopping", job.ID)
+			lost.Store(true)
+			cancel()
+			return
+		}
+		if err != nil {
+			log.Printf("Failed to heartbeat OCR job %s: %v", job.ID, err)
+		}
+	}
+}
+
+// removeUpload deletes the stored upload once the job has finished with it
+func removeUpload(job models.OrganizationOCRJob) {
+	if err := r2.DeleteObject(job.UploadKey); err != nil {
+		log.Printf("Failed to delete upload of OCR job %s: %v", job.ID, err)
+	}
+}
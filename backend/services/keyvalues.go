@@ -0,0 +1,323 @@
+package services
+
+import (
+	"serverless-tesseract/utils"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// a label ending in a colon is at most this many words long
+const maxLabelWords = 5
+
+// defaultLabelSynonyms are the labels of common invoice, receipt and form fields
+var defaultLabelSynonyms = map[string][]string{
+	"invoice_number":  {"invoice number", "invoice no", "invoice #", "invoice num", "inv no", "inv #"},
+	"invoice_date":    {"invoice date", "date of issue", "issue date"},
+	"date":            {"date"},
+	"due_date":        {"due date", "payment due", "date due"},
+	"po_number":       {"po number", "po no", "po #", "purchase order", "purchase order number"},
+	"order_number":    {"order number", "order no", "order #"},
+	"account_number":  {"account number", "account no", "account #", "acct no"},
+	"customer_number": {"customer number", "customer no", "customer id", "client id"},
+	"reference":       {"reference", "reference number", "ref", "ref no"},
+	"subtotal":        {"subtotal", "sub total", "sub-total"},
+	"tax":             {"tax", "vat", "gst", "sales tax"},
+	"total":           {"total", "total due", "amount due", "balance due", "grand total", "total amount"},
+	"terms":           {"terms", "payment terms"},
+	"phone":           {"phone", "tel", "telephone"},
+	"email":           {"email", "e-mail"},
+}
+
+// labelPhrase is a label synonym split into normalized words
+type labelPhrase struct {
+	key   string
+	words []string
+}
+
+// labelSpan is a label found in a segment, the words from start up to end
+type labelSpan struct {
+	start, end int
+	key        string
+}
+
+// ExtractKeyValues groups the words of the results into label and value pairs
+// such as "Invoice Number: 12345". Each line is split into segments at column
+// gaps like tables are. A label is a known synonym at the start of a segment, or
+// the words of a segment up to one ending in a colon. Its value is the rest of
+// the segment up to the next label, or when the label ends its segment, the next
+// segment of the line or the segment below the label.
+//
+// synonyms maps keys to the organization's label synonyms. They take precedence
+// over the built in synonyms of common invoice and form fields.
+func ExtractKeyValues(results utils.OCRResponseList, synonyms map[string][]string) []utils.KeyValue {
+	phrases := labelPhrases(synonyms)
+
+	var pageNumbers []int
+	pageWords := map[int][]utils.OCRResponse{}
+	for _, response := range results.OCRResponses {
+		if _, ok := pageWords[response.PageNumber]; !ok {
+			pageNumbers = append(pageNumbers, response.PageNumber)
+		}
+		pageWords[response.PageNumber] = append(pageWords[response.PageNumber], response)
+	}
+	sort.Ints(pageNumbers)
+
+	keyValues := []utils.KeyValue{}
+	for _, pageNumber := range pageNumbers {
+		keyValues = append(keyValues, pageKeyValues(pageWords[pageNumber], phrases, pageNumber)...)
+	}
+	return keyValues
+}
+
+// labelPhrases returns the organization's synonyms followed by the built in
+// synonyms it does not override, longest first so that "invoice date" is
+// matched before "date"
+func labelPhrases(synonyms map[string][]string) []labelPhrase {
+	var phrases []labelPhrase
+	seen := map[string]bool{}
+	add := func(all map[string][]string) {
+		keys := make([]string, 0, len(all))
+		for key := range all {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			for _, synonym := range append([]string{strings.ReplaceAll(key, "_", " ")}, all[key]...) {
+				words := splitLabel(synonym)
+				if len(words) == 0 || seen[strings.Join(words, " ")] {
+					continue
+				}
+				seen[strings.Join(words, " ")] = true
+				phrases = append(phrases, labelPhrase{key: key, words: words})
+			}
+		}
+	}
+	add(synonyms)
+	add(defaultLabelSynonyms)
+
+	sort.SliceStable(phrases, func(i, j int) bool {
+		return len(phrases[i].words) > len(phrases[j].words)
+	})
+	return phrases
+}
+
+// pageKeyValues finds the label and value pairs of a page's words
+func pageKeyValues(words []utils.OCRResponse, phrases []labelPhrase, pageNumber int) []utils.KeyValue {
+	heights := make([]int, len(words))
+	for i, word := range words {
+		heights[i] = word.BBox.BottomRight.Y - word.BBox.TopLeft.Y
+	}
+	sort.Ints(heights)
+	wordHeight := max(heights[len(heights)/2], 1)
+	columnGap := int(float64(wordHeight) * tableColumnGap)
+
+	var lines [][]tableSegment
+	for _, paragraph := range groupByPosition(words) {
+		for _, line := range paragraph {
+			lines = append(lines, splitLine(line, columnGap))
+		}
+	}
+
+	var keyValues []utils.KeyValue
+	// segments taken as the value of a label on a previous line or segment
+	used := map[[2]int]bool{}
+	for i, line := range lines {
+		for j, segment := range line {
+			if used[[2]int{i, j}] {
+				continue
+			}
+
+			labels := findLabels(segment.words, phrases)
+			for n, label := range labels {
+				valueEnd := len(segment.words)
+				if n+1 < len(labels) {
+					valueEnd = labels[n+1].start
+				}
+				labelWords := segment.words[label.start:label.end]
+				value := segment.words[label.end:valueEnd]
+
+				if len(value) == 0 && n+1 == len(labels) {
+					value = valueBeside(lines, i, j, phrases, used)
+				}
+				if len(value) == 0 && n+1 == len(labels) {
+					value = valueBelow(lines, i, labelWords, phrases, used, wordHeight)
+				}
+				if len(value) == 0 {
+					continue
+				}
+
+				keyValues = append(keyValues, newKeyValue(label, labelWords, value, pageNumber))
+			}
+		}
+	}
+	return keyValues
+}
+
+// findLabels returns the labels of a segment in order. A synonym is a label at
+// the start of the segment or when it ends in a colon, a word ending in a colon
+// ends a label that starts at the beginning of the segment or after the value
+// of the previous label.
+func findLabels(words []utils.OCRResponse, phrases []labelPhrase) []labelSpan {
+	var labels []labelSpan
+	for k := 0; k < len(words); {
+		// the previous label's value is at least one word
+		if len(labels) > 0 && k == labels[len(labels)-1].end {
+			k++
+			continue
+		}
+
+		if key, n := matchPhrase(words, k, phrases); n > 0 {
+			end := k + n
+			colon := strings.HasSuffix(words[end-1].Text, ":")
+			if end < len(words) && words[end].Text == ":" {
+				end++
+				colon = true
+			}
+			if k == 0 || colon {
+				labels = append(labels, labelSpan{start: k, end: end, key: key})
+				k = end
+				continue
+			}
+		}
+
+		if strings.HasSuffix(words[k].Text, ":") {
+			start := k
+			if len(labels) == 0 && k < maxLabelWords {
+				start = 0
+			}
+			if labelText(words[start:k+1]) != "" {
+				labels = append(labels, labelSpan{start: start, end: k + 1})
+			}
+		}
+		k++
+	}
+	return labels
+}
+
+// matchPhrase returns the key and length of the longest phrase the words at
+// index start begin with
+func matchPhrase(words []utils.OCRResponse, start int, phrases []labelPhrase) (string, int) {
+	for _, phrase := range phrases {
+		if start+len(phrase.words) > len(words) {
+			continue
+		}
+		matched := true
+		for i, word := range phrase.words {
+			if normalizeLabelWord(words[start+i].Text) != word {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return phrase.key, len(phrase.words)
+		}
+	}
+	return "", 0
+}
+
+// valueBeside takes the segment after segment j of line i as the value when it
+// has no labels of its own
+func valueBeside(lines [][]tableSegment, i, j int, phrases []labelPhrase, used map[[2]int]bool) []utils.OCRResponse {
+	if j+1 >= len(lines[i]) || used[[2]int{i, j + 1}] {
+		return nil
+	}
+	next := lines[i][j+1]
+	if len(findLabels(next.words, phrases)) > 0 {
+		return nil
+	}
+	used[[2]int{i, j + 1}] = true
+	return next.words
+}
+
+// valueBelow takes the segment of the next line that sits under the label as
+// the value when it is no further than two word heights below and has no labels
+func valueBelow(lines [][]tableSegment, i int, label []utils.OCRResponse, phrases []labelPhrase, used map[[2]int]bool, wordHeight int) []utils.OCRResponse {
+	if i+1 >= len(lines) {
+		return nil
+	}
+
+	labelElement := layoutElement(label, "")
+	var nextWords []utils.OCRResponse
+	for _, segment := range lines[i+1] {
+		nextWords = append(nextWords, segment.words...)
+	}
+	top, _ := lineExtent(nextWords)
+	if top-labelElement.BBox.BottomRight.Y > 2*wordHeight {
+		return nil
+	}
+
+	for j, segment := range lines[i+1] {
+		if used[[2]int{i + 1, j}] {
+			continue
+		}
+		if segment.left > labelElement.BBox.BottomRight.X || segment.right < labelElement.BBox.TopLeft.X {
+			continue
+		}
+		if len(findLabels(segment.words, phrases)) > 0 {
+			return nil
+		}
+		used[[2]int{i + 1, j}] = true
+		return segment.words
+	}
+	return nil
+}
+
+// newKeyValue returns the pair of the label and value words
+func newKeyValue(label labelSpan, labelWords []utils.OCRResponse, valueWords []utils.OCRResponse, pageNumber int) utils.KeyValue {
+	labelElement := layoutElement(labelWords, labelText(labelWords))
+	valueElement := layoutElement(valueWords, cellText(valueWords))
+	element := layoutElement(append(append([]utils.OCRResponse{}, labelWords...), valueWords...), "")
+
+	key := label.key
+	if key == "" {
+		key = snakeCase(labelElement.Text)
+	}
+
+	return utils.KeyValue{
+		PageNumber: pageNumber,
+		Key:        key,
+		Label:      labelElement.Text,
+		Value:      valueElement.Text,
+		Confidence: element.Confidence,
+		BBox:       element.BBox,
+		LabelBBox:  labelElement.BBox,
+		ValueBBox:  valueElement.BBox,
+	}
+}
+
+// labelText joins the words of a label without its trailing colon
+func labelText(words []utils.OCRResponse) string {
+	texts := make([]string, len(words))
+	for i, word := range words {
+		texts[i] = word.Text
+	}
+	return strings.TrimSpace(strings.TrimRight(strings.Join(texts, " "), ": "))
+}
+
+// snakeCase lower cases the label and joins its letters and digits with underscores
+func snakeCase(label string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(label), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), "_")
+}
+
+// splitLabel splits a label into its normalized words
+func splitLabel(label string) []string {
+	var words []string
+	for _, word := range strings.Fields(label) {
+		if normalized := normalizeLabelWord(word); normalized != "" {
+			words = append(words, normalized)
+		}
+	}
+	return words
+}
+
+// normalizeLabelWord lower cases a word and trims the punctuation around it,
+// keeping # as in "Invoice #"
+func normalizeLabelWord(word string) string {
+	return strings.ToLower(strings.TrimFunc(word, func(r rune) bool {
+		return r != '#' && !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}))
+}
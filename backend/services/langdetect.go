@@ -0,0 +1,82 @@
+package services
+
+import (
+	"context"
+	"log"
+	"serverless-tesseract/services/engines"
+	"serverless-tesseract/utils"
+	"strings"
+	"unicode"
+)
+
+// stopwords are frequent short words used to tell apart languages written in the same script
+var stopwords = map[string][]string{
+	"en": {"the", "and", "of", "to", "in", "is", "for", "that", "with", "on", "this", "are"},
+	"de": {"der", "die", "und", "das", "ist", "nicht", "mit", "den", "von", "zu", "ein", "für"},
+	"fr": {"le", "la", "les", "et", "des", "est", "une", "dans", "pour", "que", "du", "sur"},
+	"es": {"el", "la", "los", "las", "y", "que", "de", "en", "por", "con", "para", "una"},
+	"it": {"il", "di", "che", "e", "la", "per", "un", "non", "sono", "della", "con", "gli"},
+	"pt": {"o", "os", "que", "e", "do", "da", "em", "um", "para", "não", "uma", "com"},
+	"nl": {"de", "het", "een", "en", "van", "is", "niet", "dat", "op", "te", "voor", "met"},
+	"sv": {"och", "att", "det", "som", "en", "är", "på", "för", "med", "av", "inte", "den"},
+	"pl": {"i", "w", "nie", "na", "się", "jest", "że", "do", "z", "to", "jak", "od"},
+	"ru": {"и", "в", "не", "на", "что", "с", "по", "это", "как", "от", "для", "из"},
+	"uk": {"і", "в", "не", "на", "що", "з", "до", "це", "як", "від", "для", "та"},
+}
+
+// detectPageLanguages picks the languages to OCR a page with when languages=auto:
+// the script of the page is detected with engines.DetectScript and every installed
+// language written in it is used, up to LANGUAGE_DETECTION_MAX_LANGUAGES. The
+// default languages are used when the script cannot be detected.
+func detectPageLanguages(ctx context.Context, imageBytes []byte, engine utils.OCREngineType, pageNumber int) ([]string, utils.PageLanguage) {
+	detected := utils.PageLanguage{PageNumber: pageNumber}
+
+	ocrEngine, ok := engines.Get(engine)
+	if !ok {
+		return []string{engines.DefaultLanguage}, detected
+	}
+
+	script, confidence, err := engines.DetectScript(ctx, imageBytes)
+	if err != nil {
+		log.Printf("Failed to detect the script of page %d, using the default languages: %v", pageNumber, err)
+		languages, _ := engines.ResolveLanguages(ocrEngine, nil)
+		detected.Languages = languages
+		return languages, detected
+	}
+
+	detected.Script = script
+	detected.ScriptConfidence = confidence
+	detected.Languages = engines.LanguagesForScript(ocrEngine, script, utils.LANGUAGE_DETECTION_MAX_LANGUAGES)
+	return detected.Languages, detected
+}
+
+// detectTextLanguage returns the candidate language whose stopwords occur most
+// often in the recognized text, or the first candidate when none occur
+func detectTextLanguage(results utils.OCRResponseList, candidates []string) string {
+	if len(candidates) <= 1 {
+		return strings.Join(candidates, "")
+	}
+
+	counts := map[string]int{}
+	for _, response := range results.OCRResponses {
+		for _, word := range strings.FieldsFunc(strings.ToLower(response.Text), func(r rune) bool {
+			return !unicode.IsLetter(r)
+		}) {
+			for _, language := range candidates {
+				for _, stopword := range stopwords[language] {
+					if word == stopword {
+						counts[language]++
+					}
+				}
+			}
+		}
+	}
+
+	best := candidates[0]
+	for _, language := range candidates[1:] {
+		if counts[language] > counts[best] {
+			best = language
+		}
+	}
+	return best
+}
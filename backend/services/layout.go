@@ -0,0 +1,240 @@
+package services
+
+import (
+	"serverless-tesseract/utils"
+	"sort"
+	"strings"
+)
+
+type (
+	layoutLine      []utils.OCRResponse
+	layoutParagraph []layoutLine
+	layoutBlock     []layoutParagraph
+)
+
+// StructureResults arranges the words of the results into a page, block,
+// paragraph, line and word tree. Words from engines that detect layout are
+// grouped by the block, paragraph and line the engine reported. Other words,
+// such as EasyOCR's or a PDF text layer's, are grouped into lines by their
+// vertical overlap and into paragraphs by the gaps between lines, in a single
+// block per page.
+func StructureResults(results utils.OCRResponseList) utils.StructuredOCRResponse {
+	structured := utils.StructuredOCRResponse{
+		Pages:          []utils.StructuredPage{},
+		Engine:         results.Engine,
+		NumberOfTokens: results.NumberOfTokens,
+		Cached:         results.Cached,
+		Languages:      results.Languages,
+		PageLanguages:  results.PageLanguages,
+	}
+
+	var pageNumbers []int
+	pageWords := map[int][]utils.OCRResponse{}
+	for _, response := range results.OCRResponses {
+		if _, ok := pageWords[response.PageNumber]; !ok {
+			pageNumbers = append(pageNumbers, response.PageNumber)
+		}
+		pageWords[response.PageNumber] = append(pageWords[response.PageNumber], response)
+	}
+	for _, size := range results.PageSizes {
+		if _, ok := pageWords[size.PageNumber]; !ok {
+			// pages without any text are still part of the document
+			pageNumbers = append(pageNumbers, size.PageNumber)
+			pageWords[size.PageNumber] = nil
+		}
+	}
+	sort.Ints(pageNumbers)
+
+	sizes := map[int]utils.PageSize{}
+	for _, size := range results.PageSizes {
+		sizes[size.PageNumber] = size
+	}
+	tables := map[int][]utils.Table{}
+	for _, table := range results.Tables {
+		tables[table.PageNumber] = append(tables[table.PageNumber], table)
+	}
+	barcodes := map[int][]utils.Barcode{}
+	for _, barcode := range results.Barcodes {
+		barcodes[barcode.PageNumber] = append(barcodes[barcode.PageNumber], barcode)
+	}
+
+	for _, pageNumber := range pageNumbers {
+		words := pageWords[pageNumber]
+
+		var blocks []layoutBlock
+		if hasLayout(words) {
+			blocks = groupByLayout(words)
+		} else if len(words) > 0 {
+			blocks = []layoutBlock{groupByPosition(words)}
+		}
+
+		page := utils.StructuredPage{
+			PageNumber: pageNumber,
+			Width:      sizes[pageNumber].Width,
+			Height:     sizes[pageNumber].Height,
+			Blocks:     []utils.StructuredBlock{},
+			Tables:     tables[pageNumber],
+			Barcodes:   barcodes[pageNumber],
+		}
+		var blockTexts []string
+		for _, block := range blocks {
+			structuredBlock := structureBlock(block)
+			page.Blocks = append(page.Blocks, structuredBlock)
+			blockTexts = append(blockTexts, structuredBlock.Text)
+		}
+		page.LayoutElement = layoutElement(words, strings.Join(blockTexts, "\n\n"))
+
+		structured.Pages = append(structured.Pages, page)
+	}
+
+	return structured
+}
+
+func structureBlock(block layoutBlock) utils.StructuredBlock {
+	structured := utils.StructuredBlock{Paragraphs: []utils.StructuredParagraph{}}
+	var words []utils.OCRResponse
+	var paragraphTexts []string
+
+	for _, paragraph := range block {
+		structuredParagraph := utils.StructuredParagraph{Lines: []utils.StructuredLine{}}
+		var paragraphWords []utils.OCRResponse
+		var lineTexts []string
+
+		for _, line := range paragraph {
+			structuredLine := utils.StructuredLine{Words: []utils.LayoutElement{}}
+			var wordTexts []string
+			for _, word := range line {
+				structuredLine.Words = append(structuredLine.Words, utils.LayoutElement{
+					Text:       word.Text,
+					Confidence: word.Confidence,
+					BBox:       word.BBox,
+				})
+				wordTexts = append(wordTexts, word.Text)
+			}
+			structuredLine.LayoutElement = layoutElement(line, strings.Join(wordTexts, " "))
+
+			structuredParagraph.Lines = append(structuredParagraph.Lines, structuredLine)
+			paragraphWords = append(paragraphWords, line...)
+			lineTexts = append(lineTexts, structuredLine.Text)
+		}
+		structuredParagraph.LayoutElement = layoutElement(paragraphWords, strings.Join(lineTexts, "\n"))
+
+		structured.Paragraphs = append(structured.Paragraphs, structuredParagraph)
+		words = append(words, paragraphWords...)
+		paragraphTexts = append(paragraphTexts, structuredParagraph.Text)
+	}
+	structured.LayoutElement = layoutElement(words, strings.Join(paragraphTexts, "\n\n"))
+
+	return structured
+}
+
+// layoutElement returns the union of the words' bounding boxes and their mean confidence
+func layoutElement(words []utils.OCRResponse, text string) utils.LayoutElement {
+	element := utils.LayoutElement{Text: text}
+	if len(words) == 0 {
+		return element
+	}
+
+	left, top := words[0].BBox.TopLeft.X, words[0].BBox.TopLeft.Y
+	right, bottom := words[0].BBox.BottomRight.X, words[0].BBox.BottomRight.Y
+	confidence := 0.0
+	for _, word := range words {
+		left = min(left, word.BBox.TopLeft.X)
+		top = min(top, word.BBox.TopLeft.Y)
+		right = max(right, word.BBox.BottomRight.X)
+		bottom = max(bottom, word.BBox.BottomRight.Y)
+		confidence += word.Confidence
+	}
+
+	element.BBox = newBBox(left, top, right, bottom)
+	element.Confidence = confidence / float64(len(words))
+	return element
+}
+
+// hasLayout reports whether the engine reported the line of every word
+func hasLayout(words []utils.OCRResponse) bool {
+	for _, word := range words {
+		if word.Line == 0 {
+			return false
+		}
+	}
+	return len(words) > 0
+}
+
+// groupByLayout groups the words by the block, paragraph and line numbers the
+// engine reported, in the order they first appear
+func groupByLayout(words []utils.OCRResponse) []layoutBlock {
+	var blocks []layoutBlock
+	var block, paragraph, line int
+
+	for _, word := range words {
+		newBlock := len(blocks) == 0 || word.Block != block
+		newParagraph := newBlock || word.Paragraph != paragraph
+		newLine := newParagraph || word.Line != line
+
+		if newBlock {
+			blocks = append(blocks, layoutBlock{})
+		}
+		current := &blocks[len(blocks)-1]
+		if newParagraph {
+			*current = append(*current, layoutParagraph{})
+		}
+		currentParagraph := &(*current)[len(*current)-1]
+		if newLine {
+			*currentParagraph = append(*currentParagraph, layoutLine{})
+		}
+		currentLine := &(*currentParagraph)[len(*currentParagraph)-1]
+		*currentLine = append(*currentLine, word)
+
+		block, paragraph, line = word.Block, word.Paragraph, word.Line
+	}
+
+	return blocks
+}
+
+// groupByPosition groups words into lines when their vertical centers fall within
+// a line's extent, and starts a new paragraph when the gap above a line is taller
+// than the line
+func groupByPosition(words []utils.OCRResponse) layoutBlock {
+	sorted := append([]utils.OCRResponse{}, words...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].BBox.TopLeft.Y < sorted[j].BBox.TopLeft.Y
+	})
+
+	type lineExtent struct {
+		top, bottom int
+		words       layoutLine
+	}
+	var lines []lineExtent
+	for _, word := range sorted {
+		center := (word.BBox.TopLeft.Y + word.BBox.BottomRight.Y) / 2
+		placed := false
+		for i := range lines {
+			if center >= lines[i].top && center <= lines[i].bottom {
+				lines[i].words = append(lines[i].words, word)
+				lines[i].top = min(lines[i].top, word.BBox.TopLeft.Y)
+				lines[i].bottom = max(lines[i].bottom, word.BBox.BottomRight.Y)
+				placed = true
+				break
+			}
+		}
+		if !placed {
+			lines = append(lines, lineExtent{top: word.BBox.TopLeft.Y, bottom: word.BBox.BottomRight.Y, words: layoutLine{word}})
+		}
+	}
+	sort.SliceStable(lines, func(i, j int) bool { return lines[i].top < lines[j].top })
+
+	var block layoutBlock
+	for i, line := range lines {
+		sort.SliceStable(line.words, func(a, b int) bool {
+			return line.words[a].BBox.TopLeft.X < line.words[b].BBox.TopLeft.X
+		})
+
+		if i == 0 || line.top-lines[i-1].bottom > line.bottom-line.top {
+			block = append(block, layoutParagraph{})
+		}
+		block[len(block)-1] = append(block[len(block)-1], line.words)
+	}
+
+	return block
+}
@@ -1,46 +1,24 @@
 package services
 
 import (
+	"context"
 	"fmt"
-	externalscripts "serverless-tesseract/services/external_scripts"
+	"serverless-tesseract/services/engines"
 	"serverless-tesseract/utils"
-	"strconv"
 )
 
-// RunOCR processes an image with tesseract and returns the text
-func RunOCR(imageBytes []byte, engine utils.OCREngineType, pageNumber int, raw bool) (utils.OCRResponseList, error) {
-	switch engine {
-	case utils.EngineTesseract:
-		return tesseractOCR(imageBytes, pageNumber, raw)
-	case utils.EngineEasyOCR:
-		return easyOCR(imageBytes, pageNumber, raw)
-	case utils.EngineDoctoR:
-		return doctrOCR(imageBytes, pageNumber, raw)
-	default:
+// RunOCR processes an image with the registered engine and returns the text.
+// whitelist, when set, limits the characters the engine recognizes.
+func RunOCR(ctx context.Context, imageBytes []byte, engine utils.OCREngineType, pageNumber int, raw bool, languages []string, whitelist string) (utils.OCRResponseList, error) {
+	ocrEngine, ok := engines.Get(engine)
+	if !ok {
 		return utils.OCRResponseList{}, fmt.Errorf("invalid engine: %s", engine)
 	}
-}
-
-func tesseractOCR(imageBytes []byte, pageNumber int, raw bool) (utils.OCRResponseList, error) {
-	args := []string{strconv.Itoa(pageNumber)}
-	if raw {
-		args = append(args, "raw")
-	}
-	return externalscripts.ExecutePythonOCREngineScript("tesseract_ocr.py", imageBytes, args...)
-}
 
-func easyOCR(imageBytes []byte, pageNumber int, raw bool) (utils.OCRResponseList, error) {
-	args := []string{strconv.Itoa(pageNumber)}
-	if raw {
-		args = append(args, "raw")
-	}
-	return externalscripts.ExecutePythonOCREngineScript("easyocr_ocr.py", imageBytes, args...)
-}
-
-func doctrOCR(imageBytes []byte, pageNumber int, raw bool) (utils.OCRResponseList, error) {
-	args := []string{strconv.Itoa(pageNumber)}
-	if raw {
-		args = append(args, "raw")
-	}
-	return externalscripts.ExecutePythonOCREngineScript("doctr_ocr.py", imageBytes, args...)
+	return ocrEngine.Recognize(ctx, imageBytes, engines.Options{
+		PageNumber: pageNumber,
+		Raw:        raw,
+		Languages:  languages,
+		Whitelist:  whitelist,
+	})
 }
@@ -0,0 +1,86 @@
+package services
+
+import (
+	"context"
+	"serverless-tesseract/utils"
+	"sync"
+)
+
+// RenderedPage is a single page of a document ready for OCR, as yielded by
+// StreamPDF and StreamTIFF. Err is set on the last page sent when reading the
+// document fails.
+type RenderedPage struct {
+	// Number is the page number in the original document
+	Number int
+	// Image is the page as a PNG or JPEG image
+	Image []byte
+	// TextLayer is set instead of Image when the page was read from the PDF's text
+	// layer, Image is set as well when the page is rendered to detect barcodes
+	TextLayer *utils.OCRResponseList
+	Err       error
+
+	// index is the position of the page among the selected pages
+	index   int
+	release func()
+}
+
+// Release frees the page's in-flight slot so the next page can be rendered. It
+// must be called once the page image is no longer needed, calling it more than
+// once is safe.
+func (p RenderedPage) Release() {
+	if p.release != nil {
+		p.release()
+	}
+}
+
+// streamPages reads the pages one at a time with read and sends them on the
+// returned channel in order. At most maxInFlight pages are read and not yet
+// released at once, the reader waits for a page to be released before reading
+// the next one. The channel is closed once every page has been sent, after a
+// page with Err set, or when the context is done.
+func streamPages(
+	ctx context.Context,
+	pageNumbers []int,
+	maxInFlight int,
+	read func(pageNumber int) ([]byte, *utils.OCRResponseList, error),
+) <-chan RenderedPage {
+	if maxInFlight < 1 {
+		maxInFlight = 1
+	}
+
+	pages := make(chan RenderedPage)
+	slots := make(chan struct{}, maxInFlight)
+
+	go func() {
+		defer close(pages)
+
+		for index, i := range pageNumbers {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+
+			var once sync.Once
+			release := func() {
+				once.Do(func() { <-slots })
+			}
+
+			page := RenderedPage{Number: i, index: index, release: release}
+			page.Image, page.TextLayer, page.Err = read(i)
+
+			select {
+			case pages <- page:
+			case <-ctx.Done():
+				release()
+				return
+			}
+
+			if page.Err != nil {
+				return
+			}
+		}
+	}()
+
+	return pages
+}
@@ -2,78 +2,157 @@ package services
 
 import (
 	"bytes"
+	"context"
 	"fmt"
+	"image"
 	"image/png"
 	"log"
+	"math"
 	"serverless-tesseract/utils"
 
+	"github.com/disintegration/imaging"
 	"github.com/unidoc/unipdf/v3/model"
 	"github.com/unidoc/unipdf/v3/render"
 )
 
-// ProcessPDF takes PDF bytes, renders each page as a high-resolution image using UniPDF,
-// encodes them as PNG, and returns a slice of PNG-encoded byte slices.
-func ProcessPDF(pdfBytes *[]byte) ([][]byte, error) {
+// StreamPDF takes PDF bytes and renders each page selected by opts.Pages as a
+// high-resolution PNG image using UniPDF, one page at a time, so OCR can start
+// on the first page while later pages are still rendering. The number of
+// selected pages is returned up front, an empty selection selects every page.
+//
+// Depending on opts.TextLayer pages are read from the PDF's embedded text layer
+// instead of being rendered, see readPage.
+//
+// At most maxInFlight pages are rendered and not yet released at once, see streamPages.
+func StreamPDF(ctx context.Context, pdfBytes *[]byte, opts DocumentOptions, maxInFlight int) (<-chan RenderedPage, int, error) {
+	pdfReader, numPages, err := openPDF(pdfBytes)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	pageNumbers, err := opts.Pages.Resolve(numPages)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return streamPages(ctx, pageNumbers, maxInFlight, func(i int) ([]byte, *utils.OCRResponseList, error) {
+		return readPage(pdfReader, i, opts)
+	}), len(pageNumbers), nil
+}
+
+// CountPDFPages returns the number of pages in the PDF
+func CountPDFPages(pdfBytes *[]byte) (int, error) {
+	_, numPages, err := openPDF(pdfBytes)
+	return numPages, err
+}
+
+func openPDF(pdfBytes *[]byte) (*model.PdfReader, int, error) {
 	reader := bytes.NewReader(*pdfBytes)
 
 	pdfReader, err := model.NewPdfReader(reader)
 	if err != nil {
 		log.Printf("Failed to create PDF reader: %v", err)
-		return nil, fmt.Errorf("failed to create PDF reader: %w", err)
+		return nil, 0, fmt.Errorf("failed to create PDF reader: %w", err)
 	}
 
 	numPages, err := pdfReader.GetNumPages()
 	if err != nil {
 		log.Printf("Failed to get number of pages: %v", err)
-		return nil, fmt.Errorf("failed to get number of pages: %w", err)
+		return nil, 0, fmt.Errorf("failed to get number of pages: %w", err)
 	}
 
-	var pngPages [][]byte
+	if numPages == 0 {
+		log.Printf("No pages were rendered")
+		return nil, 0, fmt.Errorf("no pages were rendered")
+	}
+
+	return pdfReader, numPages, nil
+}
 
-	for i := 1; i <= numPages; i++ {
+// readPage returns the words of the page's text layer when text_layer is "only",
+// or when it is "auto" and the text layer has at least TEXT_LAYER_MIN_CHARACTERS
+// characters. Otherwise the page is rendered for OCR. Pages read from the text
+// layer are rendered as well when barcodes are detected.
+func readPage(pdfReader *model.PdfReader, i int, opts DocumentOptions) ([]byte, *utils.OCRResponseList, error) {
+	if opts.TextLayer == utils.TextLayerAuto || opts.TextLayer == utils.TextLayerOnly {
 		page, err := pdfReader.GetPage(i)
 		if err != nil {
 			log.Printf("Failed to get page %d: %v", i, err)
-			return nil, fmt.Errorf("failed to get page %d: %w", i, err)
+			return nil, nil, fmt.Errorf("failed to get page %d: %w", i, err)
 		}
 
-		// Get page dimensions in points (1 point = 1/72 inch)
-		mediaBox, err := page.GetMediaBox()
+		textLayer, characters, err := extractTextLayer(page, i, opts.Raw)
+		if err != nil && opts.TextLayer == utils.TextLayerOnly {
+			return nil, nil, err
+		}
 		if err != nil {
-			log.Printf("Failed to get page size for page %d: %v", i, err)
-			return nil, fmt.Errorf("failed to get page size for page %d: %w", i, err)
+			log.Printf("Failed to extract the text layer of page %d, falling back to OCR: %v", i, err)
+		} else if opts.TextLayer == utils.TextLayerOnly || characters >= utils.TEXT_LAYER_MIN_CHARACTERS {
+			if opts.Barcodes {
+				// barcodes are drawn, not written in the text layer, so the page is rendered to find them
+				img, err := renderPage(pdfReader, i)
+				return img, &textLayer, err
+			}
+			return nil, &textLayer, nil
 		}
+	}
 
-		pageWidth := mediaBox.Width()
+	img, err := renderPage(pdfReader, i)
+	return img, nil, err
+}
 
-		// Calculate output image width in pixels
-		outputWidth := int((pageWidth / 72.0) * utils.PDF_RENDER_DPI)
+// renderPage renders a single page of the PDF at PDF_RENDER_DPI and encodes it
+// as PNG. The page is turned by its /Rotate and cropped to its CropBox, see
+// PageGeometry, so text layer words line up with the rendered page.
+func renderPage(pdfReader *model.PdfReader, i int) ([]byte, error) {
+	page, err := pdfReader.GetPage(i)
+	if err != nil {
+		log.Printf("Failed to get page %d: %v", i, err)
+		return nil, fmt.Errorf("failed to get page %d: %w", i, err)
+	}
+
+	geometry, err := NewPageGeometry(page)
+	if err != nil {
+		log.Printf("Failed to get page size for page %d: %v", i, err)
+		return nil, fmt.Errorf("failed to get page size for page %d: %w", i, err)
+	}
 
-		// Create a new ImageDevice with specified output width
-		processor := render.NewImageDevice()
-		processor.OutputWidth = outputWidth
+	// the renderer places the CropBox wrongly on rotated pages, so the whole
+	// MediaBox is rendered and cropped here
+	mediaBox, _ := page.GetMediaBox()
+	full := PageGeometry{Box: normalizedRectangle(*mediaBox), Rotate: geometry.Rotate}
+	fullWidth, _ := full.Size()
 
-		// Render the page to an image
-		img, err := processor.Render(page)
-		if err != nil {
-			log.Printf("Failed to render page %d: %v", i, err)
-			return nil, fmt.Errorf("failed to render page %d: %w", i, err)
-		}
+	// Create a new ImageDevice with specified output width
+	processor := render.NewImageDevice()
+	processor.OutputWidth = int(math.Round(fullWidth / 72.0 * utils.PDF_RENDER_DPI))
 
-		// Encode the image to PNG
-		var buf bytes.Buffer
-		if err := png.Encode(&buf, img); err != nil {
-			log.Printf("Failed to encode page %d as PNG: %v", i, err)
-			return nil, fmt.Errorf("failed to encode page %d as PNG: %w", i, err)
-		}
+	cropBox := page.CropBox
+	page.CropBox = nil
+	rendered, err := processor.Render(page)
+	page.CropBox = cropBox
+	if err != nil {
+		log.Printf("Failed to render page %d: %v", i, err)
+		return nil, fmt.Errorf("failed to render page %d: %w", i, err)
+	}
 
-		pngPages = append(pngPages, buf.Bytes())
+	scale := float64(processor.OutputWidth) / fullWidth
+	left, top, right, bottom := full.ToDisplayRectangle(geometry.Box)
+	visible := image.Rect(
+		int(math.Round(left*scale)), int(math.Round(top*scale)),
+		int(math.Round(right*scale)), int(math.Round(bottom*scale)),
+	).Add(rendered.Bounds().Min).Intersect(rendered.Bounds())
+	img := rendered
+	if visible != rendered.Bounds() {
+		img = imaging.Crop(rendered, visible)
 	}
 
-	if len(pngPages) == 0 {
-		log.Printf("No pages were rendered")
-		return nil, fmt.Errorf("no pages were rendered")
+	// Encode the image to PNG
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		log.Printf("Failed to encode page %d as PNG: %v", i, err)
+		return nil, fmt.Errorf("failed to encode page %d as PNG: %w", i, err)
 	}
 
-	return pngPages, nil
+	return buf.Bytes(), nil
 }
@@ -0,0 +1,112 @@
+package services
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// PageGeometry maps the user space of a PDF page to the page as it is displayed,
+// cropped to its CropBox and turned clockwise by its /Rotate. Displayed
+// coordinates are in points with the origin in the top left, the way rendered
+// pages and OCR bounding boxes are laid out.
+type PageGeometry struct {
+	// Box is the visible part of the page in user space, the CropBox clipped to
+	// the MediaBox
+	Box model.PdfRectangle
+	// Rotate is the clockwise rotation of the displayed page, 0, 90, 180 or 270
+	Rotate int
+}
+
+// NewPageGeometry reads the MediaBox, CropBox and /Rotate of the page
+func NewPageGeometry(page *model.PdfPage) (PageGeometry, error) {
+	mediaBox, err := page.GetMediaBox()
+	if err != nil {
+		return PageGeometry{}, err
+	}
+	box := normalizedRectangle(*mediaBox)
+
+	if page.CropBox != nil {
+		crop := normalizedRectangle(*page.CropBox)
+		clipped := model.PdfRectangle{
+			Llx: math.Max(box.Llx, crop.Llx),
+			Lly: math.Max(box.Lly, crop.Lly),
+			Urx: math.Min(box.Urx, crop.Urx),
+			Ury: math.Min(box.Ury, crop.Ury),
+		}
+		if clipped.Width() > 0 && clipped.Height() > 0 {
+			box = clipped
+		}
+	}
+	if box.Width() <= 0 || box.Height() <= 0 {
+		return PageGeometry{}, fmt.Errorf("empty page box %v", box)
+	}
+
+	rotate := 0
+	if page.Rotate != nil && *page.Rotate%90 == 0 {
+		rotate = int((*page.Rotate%360 + 360) % 360)
+	}
+
+	return PageGeometry{Box: box, Rotate: rotate}, nil
+}
+
+// Size returns the width and height of the displayed page in points
+func (g PageGeometry) Size() (float64, float64) {
+	if g.Rotate == 90 || g.Rotate == 270 {
+		return g.Box.Height(), g.Box.Width()
+	}
+	return g.Box.Width(), g.Box.Height()
+}
+
+// ToDisplay maps a point of user space to the displayed page
+func (g PageGeometry) ToDisplay(x, y float64) (float64, float64) {
+	// the unrotated page with the origin in its top left
+	u, v := x-g.Box.Llx, g.Box.Ury-y
+	w, h := g.Box.Width(), g.Box.Height()
+
+	switch g.Rotate {
+	case 90:
+		return h - v, u
+	case 180:
+		return w - u, h - v
+	case 270:
+		return v, w - u
+	}
+	return u, v
+}
+
+// ToUserSpace maps a point of the displayed page back to user space, it is the
+// inverse of ToDisplay
+func (g PageGeometry) ToUserSpace(x, y float64) (float64, float64) {
+	w, h := g.Box.Width(), g.Box.Height()
+
+	u, v := x, y
+	switch g.Rotate {
+	case 90:
+		u, v = y, h-x
+	case 180:
+		u, v = w-x, h-y
+	case 270:
+		u, v = w-y, x
+	}
+	return u + g.Box.Llx, g.Box.Ury - v
+}
+
+// ToDisplayRectangle maps a rectangle of user space to the displayed page,
+// returning its left, top, right and bottom edges
+func (g PageGeometry) ToDisplayRectangle(rect model.PdfRectangle) (float64, float64, float64, float64) {
+	x1, y1 := g.ToDisplay(rect.Llx, rect.Lly)
+	x2, y2 := g.ToDisplay(rect.Urx, rect.Ury)
+	return math.Min(x1, x2), math.Min(y1, y2), math.Max(x1, x2), math.Max(y1, y2)
+}
+
+// normalizedRectangle orders the corners of a rectangle, PDFs may list them either way
+func normalizedRectangle(rect model.PdfRectangle) model.PdfRectangle {
+	return model.PdfRectangle{
+		Llx: math.Min(rect.Llx, rect.Urx),
+		Lly: math.Min(rect.Lly, rect.Ury),
+		Urx: math.Max(rect.Llx, rect.Urx),
+		Ury: math.Max(rect.Lly, rect.Ury),
+	}
+}
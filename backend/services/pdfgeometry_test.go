@@ -0,0 +1,153 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/png"
+	"math"
+	"serverless-tesseract/utils"
+	"testing"
+
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// testPDF builds a single page PDF with a black rectangle at rect, given in user
+// space as "x y width height"
+func testPDF(mediaBox string, cropBox string, rotate int, rect string) []byte {
+	content := "0 0 0 rg " + rect + " re f"
+	page := "<< /Type /Page /Parent 2 0 R /MediaBox [" + mediaBox + "] /Contents 4 0 R"
+	if cropBox != "" {
+		page += " /CropBox [" + cropBox + "]"
+	}
+	if rotate != 0 {
+		page += fmt.Sprintf(" /Rotate %d", rotate)
+	}
+	page += " >>"
+
+	var buf bytes.Buffer
+	var offsets []int
+	buf.WriteString("%PDF-1.4\n")
+	for _, object := range []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		page,
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content),
+	} {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), object)
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	return buf.Bytes()
+}
+
+// darkBounds returns the bounds of the dark pixels of the image
+func darkBounds(img image.Image) image.Rectangle {
+	var dark image.Rectangle
+	for y := img.Bounds().Min.Y; y < img.Bounds().Max.Y; y++ {
+		for x := img.Bounds().Min.X; x < img.Bounds().Max.X; x++ {
+			if r, _, _, _ := img.At(x, y).RGBA(); r < 0x4000 {
+				dark = dark.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+	return dark
+}
+
+func TestPageGeometryMatchesRenderedPage(t *testing.T) {
+	tests := []struct {
+		mediaBox string
+		cropBox  string
+		rotate   int
+	}{
+		{"0 0 200 100", "", 0},
+		{"0 0 200 100", "", 90},
+		{"0 0 200 100", "", 180},
+		{"0 0 200 100", "", 270},
+		{"0 0 200 100", "", -90},
+		{"-20 -10 180 90", "", 90},
+		{"0 0 200 100", "5 10 150 95", 0},
+		{"0 0 200 100", "5 10 150 95", 90},
+		{"0 0 200 100", "5 10 150 95", 180},
+		{"0 0 200 100", "5 10 150 95", 270},
+		{"0 0 200 100", "150 95 5 10", 90},
+	}
+
+	scale := utils.PDF_RENDER_DPI / 72
+	rect := model.PdfRectangle{Llx: 60, Lly: 60, Urx: 80, Ury: 70}
+	for _, test := range tests {
+		name := fmt.Sprintf("media %s crop %s rotate %d", test.mediaBox, test.cropBox, test.rotate)
+		t.Run(name, func(t *testing.T) {
+			pdf := testPDF(test.mediaBox, test.cropBox, test.rotate, "60 60 20 10")
+			reader, _, err := openPDF(&pdf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			page, err := reader.GetPage(1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			geometry, err := NewPageGeometry(page)
+			if err != nil {
+				t.Fatalf("NewPageGeometry() error = %v", err)
+			}
+
+			pngBytes, err := renderPage(reader, 1)
+			if err != nil {
+				t.Fatalf("renderPage() error = %v", err)
+			}
+			img, err := png.Decode(bytes.NewReader(pngBytes))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			width, height := geometry.Size()
+			size := img.Bounds().Size()
+			if math.Abs(float64(size.X)-width*scale) > 2 || math.Abs(float64(size.Y)-height*scale) > 2 {
+				t.Errorf("rendered page is %v, want %.0fx%.0f", size, width*scale, height*scale)
+			}
+
+			left, top, right, bottom := geometry.ToDisplayRectangle(rect)
+			dark := darkBounds(img)
+			for _, edge := range []struct {
+				got  int
+				want float64
+			}{
+				{dark.Min.X, left * scale}, {dark.Min.Y, top * scale},
+				{dark.Max.X, right * scale}, {dark.Max.Y, bottom * scale},
+			} {
+				if math.Abs(float64(edge.got)-edge.want) > 2 {
+					t.Errorf("rectangle rendered at %v, want (%.0f,%.0f)-(%.0f,%.0f)", dark, left*scale, top*scale, right*scale, bottom*scale)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestPageGeometryToUserSpace(t *testing.T) {
+	box := model.PdfRectangle{Llx: 5, Lly: 10, Urx: 150, Ury: 95}
+	for _, rotate := range []int{0, 90, 180, 270} {
+		geometry := PageGeometry{Box: box, Rotate: rotate}
+		for _, point := range [][2]float64{{5, 10}, {150, 95}, {60, 70}, {149, 11}} {
+			x, y := geometry.ToDisplay(point[0], point[1])
+			if gotX, gotY := geometry.ToUserSpace(x, y); math.Abs(gotX-point[0]) > 1e-9 || math.Abs(gotY-point[1]) > 1e-9 {
+				t.Errorf("rotate %d: ToUserSpace(ToDisplay(%v)) = (%v, %v)", rotate, point, gotX, gotY)
+			}
+		}
+	}
+
+	// the top left corner of the displayed page is the corner the rotation turned there
+	corners := map[int][2]float64{0: {5, 95}, 90: {5, 10}, 180: {150, 10}, 270: {150, 95}}
+	for rotate, corner := range corners {
+		if x, y := (PageGeometry{Box: box, Rotate: rotate}).ToUserSpace(0, 0); x != corner[0] || y != corner[1] {
+			t.Errorf("rotate %d: top left corner is (%v, %v), want %v", rotate, x, y, corner)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package preprocess
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"sort"
+
+	"github.com/disintegration/imaging"
+)
+
+// normalizeContrast stretches the brightness so the darkest percent of the
+// pixels becomes black and the brightest percent becomes white
+func normalizeContrast(img image.Image) image.Image {
+	histogram := imaging.Histogram(img)
+
+	low, high := 0, 255
+	cumulative := 0.0
+	for i, share := range histogram {
+		cumulative += share
+		if cumulative >= 0.01 {
+			low = i
+			break
+		}
+	}
+	cumulative = 0
+	for i := 255; i >= 0; i-- {
+		cumulative += histogram[i]
+		if cumulative >= 0.01 {
+			high = i
+			break
+		}
+	}
+
+	if high <= low {
+		return img
+	}
+
+	stretch := func(v uint8) uint8 {
+		scaled := (float64(v) - float64(low)) * 255 / float64(high-low)
+		return uint8(math.Max(0, math.Min(255, scaled)))
+	}
+	return imaging.AdjustFunc(img, func(c color.NRGBA) color.NRGBA {
+		return color.NRGBA{R: stretch(c.R), G: stretch(c.G), B: stretch(c.B), A: c.A}
+	})
+}
+
+// medianFilter replaces every pixel with the median of its 3x3 neighbourhood,
+// which removes speckles without blurring the edges of characters
+func medianFilter(img image.Image) image.Image {
+	src := imaging.Clone(img)
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+
+	window := make([]uint8, 0, 9)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			offset := y*dst.Stride + x*4
+			for channel := 0; channel < 3; channel++ {
+				window = window[:0]
+				for dy := -1; dy <= 1; dy++ {
+					for dx := -1; dx <= 1; dx++ {
+						nx := min(max(x+dx, 0), width-1)
+						ny := min(max(y+dy, 0), height-1)
+						window = append(window, src.Pix[ny*src.Stride+nx*4+channel])
+					}
+				}
+				sort.Slice(window, func(i, j int) bool { return window[i] < window[j] })
+				dst.Pix[offset+channel] = window[4]
+			}
+			dst.Pix[offset+3] = src.Pix[y*src.Stride+x*4+3]
+		}
+	}
+	return dst
+}
+
+// adaptiveThreshold binarizes the image with Bradley's method: a pixel becomes
+// black when it is darker than the mean of its neighbourhood by more than 15%.
+// The neighbourhood means are computed from an integral image.
+func adaptiveThreshold(img image.Image) image.Image {
+	gray := toGray(img)
+	width, height := gray.Rect.Dx(), gray.Rect.Dy()
+
+	integral := make([]int64, (width+1)*(height+1))
+	for y := 0; y < height; y++ {
+		var rowSum int64
+		for x := 0; x < width; x++ {
+			rowSum += int64(gray.Pix[y*gray.Stride+x])
+			integral[(y+1)*(width+1)+x+1] = integral[y*(width+1)+x+1] + rowSum
+		}
+	}
+
+	radius := max(width/16, 8) / 2
+	const sensitivity = 0.15
+
+	dst := image.NewGray(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0, y1 := max(y-radius, 0), min(y+radius+1, height)
+		for x := 0; x < width; x++ {
+			x0, x1 := max(x-radius, 0), min(x+radius+1, width)
+			count := int64((x1 - x0) * (y1 - y0))
+			sum := integral[y1*(width+1)+x1] - integral[y0*(width+1)+x1] - integral[y1*(width+1)+x0] + integral[y0*(width+1)+x0]
+
+			value := int64(gray.Pix[y*gray.Stride+x])
+			if float64(value*count) < float64(sum)*(1-sensitivity) {
+				dst.Pix[y*dst.Stride+x] = 0
+			} else {
+				dst.Pix[y*dst.Stride+x] = 255
+			}
+		}
+	}
+	return dst
+}
+
+// deskew finds the rotation that makes the text lines horizontal, that is the
+// angle whose horizontal projection of the dark pixels has the sharpest peaks,
+// and rotates the image by it. Angles up to 15 degrees are corrected, the angle
+// the image was rotated by is returned, 0 when it was not rotated.
+func deskew(img image.Image) (image.Image, float64) {
+	// estimate the angle on a small copy, precision is not lost at this size
+	sample := img
+	if img.Bounds().Dx() > 1000 {
+		sample = imaging.Resize(img, 1000, 0, imaging.Box)
+	}
+	gray := toGray(sample)
+	width, height := gray.Rect.Dx(), gray.Rect.Dy()
+
+	threshold := otsuThreshold(gray)
+	var points []image.Point
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if gray.Pix[y*gray.Stride+x] < threshold {
+				points = append(points, image.Point{X: x, Y: y})
+			}
+		}
+	}
+	if len(points) == 0 {
+		return img, 0
+	}
+
+	score := func(angle float64) float64 {
+		sin, cos := math.Sincos(angle * math.Pi / 180)
+		offset := float64(width)
+		bins := make([]float64, height+2*width+1)
+		for _, p := range points {
+			// the row the pixel ends up in after rotating counter-clockwise by angle
+			row := int(-float64(p.X)*sin + float64(p.Y)*cos + offset)
+			if row >= 0 && row < len(bins) {
+				bins[row]++
+			}
+		}
+		var sum float64
+		for _, count := range bins {
+			sum += count * count
+		}
+		return sum
+	}
+
+	best, bestScore := 0.0, score(0)
+	for angle := -15.0; angle <= 15; angle += 0.5 {
+		if s := score(angle); s > bestScore {
+			best, bestScore = angle, s
+		}
+	}
+	coarse := best
+	for angle := coarse - 0.5; angle <= coarse+0.5; angle += 0.1 {
+		if s := score(angle); s > bestScore {
+			best, bestScore = angle, s
+		}
+	}
+
+	if math.Abs(best) < 0.1 {
+		return img, 0
+	}
+	return imaging.Rotate(img, best, color.White), best
+}
+
+// otsuThreshold returns the gray level that best separates dark and light pixels
+func otsuThreshold(gray *image.Gray) uint8 {
+	var histogram [256]float64
+	for _, v := range gray.Pix {
+		histogram[v]++
+	}
+
+	total := float64(len(gray.Pix))
+	var sumAll float64
+	for i, count := range histogram {
+		sumAll += float64(i) * count
+	}
+
+	var sumDark, weightDark, bestVariance float64
+	var threshold uint8
+	for i, count := range histogram {
+		weightDark += count
+		if weightDark == 0 {
+			continue
+		}
+		weightLight := total - weightDark
+		if weightLight == 0 {
+			break
+		}
+		sumDark += float64(i) * count
+		meanDark := sumDark / weightDark
+		meanLight := (sumAll - sumDark) / weightLight
+		variance := weightDark * weightLight * (meanDark - meanLight) * (meanDark - meanLight)
+		if variance > bestVariance {
+			bestVariance = variance
+			threshold = uint8(i)
+		}
+	}
+	return threshold + 1
+}
+
+func toGray(img image.Image) *image.Gray {
+	if gray, ok := img.(*image.Gray); ok && gray.Rect.Min == (image.Point{}) {
+		return gray
+	}
+
+	bounds := img.Bounds()
+	gray := image.NewGray(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			gray.Set(x, y, img.At(bounds.Min.X+x, bounds.Min.Y+y))
+		}
+	}
+	return gray
+}
@@ -0,0 +1,168 @@
+package preprocess
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/png"
+	"serverless-tesseract/utils"
+	"strings"
+
+	"github.com/disintegration/imaging"
+)
+
+// Step is a single image cleanup step applied to a page before OCR
+type Step string
+
+const (
+	// Orientation rotates photos upright according to their EXIF orientation
+	Orientation Step = "orientation"
+	// Upscale enlarges low resolution images, see PREPROCESS_UPSCALE_MIN_SIZE
+	Upscale Step = "upscale"
+	// Grayscale removes color
+	Grayscale Step = "grayscale"
+	// Contrast stretches the brightness range of the image to the full range
+	Contrast Step = "contrast"
+	// Denoise removes speckle noise with a median filter
+	Denoise Step = "denoise"
+	// Deskew straightens text lines that are slightly rotated
+	Deskew Step = "deskew"
+	// Threshold binarizes the image with an adaptive threshold, which copes with uneven lighting
+	Threshold Step = "threshold"
+)
+
+// StepValues are the steps in the order they are applied, regardless of the
+// order they were requested in
+var StepValues = []Step{
+	Orientation,
+	Upscale,
+	Grayscale,
+	Contrast,
+	Denoise,
+	Deskew,
+	Threshold,
+}
+
+// Steps is a set of preprocessing steps, always in StepValues order
+type Steps []Step
+
+// Parse reads a comma separated list of steps such as "deskew,threshold". "all"
+// selects every step and an empty string or "none" selects none.
+func Parse(value string) (Steps, error) {
+	value = strings.TrimSpace(strings.ToLower(value))
+	if value == "" || value == "none" {
+		return nil, nil
+	}
+	if value == "all" {
+		return append(Steps{}, StepValues...), nil
+	}
+
+	requested := map[Step]bool{}
+	for _, part := range strings.Split(value, ",") {
+		step := Step(strings.TrimSpace(part))
+		if !isValidStep(step) {
+			return nil, fmt.Errorf("invalid preprocessing step %q", part)
+		}
+		requested[step] = true
+	}
+
+	var steps Steps
+	for _, step := range StepValues {
+		if requested[step] {
+			steps = append(steps, step)
+		}
+	}
+	return steps, nil
+}
+
+func isValidStep(step Step) bool {
+	for _, valid := range StepValues {
+		if valid == step {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns the canonical form of the steps, it is part of the cache key
+func (s Steps) String() string {
+	parts := make([]string, len(s))
+	for i, step := range s {
+		parts[i] = string(step)
+	}
+	return strings.Join(parts, ",")
+}
+
+func (s Steps) has(step Step) bool {
+	for _, selected := range s {
+		if selected == step {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply runs the steps over a PNG or JPEG page and returns the cleaned page as
+// a PNG, with the Transform that maps its points back to the page as it was
+// uploaded so bounding boxes can be reported on the original page.
+func Apply(imageBytes []byte, steps Steps) ([]byte, Transform, error) {
+	if len(steps) == 0 {
+		return imageBytes, Transform{}, nil
+	}
+
+	img, err := imaging.Decode(bytes.NewReader(imageBytes), imaging.AutoOrientation(steps.has(Orientation)))
+	if err != nil {
+		return nil, Transform{}, fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	transform := Transform{scale: 1}
+	if steps.has(Orientation) {
+		if config, _, err := image.DecodeConfig(bytes.NewReader(imageBytes)); err == nil {
+			transform.orientation = exifOrientation(imageBytes)
+			transform.width, transform.height = config.Width, config.Height
+		}
+	}
+
+	if steps.has(Upscale) {
+		img, transform.scale = upscale(img)
+	}
+	if steps.has(Grayscale) {
+		img = imaging.Grayscale(img)
+	}
+	if steps.has(Contrast) {
+		img = normalizeContrast(img)
+	}
+	if steps.has(Denoise) {
+		img = medianFilter(img)
+	}
+	if steps.has(Deskew) {
+		before := img.Bounds()
+		img, transform.angle = deskew(img)
+		transform.beforeWidth, transform.beforeHeight = before.Dx(), before.Dy()
+		transform.rotatedWidth, transform.rotatedHeight = img.Bounds().Dx(), img.Bounds().Dy()
+	}
+	if steps.has(Threshold) {
+		img = adaptiveThreshold(img)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, Transform{}, fmt.Errorf("failed to encode image as PNG: %w", err)
+	}
+	return buf.Bytes(), transform, nil
+}
+
+// upscale enlarges the image so its longer side is at least
+// PREPROCESS_UPSCALE_MIN_SIZE pixels, by at most PREPROCESS_UPSCALE_MAX_FACTOR
+func upscale(img image.Image) (image.Image, float64) {
+	bounds := img.Bounds()
+	longest := max(bounds.Dx(), bounds.Dy())
+	if longest == 0 || longest >= utils.PREPROCESS_UPSCALE_MIN_SIZE {
+		return img, 1
+	}
+
+	scale := min(float64(utils.PREPROCESS_UPSCALE_MIN_SIZE)/float64(longest), utils.PREPROCESS_UPSCALE_MAX_FACTOR)
+	width := int(float64(bounds.Dx()) * scale)
+	height := int(float64(bounds.Dy()) * scale)
+	return imaging.Resize(img, width, height, imaging.Lanczos), scale
+}
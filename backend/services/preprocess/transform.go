@@ -0,0 +1,123 @@
+package preprocess
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+)
+
+// Transform maps points of the preprocessed page back to the page as it was
+// uploaded, undoing the deskew rotation, the upscale and the EXIF orientation
+// in that order. The zero value maps every point to itself.
+type Transform struct {
+	// the EXIF orientation that was applied, 0 or 1 when the page was not rotated
+	orientation int
+	// size of the page as it was uploaded
+	width, height int
+
+	scale float64
+
+	// the deskew rotation in degrees, and the size of the page before and after it
+	angle                       float64
+	beforeWidth, beforeHeight   int
+	rotatedWidth, rotatedHeight int
+}
+
+// Point maps a point of the preprocessed page to the page as it was uploaded
+func (t Transform) Point(x, y float64) (float64, float64) {
+	if t.angle != 0 {
+		// the inverse of imaging.Rotate, which rotates around the center of the
+		// page and grows the canvas to fit the rotated page
+		sin, cos := math.Sincos(math.Pi * t.angle / 180)
+		dx := x - (float64(t.rotatedWidth)/2 - 0.5)
+		dy := y - (float64(t.rotatedHeight)/2 - 0.5)
+		x = dx*cos - dy*sin + float64(t.beforeWidth)/2 - 0.5
+		y = dx*sin + dy*cos + float64(t.beforeHeight)/2 - 0.5
+	}
+
+	if t.scale != 0 && t.scale != 1 {
+		x, y = x/t.scale, y/t.scale
+	}
+
+	// the inverse of the transforms imaging.AutoOrientation applies for each orientation
+	w, h := float64(t.width), float64(t.height)
+	switch t.orientation {
+	case 2:
+		x = w - x
+	case 3:
+		x, y = w-x, h-y
+	case 4:
+		y = h - y
+	case 5:
+		x, y = y, x
+	case 6:
+		x, y = y, h-x
+	case 7:
+		x, y = w-y, h-x
+	case 8:
+		x, y = w-y, x
+	}
+
+	return x, y
+}
+
+// exifOrientation reads the orientation tag of a JPEG's EXIF metadata, it is 0
+// when the image is not a JPEG or has no orientation
+func exifOrientation(imageBytes []byte) int {
+	if len(imageBytes) < 4 || imageBytes[0] != 0xFF || imageBytes[1] != 0xD8 {
+		return 0
+	}
+
+	// walk the segments up to the start of the image data looking for APP1
+	data := imageBytes[2:]
+	for len(data) >= 4 && data[0] == 0xFF {
+		marker := data[1]
+		length := int(binary.BigEndian.Uint16(data[2:4]))
+		if marker == 0xDA || length < 2 || len(data) < 2+length {
+			return 0
+		}
+		segment := data[4 : 2+length]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return tiffOrientation(segment[6:])
+		}
+		data = data[2+length:]
+	}
+	return 0
+}
+
+// tiffOrientation reads the orientation tag of the first IFD of EXIF's TIFF structure
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 0
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0
+	}
+
+	offset := int(order.Uint32(tiff[4:8]))
+	if offset < 0 || offset+2 > len(tiff) {
+		return 0
+	}
+	entries := int(order.Uint16(tiff[offset:]))
+	for i := 0; i < entries; i++ {
+		entry := offset + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 0
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			orientation := int(order.Uint16(tiff[entry+8:]))
+			if orientation < 1 || orientation > 8 {
+				return 0
+			}
+			return orientation
+		}
+	}
+	return 0
+}
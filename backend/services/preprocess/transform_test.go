@@ -0,0 +1,182 @@
+package preprocess
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"math"
+	"testing"
+
+	"github.com/disintegration/imaging"
+)
+
+// exifJPEG encodes the image as a JPEG with an EXIF orientation tag in the byte order
+func exifJPEG(t *testing.T, img image.Image, orientation int, order binary.ByteOrder) []byte {
+	t.Helper()
+
+	var encoded bytes.Buffer
+	if err := jpeg.Encode(&encoded, img, &jpeg.Options{Quality: 100}); err != nil {
+		t.Fatal(err)
+	}
+
+	// a TIFF header and a single IFD holding the orientation tag
+	tiff := make([]byte, 26)
+	copy(tiff, "II")
+	if order == binary.BigEndian {
+		copy(tiff, "MM")
+	}
+	order.PutUint16(tiff[2:], 42)
+	order.PutUint32(tiff[4:], 8)
+	order.PutUint16(tiff[8:], 1)
+	order.PutUint16(tiff[10:], 0x0112)
+	order.PutUint16(tiff[12:], 3)
+	order.PutUint32(tiff[14:], 1)
+	order.PutUint16(tiff[18:], uint16(orientation))
+
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+	app1 := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(app1[2:], uint16(2+len(segment)))
+
+	data := append([]byte{0xFF, 0xD8}, app1...)
+	data = append(data, segment...)
+	return append(data, encoded.Bytes()[2:]...)
+}
+
+// markedImage is a white image with a black block covering rect
+func markedImage(width, height int, rect image.Rectangle) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(img, rect, image.NewUniform(color.Black), image.Point{}, draw.Src)
+	return img
+}
+
+// darkCenter returns the center of the dark pixels of the image, in pixel indexes
+func darkCenter(t *testing.T, img image.Image) (float64, float64) {
+	t.Helper()
+
+	var sumX, sumY, count float64
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if r, _, _, _ := img.At(x, y).RGBA(); r < 0x8000 {
+				sumX, sumY, count = sumX+float64(x-bounds.Min.X), sumY+float64(y-bounds.Min.Y), count+1
+			}
+		}
+	}
+	if count == 0 {
+		t.Fatal("image has no dark pixels")
+	}
+	return sumX / count, sumY / count
+}
+
+func TestTransformOrientation(t *testing.T) {
+	// the block's center is at (10, 6) measured from the edges of the page
+	width, height := 40, 24
+	block := image.Rect(6, 2, 14, 10)
+
+	for orientation := 1; orientation <= 8; orientation++ {
+		for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+			jpegBytes := exifJPEG(t, markedImage(width, height, block), orientation, order)
+			if got := exifOrientation(jpegBytes); got != orientation {
+				t.Fatalf("exifOrientation() = %d, want %d", got, orientation)
+			}
+
+			pngBytes, transform, err := Apply(jpegBytes, Steps{Orientation})
+			if err != nil {
+				t.Fatalf("Apply() error = %v", err)
+			}
+			img, err := imaging.Decode(bytes.NewReader(pngBytes))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			cx, cy := darkCenter(t, img)
+			x, y := transform.Point(cx+0.5, cy+0.5)
+			if math.Abs(x-10) > 0.5 || math.Abs(y-6) > 0.5 {
+				t.Errorf("orientation %d: Point(%.1f, %.1f) = (%.1f, %.1f), want (10, 6)", orientation, cx+0.5, cy+0.5, x, y)
+			}
+		}
+	}
+}
+
+func TestTransformDeskew(t *testing.T) {
+	width, height := 60, 40
+	img := markedImage(width, height, image.Rect(40, 8, 44, 12))
+	wantX, wantY := darkCenter(t, img)
+
+	for _, angle := range []float64{-12, -3.5, 0.7, 9} {
+		rotated := imaging.Rotate(img, angle, color.White)
+		transform := Transform{
+			scale:         1,
+			angle:         angle,
+			beforeWidth:   width,
+			beforeHeight:  height,
+			rotatedWidth:  rotated.Bounds().Dx(),
+			rotatedHeight: rotated.Bounds().Dy(),
+		}
+
+		cx, cy := darkCenter(t, rotated)
+		x, y := transform.Point(cx, cy)
+		if math.Abs(x-wantX) > 1 || math.Abs(y-wantY) > 1 {
+			t.Errorf("angle %v: Point(%.1f, %.1f) = (%.1f, %.1f), want (%.1f, %.1f)", angle, cx, cy, x, y, wantX, wantY)
+		}
+	}
+}
+
+func TestTransformPoint(t *testing.T) {
+	tests := []struct {
+		name      string
+		transform Transform
+		x, y      float64
+		wantX     float64
+		wantY     float64
+	}{
+		{"zero value", Transform{}, 12, 34, 12, 34},
+		{"upscale", Transform{scale: 2.5}, 25, 50, 10, 20},
+		// a 100x50 page that was turned clockwise by orientation 6 and upscaled
+		{"upscale after orientation", Transform{orientation: 6, width: 100, height: 50, scale: 2}, 20, 60, 30, 40},
+	}
+
+	for _, tt := range tests {
+		x, y := tt.transform.Point(tt.x, tt.y)
+		if math.Abs(x-tt.wantX) > 1e-9 || math.Abs(y-tt.wantY) > 1e-9 {
+			t.Errorf("%s: Point(%v, %v) = (%v, %v), want (%v, %v)", tt.name, tt.x, tt.y, x, y, tt.wantX, tt.wantY)
+		}
+	}
+}
+
+func TestExifOrientationInvalid(t *testing.T) {
+	img := markedImage(8, 8, image.Rect(0, 0, 1, 1))
+	var plain bytes.Buffer
+	if err := jpeg.Encode(&plain, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	tagged := exifJPEG(t, img, 6, binary.LittleEndian)
+
+	tests := map[string][]byte{
+		"not a JPEG":        []byte("\x89PNG\r\n\x1a\n"),
+		"empty":             nil,
+		"no EXIF":           plain.Bytes(),
+		"orientation 0":     exifJPEG(t, img, 0, binary.LittleEndian),
+		"orientation 9":     exifJPEG(t, img, 9, binary.BigEndian),
+		"truncated segment": tagged[:20],
+	}
+	for name, data := range tests {
+		if got := exifOrientation(data); got != 0 {
+			t.Errorf("%s: exifOrientation() = %d, want 0", name, got)
+		}
+	}
+
+	for name, tiff := range map[string][]byte{
+		"short":              []byte("II*\x00"),
+		"unknown byte order": []byte("XX\x00*\x00\x00\x00\x08"),
+		"offset past end":    []byte("MM\x00*\x00\x00\x01\x00"),
+	} {
+		if got := tiffOrientation(tiff); got != 0 {
+			t.Errorf("%s: tiffOrientation() = %d, want 0", name, got)
+		}
+	}
+}
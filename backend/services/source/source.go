@@ -0,0 +1,140 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"serverless-tesseract/r2"
+	"serverless-tesseract/utils"
+	"slices"
+	"strings"
+	"time"
+)
+
+// client downloads source URLs. It does not use the environment's proxy so
+// every connection goes through the dialer, which refuses addresses that are
+// not public, and every redirect is checked against the allowed hosts.
+var client = &http.Client{
+	Timeout: utils.SOURCE_URL_TIMEOUT,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: time.Second * 10,
+			Control: utils.ControlPublicAddress,
+		}).DialContext,
+		TLSHandshakeTimeout:   time.Second * 10,
+		ResponseHeaderTimeout: utils.SOURCE_URL_TIMEOUT,
+	},
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if len(via) >= utils.SOURCE_URL_MAX_REDIRECTS {
+			return fmt.Errorf("stopped after %d redirects", utils.SOURCE_URL_MAX_REDIRECTS)
+		}
+		return validateURL(req.URL)
+	},
+}
+
+// FetchURL downloads the document at the source URL and returns it with a
+// filename taken from the URL's path. The URL must be https on one of the
+// SOURCE_URL_ALLOWED_HOSTS and resolve to a public address, and the download
+// stops with utils.ErrFileTooLarge once it is over FILE_SIZE_LIMIT bytes.
+func FetchURL(ctx context.Context, sourceURL string) ([]byte, string, error) {
+	u, err := url.Parse(sourceURL)
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", utils.ErrSourceNotAllowed, err)
+	}
+	if err := validateURL(u); err != nil {
+		return nil, "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, "", err
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return nil, "", fmt.Errorf("source responded with status %d", res.StatusCode)
+	}
+	if res.ContentLength > int64(utils.FILE_SIZE_LIMIT) {
+		return nil, "", utils.ErrFileTooLarge
+	}
+
+	// the content length is optional and not trusted, stop reading once the limit is passed
+	body, err := io.ReadAll(io.LimitReader(res.Body, int64(utils.FILE_SIZE_LIMIT)+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if len(body) > utils.FILE_SIZE_LIMIT {
+		return nil, "", utils.ErrFileTooLarge
+	}
+
+	return body, filename(res.Request.URL.Path), nil
+}
+
+// FetchObject reads the document stored under the key of a bucket listed in
+// SOURCE_ALLOWED_BUCKETS, using the service's R2 credentials
+func FetchObject(ctx context.Context, bucket string, key string) ([]byte, string, error) {
+	// the service's own bucket holds every organization's files
+	if bucket == utils.R2_BUCKET_NAME || !slices.Contains(allowList(utils.SOURCE_ALLOWED_BUCKETS), bucket) {
+		return nil, "", fmt.Errorf("%w: bucket %s", utils.ErrSourceNotAllowed, bucket)
+	}
+
+	body, err := r2.GetBucketFile(ctx, bucket, key, utils.FILE_SIZE_LIMIT)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return body, filename(key), nil
+}
+
+// validateURL checks that the URL is https, has no credentials and is on an
+// allowed host
+func validateURL(u *url.URL) error {
+	if u.Scheme != "https" {
+		return fmt.Errorf("%w: the URL must use https", utils.ErrSourceNotAllowed)
+	}
+	if u.User != nil {
+		return fmt.Errorf("%w: the URL cannot contain credentials", utils.ErrSourceNotAllowed)
+	}
+
+	host := strings.ToLower(u.Hostname())
+	for _, allowed := range allowList(utils.SOURCE_URL_ALLOWED_HOSTS) {
+		allowed = strings.ToLower(allowed)
+		if host == allowed {
+			return nil
+		}
+		if suffix, ok := strings.CutPrefix(allowed, "*"); ok && strings.HasPrefix(suffix, ".") && strings.HasSuffix(host, suffix) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: host %s", utils.ErrSourceNotAllowed, host)
+}
+
+// allowList splits a comma separated allowlist, ignoring empty entries
+func allowList(value string) []string {
+	var list []string
+	for _, entry := range strings.Split(value, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			list = append(list, entry)
+		}
+	}
+	return list
+}
+
+// filename is the last element of a URL path or object key
+func filename(name string) string {
+	base := path.Base(name)
+	if base == "." || base == "/" {
+		return "document"
+	}
+	return base
+}
@@ -0,0 +1,517 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"image"
+	"image/draw"
+	"serverless-tesseract/utils"
+	"sort"
+	"strings"
+)
+
+const (
+	// pixels darker than this are ink when looking for ruling lines
+	rulingInkThreshold = 128
+	// ruling lines may be broken by gaps of up to this many pixels from scanning
+	rulingMaxGap = 3
+	// a gap between words wider than this many word heights separates columns
+	tableColumnGap = 1.5
+	// tables without rulings need at least this many rows
+	tableMinRows = 3
+	// runs of lines averaging more words per cell are columns of prose, not tables
+	tableMaxWordsPerCell = 4.0
+)
+
+// ruling is a horizontal or vertical line on the page. position is the y of
+// horizontal and the x of vertical lines, start and end span the other axis.
+type ruling struct {
+	position, start, end int
+}
+
+type pageRulings struct {
+	horizontal, vertical []ruling
+}
+
+// tableSegment is a run of words on a line that is separated from the rest of
+// the line by a column gap
+type tableSegment struct {
+	words       []utils.OCRResponse
+	left, right int
+}
+
+// detectTables finds the tables on a page. Ruled tables are found where two or
+// more horizontal and two or more vertical ruling lines cross, and their rows and
+// columns follow the lines. Tables without rulings are found from the remaining
+// words: runs of at least tableMinRows lines that are split into two or more
+// aligned columns by wide gaps.
+func detectTables(words []utils.OCRResponse, rulings pageRulings, pageNumber int) []utils.Table {
+	var tables []utils.Table
+	remaining := words
+
+	for _, region := range ruledRegions(rulings) {
+		var inside []utils.OCRResponse
+		var outside []utils.OCRResponse
+		for _, word := range remaining {
+			x, y := bboxCenter(word.BBox)
+			if x >= region.left && x <= region.right && y >= region.top && y <= region.bottom {
+				inside = append(inside, word)
+			} else {
+				outside = append(outside, word)
+			}
+		}
+		remaining = outside
+		tables = append(tables, ruledTable(region, inside, pageNumber))
+	}
+
+	tables = append(tables, alignedTables(remaining, pageNumber)...)
+
+	sort.SliceStable(tables, func(i, j int) bool {
+		return tables[i].BBox.TopLeft.Y < tables[j].BBox.TopLeft.Y
+	})
+	return tables
+}
+
+// detectRulings finds the horizontal and vertical lines of a page image. Lines
+// must span a twentieth of the page and be thin, which leaves out text and
+// filled areas such as logos and shaded bars.
+func detectRulings(imageBytes []byte) (pageRulings, error) {
+	decoded, _, err := image.Decode(bytes.NewReader(imageBytes))
+	if err != nil {
+		return pageRulings{}, err
+	}
+
+	bounds := decoded.Bounds()
+	gray := image.NewGray(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(gray, gray.Bounds(), decoded, bounds.Min, draw.Src)
+
+	width, height := gray.Rect.Dx(), gray.Rect.Dy()
+	maxThickness := max(width, height)/150 + 3
+
+	horizontal := scanRulings(width, height, func(along, across int) bool {
+		return gray.Pix[across*gray.Stride+along] < rulingInkThreshold
+	}, max(width/20, 40), maxThickness)
+	vertical := scanRulings(height, width, func(along, across int) bool {
+		return gray.Pix[along*gray.Stride+across] < rulingInkThreshold
+	}, max(height/20, 40), maxThickness)
+
+	return pageRulings{horizontal: horizontal, vertical: vertical}, nil
+}
+
+// scanRulings finds lines of ink at least minLength long running along an axis
+// of the given length, scanning every position across the other axis. Runs on
+// neighbouring positions that overlap are one line, which is dropped when it is
+// thicker than maxThickness.
+func scanRulings(length, breadth int, ink func(along, across int) bool, minLength, maxThickness int) []ruling {
+	type line struct {
+		first, last, start, end int
+	}
+	var open, lines []line
+
+	for across := 0; across < breadth; across++ {
+		var runs [][2]int
+		for along := 0; along < length; along++ {
+			if !ink(along, across) {
+				continue
+			}
+			start, end := along, along
+			for next := along + 1; next < length && next-end <= rulingMaxGap+1; next++ {
+				if ink(next, across) {
+					end = next
+				}
+			}
+			if end-start+1 >= minLength {
+				runs = append(runs, [2]int{start, end})
+			}
+			along = end
+		}
+
+		var stillOpen []line
+		for _, run := range runs {
+			extended := false
+			for i := range open {
+				if open[i].last == across-1 && run[0] <= open[i].end && run[1] >= open[i].start {
+					open[i].last = across
+					open[i].start = min(open[i].start, run[0])
+					open[i].end = max(open[i].end, run[1])
+					extended = true
+					break
+				}
+			}
+			if !extended {
+				stillOpen = append(stillOpen, line{first: across, last: across, start: run[0], end: run[1]})
+			}
+		}
+		for _, current := range open {
+			if current.last == across {
+				stillOpen = append(stillOpen, current)
+			} else {
+				lines = append(lines, current)
+			}
+		}
+		open = stillOpen
+	}
+	lines = append(lines, open...)
+
+	var rulings []ruling
+	for _, current := range lines {
+		if current.last-current.first+1 > maxThickness {
+			continue
+		}
+		rulings = append(rulings, ruling{
+			position: (current.first + current.last) / 2,
+			start:    current.start,
+			end:      current.end,
+		})
+	}
+	return rulings
+}
+
+// tableRegion is a ruled table and the positions of its row and column lines
+type tableRegion struct {
+	left, top, right, bottom int
+	rows, columns            []int
+}
+
+// ruledRegions groups crossing ruling lines together and returns the groups of
+// at least two horizontal and two vertical lines
+func ruledRegions(rulings pageRulings) []tableRegion {
+	horizontal, vertical := rulings.horizontal, rulings.vertical
+	if len(horizontal) < 2 || len(vertical) < 2 {
+		return nil
+	}
+
+	// union find over the horizontal lines followed by the vertical lines
+	parent := make([]int, len(horizontal)+len(vertical))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	const tolerance = 5
+	for i, h := range horizontal {
+		for j, v := range vertical {
+			if v.position >= h.start-tolerance && v.position <= h.end+tolerance &&
+				h.position >= v.start-tolerance && h.position <= v.end+tolerance {
+				parent[find(i)] = find(len(horizontal) + j)
+			}
+		}
+	}
+
+	groups := map[int]*pageRulings{}
+	var roots []int
+	for i := range parent {
+		root := find(i)
+		group, ok := groups[root]
+		if !ok {
+			group = &pageRulings{}
+			groups[root] = group
+			roots = append(roots, root)
+		}
+		if i < len(horizontal) {
+			group.horizontal = append(group.horizontal, horizontal[i])
+		} else {
+			group.vertical = append(group.vertical, vertical[i-len(horizontal)])
+		}
+	}
+
+	var regions []tableRegion
+	for _, root := range roots {
+		group := groups[root]
+		if len(group.horizontal) < 2 || len(group.vertical) < 2 {
+			continue
+		}
+
+		region := tableRegion{
+			left:   group.vertical[0].position,
+			top:    group.horizontal[0].position,
+			right:  group.vertical[0].position,
+			bottom: group.horizontal[0].position,
+		}
+		var rows, columns []int
+		for _, h := range group.horizontal {
+			region.left = min(region.left, h.start)
+			region.right = max(region.right, h.end)
+			rows = append(rows, h.position)
+		}
+		for _, v := range group.vertical {
+			region.top = min(region.top, v.start)
+			region.bottom = max(region.bottom, v.end)
+			columns = append(columns, v.position)
+		}
+
+		// tables without an outer border are closed by the ends of their lines
+		region.rows = edges(append(rows, region.top, region.bottom), tolerance*2)
+		region.columns = edges(append(columns, region.left, region.right), tolerance*2)
+		if len(region.rows) < 2 || len(region.columns) < 2 {
+			continue
+		}
+		regions = append(regions, region)
+	}
+	return regions
+}
+
+// edges sorts the positions and merges positions within tolerance of each
+// other, such as the two lines of a double rule
+func edges(positions []int, tolerance int) []int {
+	sort.Ints(positions)
+	var merged []int
+	for _, position := range positions {
+		if len(merged) > 0 && position-merged[len(merged)-1] <= tolerance {
+			continue
+		}
+		merged = append(merged, position)
+	}
+	return merged
+}
+
+// ruledTable places the words into the cells of the region's grid by their centers
+func ruledTable(region tableRegion, words []utils.OCRResponse, pageNumber int) utils.Table {
+	cellWords := make([][][]utils.OCRResponse, len(region.rows)-1)
+	for i := range cellWords {
+		cellWords[i] = make([][]utils.OCRResponse, len(region.columns)-1)
+	}
+	for _, word := range words {
+		x, y := bboxCenter(word.BBox)
+		row, column := edgeIndex(region.rows, y), edgeIndex(region.columns, x)
+		cellWords[row][column] = append(cellWords[row][column], word)
+	}
+
+	rows := make([][]utils.TableCell, len(cellWords))
+	for i := range cellWords {
+		rows[i] = make([]utils.TableCell, len(cellWords[i]))
+		for j := range cellWords[i] {
+			rows[i][j] = utils.TableCell{
+				Text: cellText(cellWords[i][j]),
+				BBox: newBBox(region.columns[j], region.rows[i], region.columns[j+1], region.rows[i+1]),
+			}
+		}
+	}
+
+	return newTable(pageNumber, newBBox(region.left, region.top, region.right, region.bottom), true, rows)
+}
+
+// edgeIndex returns the index of the span between consecutive edges the
+// position falls in, positions outside the edges fall in the first or last span
+func edgeIndex(edges []int, position int) int {
+	index := sort.SearchInts(edges, position+1) - 1
+	return min(max(index, 0), len(edges)-2)
+}
+
+// alignedTables finds runs of lines that are split into aligned columns by gaps
+// wider than tableColumnGap word heights. A line with a single run of words that
+// falls within one column continues the cell above it, such as a description
+// that wraps.
+func alignedTables(words []utils.OCRResponse, pageNumber int) []utils.Table {
+	if len(words) == 0 {
+		return nil
+	}
+
+	heights := make([]int, len(words))
+	for i, word := range words {
+		heights[i] = word.BBox.BottomRight.Y - word.BBox.TopLeft.Y
+	}
+	sort.Ints(heights)
+	wordHeight := max(heights[len(heights)/2], 1)
+	columnGap := int(float64(wordHeight) * tableColumnGap)
+
+	var tables []utils.Table
+	var rows [][]tableSegment
+	var previousBottom int
+
+	flush := func() {
+		if table, ok := alignedTable(rows, pageNumber); ok {
+			tables = append(tables, table)
+		}
+		rows = nil
+	}
+
+	for _, paragraph := range groupByPosition(words) {
+		for _, line := range paragraph {
+			segments := splitLine(line, columnGap)
+			top, bottom := lineExtent(line)
+
+			if len(rows) > 0 && top-previousBottom > 2*wordHeight {
+				flush()
+			}
+
+			switch {
+			case len(segments) >= 2:
+				rows = append(rows, segments)
+			case len(rows) > 0 && withinOneColumn(rows, segments[0]):
+				rows[len(rows)-1] = append(rows[len(rows)-1], segments[0])
+			default:
+				flush()
+			}
+			previousBottom = bottom
+		}
+	}
+	flush()
+
+	return tables
+}
+
+// alignedTable builds a table from rows of segments, the columns are the
+// segments' horizontal extents merged where they overlap
+func alignedTable(rows [][]tableSegment, pageNumber int) (utils.Table, bool) {
+	if len(rows) < tableMinRows {
+		return utils.Table{}, false
+	}
+
+	columns := segmentColumns(rows)
+	if len(columns) < 2 {
+		return utils.Table{}, false
+	}
+
+	var tableWords []utils.OCRResponse
+	cells := 0
+	tableRows := make([][]utils.TableCell, len(rows))
+	for i, row := range rows {
+		cellWords := make([][]utils.OCRResponse, len(columns))
+		var rowWords []utils.OCRResponse
+		for _, segment := range row {
+			center := (segment.left + segment.right) / 2
+			for j, column := range columns {
+				if center >= column[0] && center <= column[1] {
+					cellWords[j] = append(cellWords[j], segment.words...)
+					break
+				}
+			}
+			rowWords = append(rowWords, segment.words...)
+		}
+		tableWords = append(tableWords, rowWords...)
+
+		top, bottom := lineExtent(rowWords)
+		tableRows[i] = make([]utils.TableCell, len(columns))
+		for j, column := range columns {
+			if len(cellWords[j]) > 0 {
+				cells++
+			}
+			tableRows[i][j] = utils.TableCell{
+				Text: cellText(cellWords[j]),
+				BBox: newBBox(column[0], top, column[1], bottom),
+			}
+		}
+	}
+
+	if float64(len(tableWords))/float64(cells) > tableMaxWordsPerCell {
+		return utils.Table{}, false
+	}
+
+	return newTable(pageNumber, layoutElement(tableWords, "").BBox, false, tableRows), true
+}
+
+// splitLine splits the words of a line, sorted left to right, where the gap
+// between two words is wider than columnGap
+func splitLine(line []utils.OCRResponse, columnGap int) []tableSegment {
+	var segments []tableSegment
+	for _, word := range line {
+		if len(segments) > 0 && word.BBox.TopLeft.X-segments[len(segments)-1].right <= columnGap {
+			current := &segments[len(segments)-1]
+			current.words = append(current.words, word)
+			current.right = max(current.right, word.BBox.BottomRight.X)
+			continue
+		}
+		segments = append(segments, tableSegment{
+			words: []utils.OCRResponse{word},
+			left:  word.BBox.TopLeft.X,
+			right: word.BBox.BottomRight.X,
+		})
+	}
+	return segments
+}
+
+// segmentColumns merges the horizontal extents of the segments where they overlap
+func segmentColumns(rows [][]tableSegment) [][2]int {
+	var extents [][2]int
+	for _, row := range rows {
+		for _, segment := range row {
+			extents = append(extents, [2]int{segment.left, segment.right})
+		}
+	}
+	sort.Slice(extents, func(i, j int) bool { return extents[i][0] < extents[j][0] })
+
+	var columns [][2]int
+	for _, extent := range extents {
+		if len(columns) > 0 && extent[0] <= columns[len(columns)-1][1] {
+			columns[len(columns)-1][1] = max(columns[len(columns)-1][1], extent[1])
+			continue
+		}
+		columns = append(columns, extent)
+	}
+	return columns
+}
+
+// withinOneColumn reports whether the segment overlaps exactly one of the
+// columns of the rows so far
+func withinOneColumn(rows [][]tableSegment, segment tableSegment) bool {
+	columns := segmentColumns(rows)
+	if len(columns) < 2 {
+		return false
+	}
+
+	overlapping := 0
+	for _, column := range columns {
+		if segment.left <= column[1] && segment.right >= column[0] {
+			overlapping++
+		}
+	}
+	return overlapping == 1
+}
+
+// lineExtent returns the top of the highest and the bottom of the lowest word
+func lineExtent(words []utils.OCRResponse) (int, int) {
+	element := layoutElement(words, "")
+	return element.BBox.TopLeft.Y, element.BBox.BottomRight.Y
+}
+
+// cellText joins the words of a cell line by line
+func cellText(words []utils.OCRResponse) string {
+	if len(words) == 0 {
+		return ""
+	}
+
+	var texts []string
+	for _, paragraph := range groupByPosition(words) {
+		for _, line := range paragraph {
+			for _, word := range line {
+				texts = append(texts, word.Text)
+			}
+		}
+	}
+	return strings.Join(texts, " ")
+}
+
+// newTable returns a table of the rows with their text as CSV
+func newTable(pageNumber int, bbox utils.BBox, ruled bool, rows [][]utils.TableCell) utils.Table {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	for _, row := range rows {
+		record := make([]string, len(row))
+		for i, cell := range row {
+			record[i] = cell.Text
+		}
+		// writing to a buffer does not fail
+		_ = writer.Write(record)
+	}
+	writer.Flush()
+
+	return utils.Table{
+		PageNumber: pageNumber,
+		BBox:       bbox,
+		Ruled:      ruled,
+		Rows:       rows,
+		CSV:        buf.String(),
+	}
+}
+
+// bboxCenter returns the center of the box
+func bboxCenter(box utils.BBox) (int, int) {
+	return (box.TopLeft.X + box.BottomRight.X) / 2, (box.TopLeft.Y + box.BottomRight.Y) / 2
+}
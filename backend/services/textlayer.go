@@ -0,0 +1,148 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"serverless-tesseract/utils"
+	"strings"
+	"unicode"
+
+	"github.com/unidoc/unipdf/v3/extractor"
+	"github.com/unidoc/unipdf/v3/model"
+)
+
+// extractTextLayer reads the words of the page's embedded text layer as OCR
+// responses. Bounding boxes are converted to pixels of the page rendered at
+// PDF_RENDER_DPI with the origin in the top left, turned by the page's /Rotate
+// and cropped to its CropBox like renderPage does, the same coordinates the OCR
+// engines return. Text outside the CropBox is not visible and is skipped. The number of non-whitespace characters is returned so the
+// caller can decide whether the text layer is usable.
+func extractTextLayer(page *model.PdfPage, pageNumber int, raw bool) (utils.OCRResponseList, int, error) {
+	results := utils.OCRResponseList{OCRResponses: []utils.OCRResponse{}}
+
+	geometry, err := NewPageGeometry(page)
+	if err != nil {
+		return results, 0, fmt.Errorf("failed to get page size for page %d: %w", pageNumber, err)
+	}
+
+	textExtractor, err := extractor.NewWithOptions(page, &extractor.Options{ApplyCropBox: true})
+	if err != nil {
+		return results, 0, fmt.Errorf("failed to create text extractor for page %d: %w", pageNumber, err)
+	}
+
+	pageText, _, _, err := textExtractor.ExtractPageText()
+	if err != nil {
+		return results, 0, fmt.Errorf("failed to extract text from page %d: %w", pageNumber, err)
+	}
+
+	scale := utils.PDF_RENDER_DPI / 72.0
+	toPixels := func(bbox model.PdfRectangle) utils.BBox {
+		left, top, right, bottom := geometry.ToDisplayRectangle(bbox)
+		return newBBox(
+			int(math.Round(left*scale)), int(math.Round(top*scale)),
+			int(math.Round(right*scale)), int(math.Round(bottom*scale)),
+		)
+	}
+
+	// marks are single characters, words are the runs of marks between whitespace
+	var word strings.Builder
+	var wordBox model.PdfRectangle
+	characters := 0
+
+	flush := func() {
+		if word.Len() == 0 {
+			return
+		}
+		results.OCRResponses = append(results.OCRResponses, utils.OCRResponse{
+			Text:       word.String(),
+			Confidence: 1,
+			BBox:       toPixels(wordBox),
+			PageNumber: pageNumber,
+			Source:     utils.SourceTextLayer,
+		})
+		word.Reset()
+	}
+
+	for _, mark := range pageText.Marks().Elements() {
+		text := strings.TrimSpace(mark.Text)
+		if mark.Meta || text == "" {
+			flush()
+			continue
+		}
+
+		if word.Len() == 0 {
+			wordBox = mark.BBox
+		} else {
+			wordBox = unionRectangles(wordBox, mark.BBox)
+		}
+		word.WriteString(text)
+
+		for _, r := range text {
+			if !unicode.IsSpace(r) {
+				characters++
+			}
+		}
+	}
+	flush()
+
+	if raw {
+		results.OCRResponses = combineResponses(results.OCRResponses, pageNumber)
+	}
+	results.NumberOfTokens = int64(len(results.OCRResponses))
+	width, height := geometry.Size()
+	results.PageSizes = []utils.PageSize{{
+		PageNumber: pageNumber,
+		Width:      int(math.Round(width * scale)),
+		Height:     int(math.Round(height * scale)),
+	}}
+
+	return results, characters, nil
+}
+
+// combineResponses joins the words of a page into a single response, the same
+// way the OCR scripts do when raw is set
+func combineResponses(responses []utils.OCRResponse, pageNumber int) []utils.OCRResponse {
+	if len(responses) == 0 {
+		return responses
+	}
+
+	left, top := responses[0].BBox.TopLeft.X, responses[0].BBox.TopLeft.Y
+	right, bottom := responses[0].BBox.BottomRight.X, responses[0].BBox.BottomRight.Y
+	words := make([]string, len(responses))
+	confidence := 0.0
+
+	for i, response := range responses {
+		left = min(left, response.BBox.TopLeft.X)
+		top = min(top, response.BBox.TopLeft.Y)
+		right = max(right, response.BBox.BottomRight.X)
+		bottom = max(bottom, response.BBox.BottomRight.Y)
+		words[i] = response.Text
+		confidence += response.Confidence
+	}
+
+	return []utils.OCRResponse{{
+		Text:       strings.Join(words, " "),
+		Confidence: confidence / float64(len(responses)),
+		BBox:       newBBox(left, top, right, bottom),
+		PageNumber: pageNumber,
+		Source:     responses[0].Source,
+	}}
+}
+
+func newBBox(left, top, right, bottom int) utils.BBox {
+	return utils.BBox{
+		TopLeft:     utils.XY{X: left, Y: top},
+		BottomLeft:  utils.XY{X: left, Y: bottom},
+		TopRight:    utils.XY{X: right, Y: top},
+		BottomRight: utils.XY{X: right, Y: bottom},
+	}
+}
+
+func unionRectangles(a, b model.PdfRectangle) model.PdfRectangle {
+	return model.PdfRectangle{
+		Llx: math.Min(a.Llx, b.Llx),
+		Lly: math.Min(a.Lly, b.Lly),
+		Urx: math.Max(a.Urx, b.Urx),
+		Ury: math.Max(a.Ury, b.Ury),
+	}
+}
@@ -0,0 +1,100 @@
+package sender
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"serverless-tesseract/models"
+	"serverless-tesseract/utils"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+const (
+	SIGNATURE_HEADER = "X-Webhook-Signature"
+	TIMESTAMP_HEADER = "X-Webhook-Timestamp"
+	EVENT_HEADER     = "X-Webhook-Event"
+	DELIVERY_HEADER  = "X-Webhook-Delivery"
+)
+
+// allowInternal lets deliveries reach addresses that are not public, in
+// development receivers usually run locally
+var allowInternal = os.Getenv("ENV") == "development"
+
+// client sends deliveries. It does not use the environment's proxy so every
+// connection goes through the dialer, which refuses addresses that are not
+// public unless allowInternal is set.
+var client = &http.Client{
+	Timeout: utils.WEBHOOK_TIMEOUT,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: time.Second * 10,
+			Control: func(network string, address string, c syscall.RawConn) error {
+				if allowInternal {
+					return nil
+				}
+				return utils.ControlPublicAddress(network, address, c)
+			},
+		}).DialContext,
+		TLSHandshakeTimeout:   time.Second * 10,
+		ResponseHeaderTimeout: utils.WEBHOOK_TIMEOUT,
+	},
+}
+
+// Sign returns the HMAC-SHA256 signature sent in the X-Webhook-Signature header.
+// The signed message is the timestamp header, a period, and the raw request body.
+func Sign(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Send POSTs the signed payload, returning the response status code if one was received
+func Send(delivery models.OrganizationWebhookDelivery) (int32, error) {
+	body := []byte(delivery.Payload)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequest(http.MethodPost, delivery.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "AtlasOCR-Webhooks/1.0")
+	req.Header.Set(EVENT_HEADER, delivery.Event)
+	req.Header.Set(DELIVERY_HEADER, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(TIMESTAMP_HEADER, timestamp)
+	req.Header.Set(SIGNATURE_HEADER, Sign(delivery.Secret, timestamp, body))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return int32(resp.StatusCode), fmt.Errorf("receiver responded with status %d", resp.StatusCode)
+	}
+
+	return int32(resp.StatusCode), nil
+}
+
+// Retry returns the status of a delivery whose attempt failed and how long to
+// wait before the next attempt. The wait doubles with every attempt starting at
+// WEBHOOK_RETRY_BACKOFF, and the delivery fails after WEBHOOK_MAX_ATTEMPTS.
+func Retry(attempts int32) (utils.WebhookDeliveryStatusType, time.Duration) {
+	backoff := utils.WEBHOOK_RETRY_BACKOFF * time.Duration(1<<(attempts-1))
+	if int(attempts) >= utils.WEBHOOK_MAX_ATTEMPTS {
+		return utils.WebhookDeliveryFailed, backoff
+	}
+	return utils.WebhookDeliveryPending, backoff
+}
@@ -0,0 +1,166 @@
+package sender
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"serverless-tesseract/models"
+	"serverless-tesseract/utils"
+	"strconv"
+	"testing"
+)
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"event":"ocr.completed"}`)
+
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte("1700000000." + string(body)))
+	want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	if got := Sign("secret", "1700000000", body); got != want {
+		t.Errorf("Sign() = %s, want %s", got, want)
+	}
+	if Sign("other", "1700000000", body) == want {
+		t.Error("Sign() with another secret has the same signature")
+	}
+	if Sign("secret", "1700000001", body) == want {
+		t.Error("Sign() with another timestamp has the same signature")
+	}
+}
+
+// receiver starts a server that answers with the given statuses in turn and
+// records the requests it was sent
+func receiver(t *testing.T, statuses ...int) (*httptest.Server, *[]*http.Request, *[][]byte) {
+	t.Helper()
+	allowed := allowInternal
+	allowInternal = true
+	t.Cleanup(func() { allowInternal = allowed })
+
+	var requests []*http.Request
+	var bodies [][]byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, r)
+		bodies = append(bodies, body)
+		w.WriteHeader(statuses[min(len(requests), len(statuses))-1])
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &requests, &bodies
+}
+
+func TestSend(t *testing.T) {
+	server, requests, bodies := receiver(t, http.StatusOK)
+	delivery := models.OrganizationWebhookDelivery{
+		ID:      42,
+		Event:   string(utils.WebhookEventOCRCompleted),
+		Payload: `{"event":"ocr.completed","request_id":7}`,
+		URL:     server.URL,
+		Secret:  "secret",
+	}
+
+	status, err := Send(delivery)
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if status != http.StatusOK {
+		t.Errorf("Send() status = %d, want %d", status, http.StatusOK)
+	}
+
+	if len(*requests) != 1 {
+		t.Fatalf("receiver got %d requests, want 1", len(*requests))
+	}
+	req, body := (*requests)[0], (*bodies)[0]
+	if req.Method != http.MethodPost {
+		t.Errorf("method = %s, want POST", req.Method)
+	}
+	if string(body) != delivery.Payload {
+		t.Errorf("body = %s, want %s", body, delivery.Payload)
+	}
+	if got := req.Header.Get(EVENT_HEADER); got != delivery.Event {
+		t.Errorf("%s = %s, want %s", EVENT_HEADER, got, delivery.Event)
+	}
+	if got := req.Header.Get(DELIVERY_HEADER); got != strconv.FormatInt(delivery.ID, 10) {
+		t.Errorf("%s = %s, want %d", DELIVERY_HEADER, got, delivery.ID)
+	}
+
+	// the receiver verifies the signature from the timestamp header and the raw body
+	timestamp := req.Header.Get(TIMESTAMP_HEADER)
+	if _, err := strconv.ParseInt(timestamp, 10, 64); err != nil {
+		t.Errorf("%s = %q is not a unix timestamp", TIMESTAMP_HEADER, timestamp)
+	}
+	if got, want := req.Header.Get(SIGNATURE_HEADER), Sign(delivery.Secret, timestamp, body); !hmac.Equal([]byte(got), []byte(want)) {
+		t.Errorf("%s = %s, want %s", SIGNATURE_HEADER, got, want)
+	}
+}
+
+func TestSendRetry(t *testing.T) {
+	server, requests, _ := receiver(t, http.StatusInternalServerError, http.StatusOK)
+	delivery := models.OrganizationWebhookDelivery{ID: 1, Payload: `{}`, URL: server.URL, Secret: "secret"}
+
+	status, err := Send(delivery)
+	if err == nil {
+		t.Fatal("Send() to a failing receiver succeeded")
+	}
+	if status != http.StatusInternalServerError {
+		t.Errorf("Send() status = %d, want %d", status, http.StatusInternalServerError)
+	}
+
+	next, backoff := Retry(1)
+	if next != utils.WebhookDeliveryPending || backoff != utils.WEBHOOK_RETRY_BACKOFF {
+		t.Errorf("Retry(1) = %s, %s, want %s, %s", next, backoff, utils.WebhookDeliveryPending, utils.WEBHOOK_RETRY_BACKOFF)
+	}
+
+	status, err = Send(delivery)
+	if err != nil {
+		t.Fatalf("Send() retry error = %v", err)
+	}
+	if status != http.StatusOK {
+		t.Errorf("Send() retry status = %d, want %d", status, http.StatusOK)
+	}
+	if len(*requests) != 2 {
+		t.Errorf("receiver got %d requests, want 2", len(*requests))
+	}
+}
+
+func TestSendRefusesInternalAddresses(t *testing.T) {
+	server, requests, _ := receiver(t, http.StatusOK)
+	allowInternal = false
+
+	_, err := Send(models.OrganizationWebhookDelivery{ID: 1, Payload: `{}`, URL: server.URL, Secret: "secret"})
+	if err == nil {
+		t.Fatal("Send() to a loopback address succeeded")
+	}
+	if len(*requests) != 0 {
+		t.Errorf("receiver got %d requests, want 0", len(*requests))
+	}
+}
+
+func TestRetry(t *testing.T) {
+	for attempts := int32(1); attempts <= int32(utils.WEBHOOK_MAX_ATTEMPTS); attempts++ {
+		status, backoff := Retry(attempts)
+
+		want := utils.WEBHOOK_RETRY_BACKOFF
+		for i := int32(1); i < attempts; i++ {
+			want *= 2
+		}
+		if backoff != want {
+			t.Errorf("Retry(%d) backoff = %s, want %s", attempts, backoff, want)
+		}
+
+		wantStatus := utils.WebhookDeliveryPending
+		if attempts == int32(utils.WEBHOOK_MAX_ATTEMPTS) {
+			wantStatus = utils.WebhookDeliveryFailed
+		}
+		if status != wantStatus {
+			t.Errorf("Retry(%d) status = %s, want %s", attempts, status, wantStatus)
+		}
+	}
+
+	if _, backoff := Retry(3); backoff != 4*utils.WEBHOOK_RETRY_BACKOFF {
+		t.Errorf("Retry(3) backoff = %s, want %s", backoff, 4*utils.WEBHOOK_RETRY_BACKOFF)
+	}
+}
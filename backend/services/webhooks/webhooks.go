@@ -0,0 +1,149 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"serverless-tesseract/db"
+	"serverless-tesseract/models"
+	"serverless-tesseract/services/webhooks/sender"
+	"serverless-tesseract/utils"
+	"time"
+)
+
+var wake = make(chan struct{}, 1)
+
+// recorded holds the OCR requests whose deliveries are still to be queued, so
+// the request that recorded them does not wait on the database
+var recorded = make(chan models.OrganizationOCRRequest, 256)
+
+// Start registers the OCR request listener that queues webhook deliveries and
+// launches the worker that sends them
+func Start() {
+	db.OnOCRRequestRecorded(func(request models.OrganizationOCRRequest) {
+		// the listener runs inside the request that recorded the OCR request, so
+		// when the queuer falls behind the deliveries are dropped instead
+		select {
+		case recorded <- request:
+		default:
+			log.Printf("Webhook queue is full, dropping the deliveries of OCR request %d", request.ID)
+		}
+	})
+	go queuer()
+	go worker()
+}
+
+// queuer queues the deliveries of recorded OCR requests in the background
+func queuer() {
+	for request := range recorded {
+		queueDeliveries(request)
+	}
+}
+
+// queueDeliveries records a pending delivery for each of the organization's
+// enabled webhooks
+func queueDeliveries(request models.OrganizationOCRRequest) {
+	webhooks, err := db.GetWebhooks(request.OrganizationID, true)
+	if err != nil {
+		log.Printf("Failed to get webhooks for organization %d: %v", request.OrganizationID, err)
+		return
+	}
+
+	if len(webhooks) == 0 {
+		return
+	}
+
+	payload := utils.WebhookPayload{
+		Event:      utils.WebhookEventOCRFailed,
+		RequestID:  request.ID,
+		JobID:      request.JobID,
+		Filename:   request.Filename,
+		FileHash:   request.FileHash,
+		Engine:     string(request.OCREngine),
+		PageCount:  request.NumOfPages,
+		TokenCount: request.TokenCount,
+		Success:    request.Success,
+		Cached:     request.CacheHit,
+		CreatedAt:  request.CreatedAt,
+	}
+	if request.Success {
+		payload.Event = utils.WebhookEventOCRCompleted
+		// results of synchronous requests are returned in the response, only
+		// jobs have a location the results can be fetched from
+		if request.JobID != "" {
+			payload.ResultLocation = fmt.Sprintf("%s/api/service/ocr/jobs/%s/result", utils.API_BASE_URL, request.JobID)
+		}
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("Failed to marshal webhook payload: %v", err)
+		return
+	}
+
+	for _, webhook := range webhooks {
+		err := db.CreateWebhookDelivery(webhook.ID, request.OrganizationID, request.ID, payload.Event, string(body))
+		if err != nil {
+			log.Printf("Failed to queue delivery for webhook %s: %v", webhook.ID, err)
+		}
+	}
+
+	select {
+	case wake <- struct{}{}:
+	default:
+	}
+}
+
+func worker() {
+	ticker := time.NewTicker(utils.WEBHOOK_POLL_INTERVAL)
+	defer ticker.Stop()
+
+	for {
+		// deliveries are claimed one at a time, a lease covers a single send so a
+		// slow receiver never lets another worker claim the next delivery twice
+		deliveries, err := db.ClaimDueWebhookDeliveries(1, utils.WEBHOOK_TIMEOUT*2)
+		if err != nil {
+			log.Printf("Failed to claim webhook deliveries: %v", err)
+		}
+
+		for _, delivery := range deliveries {
+			attempt(delivery)
+		}
+
+		if len(deliveries) == 0 {
+			select {
+			case <-wake:
+			case <-ticker.C:
+			}
+		}
+	}
+}
+
+// attempt sends a delivery once and records the outcome, scheduling a retry
+// with exponential backoff when the receiver did not accept it
+func attempt(delivery models.OrganizationWebhookDelivery) {
+	attempts := delivery.Attempts + 1
+	status, err := sender.Send(delivery)
+
+	var response_status *int32
+	if status != 0 {
+		response_status = &status
+	}
+
+	if err == nil {
+		err = db.UpdateWebhookDeliveryAttempt(delivery.ID, utils.WebhookDeliverySucceeded, attempts, response_status, nil, time.Now())
+		if err != nil {
+			log.Printf("Failed to record webhook delivery %d: %v", delivery.ID, err)
+		}
+		return
+	}
+
+	log.Printf("Webhook delivery %d attempt %d failed: %v", delivery.ID, attempts, err)
+	delivery_error := err.Error()
+	next_status, backoff := sender.Retry(attempts)
+
+	err = db.UpdateWebhookDeliveryAttempt(delivery.ID, next_status, attempts, response_status, &delivery_error, time.Now().Add(backoff))
+	if err != nil {
+		log.Printf("Failed to record webhook delivery %d: %v", delivery.ID, err)
+	}
+}
@@ -0,0 +1,197 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"math"
+	"regexp"
+	externalscripts "serverless-tesseract/services/external_scripts"
+	"serverless-tesseract/utils"
+	"slices"
+	"strings"
+
+	"github.com/disintegration/imaging"
+)
+
+// OCRZones reads the zones of a template from the file. Only the pages the zones
+// are on are rendered, and every zone is cropped from its page and OCR'd on its
+// own with the zone's engine, languages and whitelist. Zones must have their
+// engine and languages set. The fields are returned by zone name along with the
+// number of pages processed.
+func OCRZones(ctx context.Context, fileBytes []byte, zones []utils.TemplateZone, opts DocumentOptions) (map[string]utils.TemplateField, int32, error) {
+	ctx, cancel := context.WithTimeout(ctx, utils.OCR_DOCUMENT_TIMEOUT)
+	defer cancel()
+
+	fileType, err := DetectFileType(fileBytes)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	pageZones := map[int][]utils.TemplateZone{}
+	for _, zone := range zones {
+		pageZones[zone.Page] = append(pageZones[zone.Page], zone)
+	}
+
+	opts.Pages = ZonePages(zones)
+	pages, _, err := renderPages(ctx, fileBytes, fileType, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	fields := map[string]utils.TemplateField{}
+	var processed int32
+	for page := range pages {
+		if page.Err != nil {
+			page.Release()
+			return nil, processed, documentError(ctx, page.Err)
+		}
+
+		pageFields, err := ocrPageZones(ctx, page.Image, pageZones[page.Number], opts, page.Number)
+		page.Release()
+		if err != nil {
+			return nil, processed, err
+		}
+		for name, field := range pageFields {
+			fields[name] = field
+		}
+		processed++
+	}
+
+	return fields, processed, nil
+}
+
+// ZonePages returns the selection of the pages the zones are on
+func ZonePages(zones []utils.TemplateZone) utils.PageSelection {
+	var pageNumbers []int
+	for _, zone := range zones {
+		if !slices.Contains(pageNumbers, zone.Page) {
+			pageNumbers = append(pageNumbers, zone.Page)
+		}
+	}
+	slices.Sort(pageNumbers)
+
+	selection := utils.PageSelection{}
+	for _, pageNumber := range pageNumbers {
+		selection = append(selection, utils.PageRange{From: pageNumber, To: pageNumber})
+	}
+	return selection
+}
+
+// ocrPageZones crops the zones from the page image and OCRs each of them
+func ocrPageZones(ctx context.Context, imageBytes []byte, zones []utils.TemplateZone, opts DocumentOptions, pageNumber int) (map[string]utils.TemplateField, error) {
+	img, err := imaging.Decode(bytes.NewReader(imageBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode page %d: %w", pageNumber, err)
+	}
+	bounds := img.Bounds()
+
+	fields := map[string]utils.TemplateField{}
+	for _, zone := range zones {
+		rect := zoneRect(zone, bounds)
+		if rect.Empty() {
+			return nil, fmt.Errorf("zone %s is outside page %d", zone.Name, pageNumber)
+		}
+
+		var buf bytes.Buffer
+		if err := imaging.Encode(&buf, imaging.Crop(img, rect), imaging.PNG); err != nil {
+			return nil, fmt.Errorf("failed to encode zone %s: %w", zone.Name, err)
+		}
+
+		words, err := runZoneOCR(ctx, buf.Bytes(), zone, opts, pageNumber)
+		if err != nil {
+			return nil, err
+		}
+
+		// report positions on the page, not the cropped zone
+		offset := rect.Min.Sub(bounds.Min)
+		for i := range words {
+			words[i].BBox = offsetBBox(words[i].BBox, offset)
+		}
+
+		element := layoutElement(words, cellText(words))
+		field := utils.TemplateField{
+			Value:      element.Text,
+			Confidence: element.Confidence,
+			PageNumber: pageNumber,
+			BBox:       newBBox(offset.X, offset.Y, offset.X+rect.Dx(), offset.Y+rect.Dy()),
+			Valid:      true,
+		}
+		if zone.Pattern != "" {
+			pattern, err := regexp.Compile(zone.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern of zone %s: %w", zone.Name, err)
+			}
+			field.Valid = pattern.MatchString(field.Value)
+		}
+		fields[zone.Name] = field
+	}
+	return fields, nil
+}
+
+// runZoneOCR OCRs a cropped zone, returning its words with the characters
+// outside the zone's whitelist removed
+func runZoneOCR(ctx context.Context, imageBytes []byte, zone utils.TemplateZone, opts DocumentOptions, pageNumber int) ([]utils.OCRResponse, error) {
+	release, err := acquirePageSlot(ctx, opts.OrganizationID, opts.PageConcurrency)
+	if err != nil {
+		return nil, documentError(ctx, err)
+	}
+	defer release()
+
+	zoneCtx, cancel := externalscripts.WithWorkerDeadline(ctx, utils.OCR_PAGE_TIMEOUT)
+	defer cancel()
+
+	results, err := RunOCR(zoneCtx, imageBytes, zone.Engine, pageNumber, false, zone.Languages, zone.Whitelist)
+	if err != nil && ctx.Err() == nil && errors.Is(context.Cause(zoneCtx), context.DeadlineExceeded) {
+		return nil, &utils.TimeoutError{Scope: "page", PageNumber: pageNumber, Timeout: utils.OCR_PAGE_TIMEOUT}
+	}
+	if err != nil {
+		return nil, documentError(ctx, fmt.Errorf("failed to OCR zone %s: %w", zone.Name, err))
+	}
+
+	// not every engine supports a whitelist, so the results are filtered as well
+	words := []utils.OCRResponse{}
+	for _, word := range results.OCRResponses {
+		if zone.Whitelist != "" {
+			word.Text = strings.Map(func(r rune) rune {
+				if strings.ContainsRune(zone.Whitelist, r) {
+					return r
+				}
+				return -1
+			}, word.Text)
+		}
+		if word.Text == "" {
+			continue
+		}
+		word.Source = utils.SourceOCR
+		words = append(words, word)
+	}
+	return words, nil
+}
+
+// zoneRect returns the zone's rectangle in pixels of the page, clipped to the page
+func zoneRect(zone utils.TemplateZone, bounds image.Rectangle) image.Rectangle {
+	width, height := float64(bounds.Dx()), float64(bounds.Dy())
+	rect := image.Rect(
+		bounds.Min.X+int(math.Round(zone.X*width)),
+		bounds.Min.Y+int(math.Round(zone.Y*height)),
+		bounds.Min.X+int(math.Round((zone.X+zone.Width)*width)),
+		bounds.Min.Y+int(math.Round((zone.Y+zone.Height)*height)),
+	)
+	return rect.Intersect(bounds)
+}
+
+// offsetBBox moves every corner of the box by offset
+func offsetBBox(box utils.BBox, offset image.Point) utils.BBox {
+	move := func(p utils.XY) utils.XY {
+		return utils.XY{X: p.X + offset.X, Y: p.Y + offset.Y}
+	}
+	return utils.BBox{
+		TopLeft:     move(box.TopLeft),
+		TopRight:    move(box.TopRight),
+		BottomLeft:  move(box.BottomLeft),
+		BottomRight: move(box.BottomRight),
+	}
+}
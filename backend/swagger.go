@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"serverless-tesseract/docs"
+	"serverless-tesseract/utils"
+)
+
+// registerEngineDocs adds the engines enabled in this deployment as the enum of
+// every "engine" parameter in the served swagger docs. The generated docs cannot
+// list them because engines are registered at runtime.
+func registerEngineDocs() {
+	var spec map[string]interface{}
+	if err := json.Unmarshal([]byte(docs.SwaggerInfo.ReadDoc()), &spec); err != nil {
+		log.Printf("Failed to parse swagger docs: %v", err)
+		return
+	}
+
+	engines := []string{}
+	for _, engine := range utils.OCREngineValues {
+		engines = append(engines, string(engine))
+	}
+
+	paths, _ := spec["paths"].(map[string]interface{})
+	for _, path := range paths {
+		operations, _ := path.(map[string]interface{})
+		for _, operation := range operations {
+			// a path also holds entries that are not operations, such as shared parameters
+			operation, ok := operation.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			parameters, _ := operation["parameters"].([]interface{})
+			for _, parameter := range parameters {
+				parameter, ok := parameter.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				if parameter["name"] == "engine" {
+					parameter["enum"] = engines
+				}
+			}
+		}
+	}
+
+	specBytes, err := json.Marshal(spec)
+	if err != nil {
+		log.Printf("Failed to marshal swagger docs: %v", err)
+		return
+	}
+	docs.SwaggerInfo.SwaggerTemplate = string(specBytes)
+}
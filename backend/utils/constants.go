@@ -3,6 +3,7 @@ package utils
 import (
 	"fmt"
 	"os"
+	"runtime"
 	"time"
 )
 
@@ -14,10 +15,97 @@ var ORG_USER_LIMIT = 3
 
 var FILE_SIZE_LIMIT = 20 * 1024 * 1024 // 20MB
 
+// a batch has at most BATCH_MAX_FILES files, including the files of ZIP archives,
+// of at most BATCH_SIZE_LIMIT bytes in total once extracted
+var BATCH_MAX_FILES = GetEnvInt("BATCH_MAX_FILES", 500)
+var BATCH_SIZE_LIMIT = GetEnvInt("BATCH_SIZE_LIMIT", 200*1024*1024) // 200MB
+
+// documents can be read from a source_url on one of the comma separated
+// SOURCE_URL_ALLOWED_HOSTS, a "*." prefix allows every subdomain of a host, or
+// from a bucket of the R2 account listed in SOURCE_ALLOWED_BUCKETS. Nothing can
+// be read from a source while these are unset.
+var SOURCE_URL_ALLOWED_HOSTS = os.Getenv("SOURCE_URL_ALLOWED_HOSTS")
+var SOURCE_ALLOWED_BUCKETS = os.Getenv("SOURCE_ALLOWED_BUCKETS")
+var SOURCE_URL_TIMEOUT = GetEnvDuration("SOURCE_URL_TIMEOUT", time.Second*30)
+var SOURCE_URL_MAX_REDIRECTS = 5
+
 var PDF_RENDER_DPI = 120.0
 
+// images and TIFF pages of more than IMAGE_MAX_PIXELS pixels are rejected
+// before they are decoded, a decoded page takes 4 bytes per pixel
+var IMAGE_MAX_PIXELS = GetEnvInt("IMAGE_MAX_PIXELS", 100_000_000)
+
+// with text_layer=auto a PDF page is OCR'd unless its text layer has at least
+// TEXT_LAYER_MIN_CHARACTERS non-whitespace characters
+var TEXT_LAYER_MIN_CHARACTERS = GetEnvInt("TEXT_LAYER_MIN_CHARACTERS", 16)
+
+// download links returned for delivery=link expire after EXPORT_LINK_TTL
+var EXPORT_LINK_TTL = GetEnvDuration("EXPORT_LINK_TTL", time.Hour)
+
+// languages=auto OCRs a page with at most LANGUAGE_DETECTION_MAX_LANGUAGES of the
+// installed languages written in the page's script
+var LANGUAGE_DETECTION_MAX_LANGUAGES = GetEnvInt("LANGUAGE_DETECTION_MAX_LANGUAGES", 3)
+
+// an OCR template has at most TEMPLATE_MAX_ZONES zones
+var TEMPLATE_MAX_ZONES = GetEnvInt("TEMPLATE_MAX_ZONES", 50)
+
+// the "upscale" preprocessing step enlarges images whose longer side is shorter
+// than PREPROCESS_UPSCALE_MIN_SIZE pixels, by at most PREPROCESS_UPSCALE_MAX_FACTOR
+var PREPROCESS_UPSCALE_MIN_SIZE = GetEnvInt("PREPROCESS_UPSCALE_MIN_SIZE", 2000)
+var PREPROCESS_UPSCALE_MAX_FACTOR = 3.0
+
+// PDF pages are rendered while earlier pages are OCR'd, at most
+// PDF_MAX_IN_FLIGHT_PAGES rendered page images are held in memory at once
+var PDF_MAX_IN_FLIGHT_PAGES = GetEnvInt("PDF_MAX_IN_FLIGHT_PAGES", 8)
+
+// pages are OCR'd concurrently, at most OCR_MAX_CONCURRENT_PAGES across the
+// service and OCR_ORGANIZATION_PAGE_CONCURRENCY per organization unless the
+// organization has its own "ocrPageConcurrency" set. At least one page is
+// OCR'd at a time whatever OCR_MAX_CONCURRENT_PAGES is set to.
+var OCR_MAX_CONCURRENT_PAGES = max(1, GetEnvInt("OCR_MAX_CONCURRENT_PAGES", runtime.NumCPU()))
+var OCR_ORGANIZATION_PAGE_CONCURRENCY = GetEnvInt("OCR_ORGANIZATION_PAGE_CONCURRENCY", 4)
+
+// deadlines for OCR, a page or document that runs longer is cancelled and the
+// request fails with a 504. A page's deadline starts once it gets an engine worker.
+var OCR_PAGE_TIMEOUT = GetEnvDuration("OCR_PAGE_TIMEOUT", time.Minute*2)
+var OCR_DOCUMENT_TIMEOUT = GetEnvDuration("OCR_DOCUMENT_TIMEOUT", time.Minute*10)
+
 var POLAR_FREE_PAGE_LIMIT = 100
 
+// configuration for the persistent python OCR workers. The pool size can be set
+// per engine with PYTHON_WORKER_POOL_SIZE_<ENGINE>, a size of 0 disables the
+// pool and spawns a new python process for every page instead. It defaults to
+// OCR_MAX_CONCURRENT_PAGES so every page holding a page slot has a worker.
+var PYTHON_WORKER_POOL_SIZE = GetEnvInt("PYTHON_WORKER_POOL_SIZE", OCR_MAX_CONCURRENT_PAGES)
+var PYTHON_WORKER_MAX_REQUESTS = GetEnvInt("PYTHON_WORKER_MAX_REQUESTS", 500)
+var PYTHON_WORKER_HEALTH_INTERVAL = time.Second * 30
+var PYTHON_WORKER_PING_TIMEOUT = time.Second * 30
+
+// comma separated list of the OCR engines to enable, all engines are enabled when unset
+var OCR_ENGINES = os.Getenv("OCR_ENGINES")
+
+// configuration for the asynchronous OCR job workers
+var OCR_JOB_WORKERS = GetEnvInt("OCR_JOB_WORKERS", 2)
+var OCR_JOB_POLL_INTERVAL = time.Second * 5
+
+// jobs whose worker has not sent a heartbeat within this window are assumed to
+// be orphaned by a crashed or restarted worker and are queued again
+var OCR_JOB_STALE_TIMEOUT = time.Minute * 15
+
+// a worker refreshes the jobs it is processing every OCR_JOB_HEARTBEAT_INTERVAL,
+// so a slow job is not mistaken for an orphaned one
+var OCR_JOB_HEARTBEAT_INTERVAL = time.Minute
+
+// configuration for webhook deliveries, failed deliveries are retried with an
+// exponential backoff starting at WEBHOOK_RETRY_BACKOFF
+var WEBHOOK_MAX_ATTEMPTS = 8
+var WEBHOOK_RETRY_BACKOFF = time.Second * 30
+var WEBHOOK_TIMEOUT = time.Second * 10
+var WEBHOOK_POLL_INTERVAL = time.Second * 5
+
+// public URL of the API, used to build links sent to clients
+var API_BASE_URL = GetEnv("API_BASE_URL", "https://api.atlasocr.com")
+
 // configuration for R2
 var R2_ACCESS_KEY_ID = os.Getenv("R2_ACCESS_KEY_ID")
 var R2_SECRET_ACCESS_KEY = os.Getenv("R2_SECRET_ACCESS_KEY")
@@ -1,10 +1,42 @@
 package utils
 
-import "errors"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
 
 var (
-	ErrTokenRequired    = errors.New("token required")
-	ErrInvalidAPIKey    = errors.New("invalid API key")
-	ErrAPIKeyExpired    = errors.New("API key expired")
-	ErrPermissionDenied = errors.New("permission denied")
+	ErrTokenRequired        = errors.New("token required")
+	ErrInvalidAPIKey        = errors.New("invalid API key")
+	ErrAPIKeyExpired        = errors.New("API key expired")
+	ErrPermissionDenied     = errors.New("permission denied")
+	ErrInvalidFileType      = errors.New("invalid file type")
+	ErrPageOutOfRange       = errors.New("page out of range")
+	ErrUnsupportedMediaType = errors.New("unsupported media type")
+	ErrFileTooLarge         = errors.New("file size exceeds limit")
+	ErrImageTooLarge        = errors.New("image exceeds pixel limit")
+	ErrSourceNotAllowed     = errors.New("source not allowed")
+	ErrAddressNotPublic     = errors.New("address not public")
+	ErrJobClaimLost         = errors.New("OCR job is no longer claimed by this worker")
 )
+
+// TimeoutError is returned when OCR runs past the per page or per document deadline
+type TimeoutError struct {
+	// "page" or "document"
+	Scope      string
+	PageNumber int
+	Timeout    time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	if e.Scope == "page" {
+		return fmt.Sprintf("page %d exceeded the OCR timeout of %s", e.PageNumber, e.Timeout)
+	}
+	return fmt.Sprintf("document exceeded the OCR timeout of %s", e.Timeout)
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return context.DeadlineExceeded
+}
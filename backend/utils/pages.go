@@ -0,0 +1,122 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PageRange is an inclusive range of 1-based page numbers, a To of 0 means the
+// range runs to the last page
+type PageRange struct {
+	From int
+	To   int
+}
+
+// PageSelection is the set of pages to OCR, parsed from an expression such as
+// "1-3,7,10-". An empty selection selects every page.
+type PageSelection []PageRange
+
+// ParsePageSelection parses a comma separated list of page numbers and ranges
+func ParsePageSelection(expression string) (PageSelection, error) {
+	expression = strings.TrimSpace(expression)
+	if expression == "" {
+		return nil, nil
+	}
+
+	var selection PageSelection
+	for _, part := range strings.Split(expression, ",") {
+		part = strings.TrimSpace(part)
+
+		from, to, isRange := strings.Cut(part, "-")
+		first, err := parsePageNumber(from)
+		if err != nil {
+			return nil, fmt.Errorf("invalid page %q", part)
+		}
+
+		pageRange := PageRange{From: first, To: first}
+		if isRange {
+			pageRange.To = 0
+			if strings.TrimSpace(to) != "" {
+				last, err := parsePageNumber(to)
+				if err != nil || last < first {
+					return nil, fmt.Errorf("invalid page range %q", part)
+				}
+				pageRange.To = last
+			}
+		}
+
+		selection = append(selection, pageRange)
+	}
+
+	return selection, nil
+}
+
+func parsePageNumber(value string) (int, error) {
+	page, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || page < 1 {
+		return 0, fmt.Errorf("invalid page number %q", value)
+	}
+	return page, nil
+}
+
+// Resolve returns the selected page numbers of a document with numPages pages in
+// ascending order, without duplicates. ErrPageOutOfRange is returned when the
+// selection refers to a page past the end of the document.
+func (s PageSelection) Resolve(numPages int) ([]int, error) {
+	selected := make([]bool, numPages+1)
+	for _, pageRange := range s {
+		to := pageRange.To
+		if to == 0 {
+			to = numPages
+		}
+
+		if pageRange.From > numPages || to > numPages {
+			return nil, fmt.Errorf("%w: %s, the document has %d pages", ErrPageOutOfRange, pageRange, numPages)
+		}
+
+		for page := pageRange.From; page <= to; page++ {
+			selected[page] = true
+		}
+	}
+
+	var pages []int
+	for page := 1; page <= numPages; page++ {
+		if len(s) == 0 || selected[page] {
+			pages = append(pages, page)
+		}
+	}
+	return pages, nil
+}
+
+// Includes reports whether the page is selected, ignoring the document length
+func (s PageSelection) Includes(page int) bool {
+	if len(s) == 0 {
+		return true
+	}
+	for _, pageRange := range s {
+		if page >= pageRange.From && (pageRange.To == 0 || page <= pageRange.To) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r PageRange) String() string {
+	switch {
+	case r.To == 0:
+		return fmt.Sprintf("%d-", r.From)
+	case r.From == r.To:
+		return strconv.Itoa(r.From)
+	default:
+		return fmt.Sprintf("%d-%d", r.From, r.To)
+	}
+}
+
+func (s PageSelection) String() string {
+	parts := make([]string, len(s))
+	for i, pageRange := range s {
+		parts[i] = pageRange.String()
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,118 @@
+package utils
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestParsePageSelection(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       PageSelection
+		wantErr    bool
+	}{
+		{expression: "", want: nil},
+		{expression: "   ", want: nil},
+		{expression: "1-3,7,10-", want: PageSelection{{From: 1, To: 3}, {From: 7, To: 7}, {From: 10, To: 0}}},
+		{expression: " 2 - 4 , 6 ", want: PageSelection{{From: 2, To: 4}, {From: 6, To: 6}}},
+		{expression: "1-5,3-8", want: PageSelection{{From: 1, To: 5}, {From: 3, To: 8}}},
+		{expression: "4-4", want: PageSelection{{From: 4, To: 4}}},
+		{expression: "5-", want: PageSelection{{From: 5, To: 0}}},
+		{expression: "0", wantErr: true},
+		{expression: "0-3", wantErr: true},
+		{expression: "3-1", wantErr: true},
+		{expression: "-3", wantErr: true},
+		{expression: "1,,2", wantErr: true},
+		{expression: "a", wantErr: true},
+		{expression: "1-b", wantErr: true},
+		{expression: "-1", wantErr: true},
+	}
+
+	for _, test := range tests {
+		got, err := ParsePageSelection(test.expression)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("ParsePageSelection(%q) = %v, want an error", test.expression, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParsePageSelection(%q) error = %v", test.expression, err)
+			continue
+		}
+		if !slices.Equal(got, test.want) {
+			t.Errorf("ParsePageSelection(%q) = %v, want %v", test.expression, got, test.want)
+		}
+	}
+}
+
+func TestPageSelectionResolve(t *testing.T) {
+	tests := []struct {
+		expression string
+		numPages   int
+		want       []int
+		wantErr    bool
+	}{
+		{expression: "", numPages: 3, want: []int{1, 2, 3}},
+		{expression: "1-3,7,10-", numPages: 12, want: []int{1, 2, 3, 7, 10, 11, 12}},
+		{expression: "1-5,3-8", numPages: 10, want: []int{1, 2, 3, 4, 5, 6, 7, 8}},
+		{expression: "7,2,2,1-2", numPages: 7, want: []int{1, 2, 7}},
+		{expression: "3-", numPages: 3, want: []int{3}},
+		{expression: "5-", numPages: 3, wantErr: true},
+		{expression: "4", numPages: 3, wantErr: true},
+		{expression: "2-4", numPages: 3, wantErr: true},
+	}
+
+	for _, test := range tests {
+		selection, err := ParsePageSelection(test.expression)
+		if err != nil {
+			t.Fatalf("ParsePageSelection(%q) error = %v", test.expression, err)
+		}
+
+		got, err := selection.Resolve(test.numPages)
+		if test.wantErr {
+			if !errors.Is(err, ErrPageOutOfRange) {
+				t.Errorf("%q.Resolve(%d) error = %v, want %v", test.expression, test.numPages, err, ErrPageOutOfRange)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q.Resolve(%d) error = %v", test.expression, test.numPages, err)
+			continue
+		}
+		if !slices.Equal(got, test.want) {
+			t.Errorf("%q.Resolve(%d) = %v, want %v", test.expression, test.numPages, got, test.want)
+		}
+	}
+}
+
+func TestPageSelectionIncludes(t *testing.T) {
+	selection, err := ParsePageSelection("1-3,7,10-")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for page, want := range map[int]bool{1: true, 3: true, 4: false, 7: true, 8: false, 10: true, 1000: true} {
+		if got := selection.Includes(page); got != want {
+			t.Errorf("Includes(%d) = %v, want %v", page, got, want)
+		}
+	}
+
+	// an empty selection includes every page
+	if !PageSelection(nil).Includes(42) {
+		t.Error("an empty selection does not include page 42")
+	}
+}
+
+func TestPageSelectionString(t *testing.T) {
+	for _, expression := range []string{"1-3,7,10-", "5", "2-4,9-"} {
+		selection, err := ParsePageSelection(expression)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := selection.String(); got != expression {
+			t.Errorf("ParsePageSelection(%q).String() = %q", expression, got)
+		}
+	}
+}
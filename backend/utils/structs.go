@@ -1,7 +1,17 @@
 package utils
 
+import "time"
+
 type ErrorResponse struct {
 	Error string `json:"error" example:"Error message"`
+	// the invalid fields of a request that failed validation
+	Fields []FieldError `json:"fields,omitempty"`
+}
+
+// FieldError is the validation error of one field of a request
+type FieldError struct {
+	Field string `json:"field" example:"engine"`
+	Error string `json:"error" example:"Invalid engine"`
 }
 
 type ErrPermissionDeniedResponse struct {
@@ -29,6 +39,116 @@ var CachePolicyValues = []CachePolicyType{
 	CacheOnly,
 }
 
+// TEXT LAYER
+type TextLayerModeType string
+
+const (
+	// TextLayerAuto uses the PDF's text layer where a page has one and OCRs the other pages
+	TextLayerAuto TextLayerModeType = "auto"
+	// TextLayerOnly uses the PDF's text layer and never OCRs
+	TextLayerOnly TextLayerModeType = "only"
+	// TextLayerNever OCRs every page
+	TextLayerNever TextLayerModeType = "never"
+)
+
+var TextLayerModeValues = []TextLayerModeType{
+	TextLayerAuto,
+	TextLayerOnly,
+	TextLayerNever,
+}
+
+// RESPONSE FORMAT
+type ResponseFormatType string
+
+const (
+	// FormatFlat returns a flat list of words, or a single response per page when raw is set
+	FormatFlat ResponseFormatType = "flat"
+	// FormatStructured returns a page, block, paragraph, line and word tree
+	FormatStructured ResponseFormatType = "structured"
+)
+
+var ResponseFormatValues = []ResponseFormatType{
+	FormatFlat,
+	FormatStructured,
+}
+
+// OUTPUT FORMAT
+type OutputFormatType string
+
+const (
+	OutputJSON    OutputFormatType = "json"
+	OutputHOCR    OutputFormatType = "hocr"
+	OutputALTO    OutputFormatType = "alto"
+	OutputPageXML OutputFormatType = "pagexml"
+	OutputText    OutputFormatType = "txt"
+	OutputTSV     OutputFormatType = "tsv"
+	// OutputSearchablePDF is the original document with an invisible text layer
+	OutputSearchablePDF OutputFormatType = "searchable_pdf"
+)
+
+var OutputFormatValues = []OutputFormatType{
+	OutputJSON,
+	OutputHOCR,
+	OutputALTO,
+	OutputPageXML,
+	OutputText,
+	OutputTSV,
+	OutputSearchablePDF,
+}
+
+// DELIVERY
+type DeliveryType string
+
+const (
+	// DeliveryBytes returns the rendered document in the response body
+	DeliveryBytes DeliveryType = "bytes"
+	// DeliveryLink stores the rendered document in R2 and returns a download link
+	DeliveryLink DeliveryType = "link"
+)
+
+var DeliveryValues = []DeliveryType{
+	DeliveryBytes,
+	DeliveryLink,
+}
+
+// EXTRACT
+type ExtractType string
+
+const (
+	// ExtractKeyValues groups the words into label and value pairs, see services.ExtractKeyValues
+	ExtractKeyValues ExtractType = "key_values"
+)
+
+var ExtractValues = []ExtractType{
+	ExtractKeyValues,
+}
+
+// BARCODE SYMBOLOGY
+type BarcodeSymbologyType string
+
+const (
+	SymbologyQRCode     BarcodeSymbologyType = "qr_code"
+	SymbologyCode128    BarcodeSymbologyType = "code_128"
+	SymbologyEAN13      BarcodeSymbologyType = "ean_13"
+	SymbologyEAN8       BarcodeSymbologyType = "ean_8"
+	SymbologyUPCA       BarcodeSymbologyType = "upc_a"
+	SymbologyUPCE       BarcodeSymbologyType = "upc_e"
+	SymbologyDataMatrix BarcodeSymbologyType = "data_matrix"
+	SymbologyPDF417     BarcodeSymbologyType = "pdf417"
+)
+
+type DownloadLinkResponse struct {
+	URL         string    `json:"url"`
+	ContentType string    `json:"content_type" example:"application/pdf"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// OCRResponse sources
+const (
+	SourceOCR       = "ocr"
+	SourceTextLayer = "text_layer"
+)
+
 // OCR ENGINE
 type OCREngine struct {
 	Tesseract string `json:"tesseract" example:"TESSERACT"`
@@ -44,10 +164,197 @@ const (
 	EngineDoctoR    OCREngineType = "DOCTR"
 )
 
-var OCREngineValues = []OCREngineType{
-	EngineTesseract,
-	EngineEasyOCR,
-	EngineDoctoR,
+// OCREngineValues holds the engines that are enabled in this deployment, it is
+// populated as engines are registered with services/engines
+var OCREngineValues = []OCREngineType{}
+
+type OCREngineInfo struct {
+	Name               OCREngineType `json:"name" example:"TESSERACT"`
+	WordBoxes          bool          `json:"word_boxes" example:"true"`
+	Confidence         bool          `json:"confidence" example:"true"`
+	Layout             bool          `json:"layout" example:"true"`
+	SupportedLanguages []string      `json:"supported_languages" example:"en"`
+}
+
+// OCR JOB STATUS
+type OCRJobStatusType string
+
+const (
+	JobQueued     OCRJobStatusType = "QUEUED"
+	JobProcessing OCRJobStatusType = "PROCESSING"
+	JobCompleted  OCRJobStatusType = "COMPLETED"
+	JobFailed     OCRJobStatusType = "FAILED"
+)
+
+type OCRJobResponse struct {
+	ID           string             `json:"id" example:"5f2b8e0c9d7a4e3b8c1d2e3f4a5b6c7d"`
+	Status       OCRJobStatusType   `json:"status" example:"PROCESSING"`
+	Filename     string             `json:"filename" example:"invoice.pdf"`
+	FileHash     string             `json:"file_hash"`
+	Engine       OCREngineType      `json:"engine" example:"TESSERACT"`
+	Raw          bool               `json:"raw" example:"true"`
+	TextLayer    TextLayerModeType  `json:"text_layer" example:"never"`
+	Preprocess   string             `json:"preprocess,omitempty" example:"deskew,threshold"`
+	Languages    []string           `json:"languages" example:"en,de"`
+	Tables       bool               `json:"tables" example:"false"`
+	Barcodes     bool               `json:"barcodes" example:"false"`
+	Extract      []ExtractType      `json:"extract,omitempty" example:"key_values"`
+	Format       ResponseFormatType `json:"format" example:"flat"`
+	OutputFormat OutputFormatType   `json:"output_format" example:"json"`
+	Pages        string             `json:"pages,omitempty" example:"1-3,7"`
+	PagesTotal   int32              `json:"pages_total" example:"10"`
+	PagesDone    int32              `json:"pages_done" example:"4"`
+	Error        string             `json:"error,omitempty"`
+	CreatedAt    time.Time          `json:"created_at"`
+	StartedAt    *time.Time         `json:"started_at,omitempty"`
+	CompletedAt  *time.Time         `json:"completed_at,omitempty"`
+}
+
+// WEBHOOKS
+type WebhookDeliveryStatusType string
+
+const (
+	WebhookDeliveryPending   WebhookDeliveryStatusType = "PENDING"
+	WebhookDeliverySucceeded WebhookDeliveryStatusType = "SUCCEEDED"
+	WebhookDeliveryFailed    WebhookDeliveryStatusType = "FAILED"
+)
+
+const (
+	WebhookEventOCRCompleted = "ocr.completed"
+	WebhookEventOCRFailed    = "ocr.failed"
+)
+
+type CreateWebhookRequest struct {
+	URL    string `json:"url" binding:"required" example:"https://example.com/webhooks/ocr"`
+	Secret string `json:"secret" example:"whsec_5f2b8e0c9d7a4e3b8c1d2e3f"`
+}
+
+type WebhookResponse struct {
+	ID        string    `json:"id" example:"5f2b8e0c9d7a4e3b8c1d2e3f4a5b6c7d"`
+	URL       string    `json:"url" example:"https://example.com/webhooks/ocr"`
+	Enabled   bool      `json:"enabled" example:"true"`
+	CreatedAt time.Time `json:"created_at"`
+	// only returned when the webhook is created
+	Secret string `json:"secret,omitempty" example:"whsec_5f2b8e0c9d7a4e3b8c1d2e3f"`
+}
+
+type WebhookDeliveryResponse struct {
+	ID             int64                     `json:"id" example:"1"`
+	Event          string                    `json:"event" example:"ocr.completed"`
+	Status         WebhookDeliveryStatusType `json:"status" example:"SUCCEEDED"`
+	Attempts       int32                     `json:"attempts" example:"1"`
+	ResponseStatus *int32                    `json:"response_status,omitempty" example:"200"`
+	Error          string                    `json:"error,omitempty"`
+	Payload        string                    `json:"payload"`
+	CreatedAt      time.Time                 `json:"created_at"`
+	NextAttemptAt  time.Time                 `json:"next_attempt_at"`
+	DeliveredAt    *time.Time                `json:"delivered_at,omitempty"`
+}
+
+// KEY VALUE LABELS
+type KeyValueLabelRequest struct {
+	Key      string   `json:"key" binding:"required" example:"invoice_number"`
+	Synonyms []string `json:"synonyms" binding:"required" example:"invoice no,bill number"`
+}
+
+type KeyValueLabelResponse struct {
+	ID        string    `json:"id" example:"5f2b8e0c9d7a4e3b8c1d2e3f4a5b6c7d"`
+	Key       string    `json:"key" example:"invoice_number"`
+	Synonyms  []string  `json:"synonyms" example:"invoice no,bill number"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// BATCH
+// BatchFileResult is the outcome of one file of a batch, Result is the file's
+// results in the requested format when Status is 200 and Error is set otherwise
+type BatchFileResult struct {
+	Status int    `json:"status" example:"200"`
+	Result any    `json:"result,omitempty"`
+	Error  string `json:"error,omitempty"`
+	// the file with identical content the results were taken from
+	DuplicateOf string `json:"duplicate_of,omitempty" example:"scan-001.png"`
+}
+
+type BatchOCRResponse struct {
+	// results by filename, files of ZIP archives are named archive.zip/path/in/archive
+	Files     map[string]BatchFileResult `json:"files"`
+	Succeeded int                        `json:"succeeded" example:"9"`
+	Failed    int                        `json:"failed" example:"1"`
+	// pages across the distinct files the organization was checked to be entitled to
+	Pages int `json:"pages" example:"10"`
+}
+
+// OCR TEMPLATES
+// TemplateZone is a named rectangle of a page. X, Y, Width and Height are
+// fractions of the page's width and height, measured from its top left corner.
+type TemplateZone struct {
+	Name string `json:"name" binding:"required" example:"invoice_number"`
+	// the page of the document the zone is on, starting at 1
+	Page   int     `json:"page" example:"1"`
+	X      float64 `json:"x" example:"0.6"`
+	Y      float64 `json:"y" example:"0.05"`
+	Width  float64 `json:"width" example:"0.3"`
+	Height float64 `json:"height" example:"0.04"`
+	// the engine and languages of the zone, the template's when empty
+	Engine    OCREngineType `json:"engine,omitempty" example:"TESSERACT"`
+	Languages []string      `json:"languages,omitempty" example:"en"`
+	// the only characters recognized in the zone
+	Whitelist string `json:"whitelist,omitempty" example:"0123456789-"`
+	// a regular expression the zone's text has to match to be valid
+	Pattern string `json:"pattern,omitempty" example:"^\\d{4,}$"`
+}
+
+type TemplateRequest struct {
+	Name      string         `json:"name" binding:"required" example:"acme_invoice"`
+	Engine    OCREngineType  `json:"engine" example:"TESSERACT"`
+	Languages []string       `json:"languages" example:"en"`
+	Zones     []TemplateZone `json:"zones" binding:"required"`
+}
+
+type TemplateResponse struct {
+	ID        string         `json:"id" example:"5f2b8e0c9d7a4e3b8c1d2e3f4a5b6c7d"`
+	Name      string         `json:"name" example:"acme_invoice"`
+	Engine    OCREngineType  `json:"engine" example:"TESSERACT"`
+	Languages []string       `json:"languages" example:"en"`
+	Zones     []TemplateZone `json:"zones"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+// TemplateField is the text read from one of a template's zones
+type TemplateField struct {
+	Value      string  `json:"value" example:"12345"`
+	Confidence float64 `json:"confidence" example:"0.9"`
+	PageNumber int     `json:"page_number" example:"1"`
+	// the zone in pixels of the page
+	BBox BBox `json:"bbox"`
+	// false when the value does not match the zone's pattern
+	Valid bool `json:"valid" example:"true"`
+}
+
+type TemplateOCRResponse struct {
+	TemplateID string                   `json:"template_id" example:"5f2b8e0c9d7a4e3b8c1d2e3f4a5b6c7d"`
+	Template   string                   `json:"template" example:"acme_invoice"`
+	Fields     map[string]TemplateField `json:"fields"`
+	// true when every field is valid
+	Valid bool `json:"valid" example:"true"`
+}
+
+// WebhookPayload is the body POSTed to an organization's webhooks when an OCR request finishes
+type WebhookPayload struct {
+	Event          string    `json:"event" example:"ocr.completed"`
+	RequestID      int64     `json:"request_id" example:"1"`
+	JobID          string    `json:"job_id,omitempty" example:"5f2b8e0c9d7a4e3b8c1d2e3f4a5b6c7d"`
+	Filename       string    `json:"filename" example:"invoice.pdf"`
+	FileHash       string    `json:"file_hash"`
+	Engine         string    `json:"engine" example:"TESSERACT"`
+	PageCount      int32     `json:"page_count" example:"3"`
+	TokenCount     int64     `json:"token_count" example:"100"`
+	Success        bool      `json:"success" example:"true"`
+	Cached         bool      `json:"cached" example:"false"`
+	ResultLocation string    `json:"result_location,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
 }
 
 // OCR RESPONSE LIST
@@ -57,6 +364,105 @@ type OCRResponseList struct {
 	NumberOfTokens int64         `json:"number_of_tokens" example:"100"`
 	Raw            bool          `json:"raw" example:"true"`
 	Cached         bool          `json:"cached" example:"true"`
+	// ISO 639-1 codes of the languages the engine was asked to recognize, or "auto"
+	Languages []string `json:"languages,omitempty" example:"en,de"`
+	// the language and script detected on each OCR'd page when languages is "auto"
+	PageLanguages []PageLanguage `json:"page_languages,omitempty"`
+	// the size of every page in pixels, bounding boxes are relative to it
+	PageSizes []PageSize `json:"page_sizes,omitempty"`
+	// the tables detected on every page when tables are requested
+	Tables []Table `json:"tables,omitempty"`
+	// the barcodes decoded on every page when barcodes are requested
+	Barcodes []Barcode `json:"barcodes,omitempty"`
+	// the label and value pairs found when extract includes key_values
+	KeyValues []KeyValue `json:"key_values,omitempty"`
+
+	// DocumentKey is the R2 key the results were loaded from, rendered output
+	// formats are stored next to it
+	DocumentKey string `json:"-"`
+	// RequestID is the ID of the organization_ocr_request the results were recorded as
+	RequestID int64 `json:"-"`
+}
+
+// OCRStreamPage is the "page" event of a streamed OCR request, the results of
+// one page sent as soon as it is done
+type OCRStreamPage struct {
+	PageNumber   int           `json:"page_number" example:"1"`
+	OCRResponses []OCRResponse `json:"ocr_responses"`
+	PageSize     *PageSize     `json:"page_size,omitempty"`
+	PageLanguage *PageLanguage `json:"page_language,omitempty"`
+	Tables       []Table       `json:"tables,omitempty"`
+	Barcodes     []Barcode     `json:"barcodes,omitempty"`
+}
+
+// OCRStreamSummary is the "summary" event that ends a streamed OCR request
+type OCRStreamSummary struct {
+	RequestID      int64         `json:"request_id" example:"42"`
+	Pages          int           `json:"pages" example:"3"`
+	Words          int           `json:"words" example:"250"`
+	NumberOfTokens int64         `json:"number_of_tokens" example:"100"`
+	Cached         bool          `json:"cached" example:"false"`
+	Engine         OCREngineType `json:"engine"`
+	Raw            bool          `json:"raw" example:"false"`
+	Languages      []string      `json:"languages,omitempty" example:"en"`
+	// the label and value pairs found in the whole document when extract includes key_values
+	KeyValues []KeyValue `json:"key_values,omitempty"`
+}
+
+type PageSize struct {
+	PageNumber int `json:"page_number" example:"1"`
+	Width      int `json:"width" example:"2550"`
+	Height     int `json:"height" example:"3300"`
+}
+
+type Table struct {
+	PageNumber int  `json:"page_number" example:"1"`
+	BBox       BBox `json:"bbox"`
+	// ruled tables were found from the ruling lines of the page, the others from
+	// the alignment of the words
+	Ruled bool          `json:"ruled" example:"true"`
+	Rows  [][]TableCell `json:"rows"`
+	// the text of the cells as comma separated values, one record per row
+	CSV string `json:"csv" example:"Item,Qty,Price\nWidget,2,9.99\n"`
+}
+
+type Barcode struct {
+	PageNumber int                  `json:"page_number" example:"1"`
+	Symbology  BarcodeSymbologyType `json:"symbology" example:"qr_code"`
+	// the decoded content of the symbol
+	Payload string `json:"payload" example:"https://example.com/track/1Z999AA10123456784"`
+	BBox    BBox   `json:"bbox"`
+}
+
+type KeyValue struct {
+	PageNumber int `json:"page_number" example:"1"`
+	// the key of the organization's or the built in label synonyms the label
+	// matched, or the label in snake case when it matched none
+	Key   string `json:"key" example:"invoice_number"`
+	Label string `json:"label" example:"Invoice No"`
+	Value string `json:"value" example:"12345"`
+	// mean confidence of the label and value words
+	Confidence float64 `json:"confidence" example:"0.9"`
+	BBox       BBox    `json:"bbox"`
+	LabelBBox  BBox    `json:"label_bbox"`
+	ValueBBox  BBox    `json:"value_bbox"`
+}
+
+type TableCell struct {
+	Text string `json:"text" example:"9.99"`
+	BBox BBox   `json:"bbox"`
+}
+
+type PageLanguage struct {
+	PageNumber int `json:"page_number" example:"1"`
+	// script detected by tesseract's orientation and script detection, empty when
+	// the page has too little text to detect it
+	Script           string  `json:"script,omitempty" example:"Latin"`
+	ScriptConfidence float64 `json:"script_confidence,omitempty" example:"2.5"`
+	// the language that best matches the recognized text
+	Language string `json:"language" example:"de"`
+	// the languages the page was OCR'd with
+	Languages []string `json:"languages" example:"en,de,fr"`
 }
 
 type OCRResponse struct {
@@ -64,6 +470,57 @@ type OCRResponse struct {
 	Confidence float64 `json:"confidence" example:"0.5"`
 	BBox       BBox    `json:"bbox"`
 	PageNumber int     `json:"page_number" example:"1"`
+	// "ocr" or "text_layer" when the text was extracted from the PDF
+	Source string `json:"source,omitempty" example:"ocr"`
+	// the 1-based block, paragraph and line of the page the word is in, for
+	// engines that detect layout
+	Block     int `json:"block,omitempty" example:"1"`
+	Paragraph int `json:"paragraph,omitempty" example:"1"`
+	Line      int `json:"line,omitempty" example:"1"`
+}
+
+// STRUCTURED RESPONSE
+type StructuredOCRResponse struct {
+	Pages          []StructuredPage `json:"pages"`
+	Engine         OCREngineType    `json:"engine"`
+	NumberOfTokens int64            `json:"number_of_tokens" example:"100"`
+	Cached         bool             `json:"cached" example:"true"`
+	Languages      []string         `json:"languages,omitempty" example:"en,de"`
+	PageLanguages  []PageLanguage   `json:"page_languages,omitempty"`
+}
+
+// LayoutElement is the text, bounding box and mean word confidence of a node of the tree
+type LayoutElement struct {
+	Text       string  `json:"text" example:"hello world"`
+	Confidence float64 `json:"confidence" example:"0.5"`
+	BBox       BBox    `json:"bbox"`
+}
+
+type StructuredPage struct {
+	PageNumber int `json:"page_number" example:"1"`
+	// size of the page in pixels, 0 when it is not known
+	Width  int `json:"width" example:"2550"`
+	Height int `json:"height" example:"3300"`
+	LayoutElement
+	Blocks    []StructuredBlock `json:"blocks"`
+	Tables    []Table           `json:"tables,omitempty"`
+	Barcodes  []Barcode         `json:"barcodes,omitempty"`
+	KeyValues []KeyValue        `json:"key_values,omitempty"`
+}
+
+type StructuredBlock struct {
+	LayoutElement
+	Paragraphs []StructuredParagraph `json:"paragraphs"`
+}
+
+type StructuredParagraph struct {
+	LayoutElement
+	Lines []StructuredLine `json:"lines"`
+}
+
+type StructuredLine struct {
+	LayoutElement
+	Words []LayoutElement `json:"words"`
 }
 
 type BBox struct {
@@ -77,3 +534,27 @@ type XY struct {
 	X int `json:"x" example:"10"`
 	Y int `json:"y" example:"10"`
 }
+
+// OCRJSONRequest is the JSON body of an OCR request. The document is either the
+// base64 encoded file, or read from source_url or the key of a bucket instead of
+// being uploaded. The other fields are the OCR options of the form.
+type OCRJSONRequest struct {
+	File         string   `json:"file" example:"JVBERi0xLjcKJeLjz9MK..."`
+	Filename     string   `json:"filename" example:"invoice.pdf"`
+	SourceURL    string   `json:"source_url" example:"https://documents.example.com/invoice.pdf"`
+	Bucket       string   `json:"bucket" example:"documents"`
+	Key          string   `json:"key" example:"invoices/invoice.pdf"`
+	Engine       string   `json:"engine" example:"TESSERACT"`
+	Raw          *bool    `json:"raw" example:"false"`
+	CachePolicy  string   `json:"cache_policy" example:"cache_first"`
+	TextLayer    string   `json:"text_layer" example:"auto"`
+	Pages        string   `json:"pages" example:"1-3,7"`
+	Preprocess   string   `json:"preprocess" example:"deskew,threshold"`
+	Languages    []string `json:"languages" example:"en"`
+	Tables       *bool    `json:"tables" example:"false"`
+	Barcodes     *bool    `json:"barcodes" example:"false"`
+	Extract      []string `json:"extract" example:"key_values"`
+	Format       string   `json:"format" example:"flat"`
+	OutputFormat string   `json:"output_format" example:"json"`
+	Delivery     string   `json:"delivery" example:"bytes"`
+}
@@ -1,11 +1,17 @@
 package utils
 
 import (
+	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
 	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -145,3 +151,141 @@ func IsValidCachePolicy(cachePolicy string) bool {
 	}
 	return false
 }
+
+func IsValidTextLayerMode(textLayer string) bool {
+	for _, valid := range TextLayerModeValues {
+		if string(valid) == textLayer {
+			return true
+		}
+	}
+	return false
+}
+
+func IsValidResponseFormat(format string) bool {
+	for _, valid := range ResponseFormatValues {
+		if string(valid) == format {
+			return true
+		}
+	}
+	return false
+}
+
+func IsValidOutputFormat(format string) bool {
+	for _, valid := range OutputFormatValues {
+		if string(valid) == format {
+			return true
+		}
+	}
+	return false
+}
+
+func IsValidExtract(extract string) bool {
+	for _, valid := range ExtractValues {
+		if string(valid) == extract {
+			return true
+		}
+	}
+	return false
+}
+
+func IsValidDelivery(delivery string) bool {
+	for _, valid := range DeliveryValues {
+		if string(valid) == delivery {
+			return true
+		}
+	}
+	return false
+}
+
+// GenerateID returns a random 32 character hex identifier
+func GenerateID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// GetEnv reads a string from the environment, falling back to the default when
+// the variable is unset
+func GetEnv(name string, fallback string) string {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+// GetEnvDuration reads a duration such as "90s" from the environment, falling
+// back to the default when the variable is unset or not a valid duration
+func GetEnvDuration(name string, fallback time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(name))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// GetEnvInt reads an integer from the environment, falling back to the default
+// when the variable is unset or not a valid integer
+func GetEnvInt(name string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(name))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// nonPublicPrefixes are the ranges that are not public but that net.IP has no
+// check for
+var nonPublicPrefixes = []netip.Prefix{
+	// "this network"
+	netip.MustParsePrefix("0.0.0.0/8"),
+	// shared address space of carrier-grade NAT, also used inside some clouds
+	netip.MustParsePrefix("100.64.0.0/10"),
+	// benchmarking
+	netip.MustParsePrefix("198.18.0.0/15"),
+	// NAT64, which embeds an IPv4 address that may be internal
+	netip.MustParsePrefix("64:ff9b::/96"),
+}
+
+// IsPublicIP reports whether the address is not loopback, private, link-local,
+// multicast, unspecified or in one of nonPublicPrefixes
+func IsPublicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return false
+	}
+
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range nonPublicPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// ControlPublicAddress is a net.Dialer Control that refuses connections to
+// addresses that are not public. It runs after the host is resolved so a name
+// cannot point an outgoing request at an internal address.
+func ControlPublicAddress(network string, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return fmt.Errorf("%w: invalid address %s", ErrAddressNotPublic, host)
+	}
+	if !IsPublicIP(ip) {
+		return fmt.Errorf("%w: address %s is not public", ErrAddressNotPublic, ip)
+	}
+
+	return nil
+}
@@ -0,0 +1,55 @@
+package utils
+
+import (
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestIsPublicIP(t *testing.T) {
+	tests := []struct {
+		ip     string
+		public bool
+	}{
+		{"8.8.8.8", true},
+		{"2606:4700:4700::1111", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"fc00::1", false},
+		{"224.0.0.1", false},
+		{"0.0.0.0", false},
+		{"::", false},
+		{"0.1.2.3", false},
+		{"100.64.0.1", false},
+		{"100.127.255.255", false},
+		{"100.128.0.1", true},
+		{"198.18.0.1", false},
+		{"198.19.255.255", false},
+		{"198.20.0.1", true},
+		{"64:ff9b::a00:1", false},
+		{"::ffff:10.0.0.1", false},
+		{"::ffff:100.64.0.1", false},
+	}
+
+	for _, test := range tests {
+		if got := IsPublicIP(net.ParseIP(test.ip)); got != test.public {
+			t.Errorf("IsPublicIP(%s) = %v, want %v", test.ip, got, test.public)
+		}
+	}
+}
+
+func TestControlPublicAddress(t *testing.T) {
+	if err := ControlPublicAddress("tcp", "93.184.216.34:443", nil); err != nil {
+		t.Errorf("ControlPublicAddress() for a public address error = %v", err)
+	}
+	for _, address := range []string{"127.0.0.1:80", "[::1]:443", "100.64.0.1:443", "example.com:443"} {
+		if err := ControlPublicAddress("tcp", address, nil); !errors.Is(err, ErrAddressNotPublic) {
+			t.Errorf("ControlPublicAddress(%s) error = %v, want %v", address, err, ErrAddressNotPublic)
+		}
+	}
+}
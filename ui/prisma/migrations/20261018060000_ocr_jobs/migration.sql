@@ -0,0 +1,43 @@
+-- CreateEnum
+CREATE TYPE "OCRJobStatus" AS ENUM ('QUEUED', 'PROCESSING', 'COMPLETED', 'FAILED');
+
+-- AlterTable
+ALTER TABLE "organization_ocr_request" ADD COLUMN "jobId" TEXT;
+
+-- CreateTable
+CREATE TABLE "organization_ocr_job" (
+    "id" TEXT NOT NULL,
+    "organizationId" BIGINT NOT NULL,
+    "status" "OCRJobStatus" NOT NULL DEFAULT 'QUEUED',
+    "filename" TEXT NOT NULL,
+    "fileHash" TEXT NOT NULL,
+    "uploadKey" TEXT NOT NULL,
+    "resultKey" TEXT,
+    "ocrEngine" "OCREngine" NOT NULL,
+    "raw" BOOLEAN NOT NULL DEFAULT false,
+    "cachePolicy" TEXT NOT NULL,
+    "pagesTotal" INTEGER NOT NULL DEFAULT 0,
+    "pagesDone" INTEGER NOT NULL DEFAULT 0,
+    "error" TEXT,
+    "createdAt" TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "updatedAt" TIMESTAMP(3) NOT NULL,
+    "startedAt" TIMESTAMP(3),
+    "completedAt" TIMESTAMP(3),
+
+    CONSTRAINT "organization_ocr_job_pkey" PRIMARY KEY ("id")
+);
+
+-- CreateIndex
+CREATE INDEX "organization_ocr_request_jobId_idx" ON "organization_ocr_request"("jobId");
+
+-- CreateIndex
+CREATE INDEX "organization_ocr_job_status_createdAt_idx" ON "organization_ocr_job"("status", "createdAt");
+
+-- CreateIndex
+CREATE INDEX "organization_ocr_job_organizationId_idx" ON "organization_ocr_job"("organizationId");
+
+-- AddForeignKey
+ALTER TABLE "organization_ocr_request" ADD CONSTRAINT "organization_ocr_request_jobId_fkey" FOREIGN KEY ("jobId") REFERENCES "organization_ocr_job"("id") ON DELETE SET NULL ON UPDATE CASCADE;
+
+-- AddForeignKey
+ALTER TABLE "organization_ocr_job" ADD CONSTRAINT "organization_ocr_job_organizationId_fkey" FOREIGN KEY ("organizationId") REFERENCES "organization"("id") ON DELETE CASCADE ON UPDATE CASCADE;
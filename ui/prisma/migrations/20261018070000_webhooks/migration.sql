@@ -0,0 +1,50 @@
+-- CreateEnum
+CREATE TYPE "WebhookDeliveryStatus" AS ENUM ('PENDING', 'SUCCEEDED', 'FAILED');
+
+-- CreateTable
+CREATE TABLE "organization_webhook" (
+    "id" TEXT NOT NULL,
+    "organizationId" BIGINT NOT NULL,
+    "url" TEXT NOT NULL,
+    "secret" TEXT NOT NULL,
+    "enabled" BOOLEAN NOT NULL DEFAULT true,
+    "createdAt" TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "updatedAt" TIMESTAMP(3) NOT NULL,
+
+    CONSTRAINT "organization_webhook_pkey" PRIMARY KEY ("id")
+);
+
+-- CreateTable
+CREATE TABLE "organization_webhook_delivery" (
+    "id" BIGSERIAL NOT NULL,
+    "webhookId" TEXT NOT NULL,
+    "organizationId" BIGINT NOT NULL,
+    "ocrRequestId" BIGINT,
+    "event" TEXT NOT NULL,
+    "payload" TEXT NOT NULL,
+    "status" "WebhookDeliveryStatus" NOT NULL DEFAULT 'PENDING',
+    "attempts" INTEGER NOT NULL DEFAULT 0,
+    "responseStatus" INTEGER,
+    "error" TEXT,
+    "nextAttemptAt" TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "createdAt" TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "updatedAt" TIMESTAMP(3) NOT NULL,
+    "deliveredAt" TIMESTAMP(3),
+
+    CONSTRAINT "organization_webhook_delivery_pkey" PRIMARY KEY ("id")
+);
+
+-- CreateIndex
+CREATE INDEX "organization_webhook_organizationId_idx" ON "organization_webhook"("organizationId");
+
+-- CreateIndex
+CREATE INDEX "organization_webhook_delivery_status_nextAttemptAt_idx" ON "organization_webhook_delivery"("status", "nextAttemptAt");
+
+-- CreateIndex
+CREATE INDEX "organization_webhook_delivery_webhookId_createdAt_idx" ON "organization_webhook_delivery"("webhookId", "createdAt");
+
+-- AddForeignKey
+ALTER TABLE "organization_webhook" ADD CONSTRAINT "organization_webhook_organizationId_fkey" FOREIGN KEY ("organizationId") REFERENCES "organization"("id") ON DELETE CASCADE ON UPDATE CASCADE;
+
+-- AddForeignKey
+ALTER TABLE "organization_webhook_delivery" ADD CONSTRAINT "organization_webhook_delivery_webhookId_fkey" FOREIGN KEY ("webhookId") REFERENCES "organization_webhook"("id") ON DELETE CASCADE ON UPDATE CASCADE;
@@ -0,0 +1,2 @@
+-- AlterTable
+ALTER TABLE "organization" ADD COLUMN     "ocrPageConcurrency" INTEGER;
@@ -0,0 +1,16 @@
+-- DropForeignKey
+ALTER TABLE "organization_ocr_request" DROP CONSTRAINT "organization_ocr_request_cacheFileHash_organizationId_raw__fkey";
+
+-- AlterTable
+ALTER TABLE "organization_file_cache" DROP CONSTRAINT "organization_file_cache_pkey",
+ADD COLUMN     "textLayer" TEXT NOT NULL DEFAULT 'never',
+ADD CONSTRAINT "organization_file_cache_pkey" PRIMARY KEY ("organizationId", "hash", "raw", "ocrEngine", "textLayer");
+
+-- AlterTable
+ALTER TABLE "organization_ocr_job" ADD COLUMN     "textLayer" TEXT NOT NULL DEFAULT 'never';
+
+-- AlterTable
+ALTER TABLE "organization_ocr_request" ADD COLUMN     "textLayer" TEXT NOT NULL DEFAULT 'never';
+
+-- AddForeignKey
+ALTER TABLE "organization_ocr_request" ADD CONSTRAINT "organization_ocr_request_cacheFileHash_organizationId_raw__fkey" FOREIGN KEY ("cacheFileHash", "organizationId", "raw", "ocrEngine", "textLayer") REFERENCES "organization_file_cache"("hash", "organizationId", "raw", "ocrEngine", "textLayer") ON DELETE RESTRICT ON UPDATE CASCADE;
@@ -0,0 +1,5 @@
+-- AlterTable
+ALTER TABLE "organization_ocr_job" ADD COLUMN     "mimeType" TEXT;
+
+-- AlterTable
+ALTER TABLE "organization_ocr_request" ADD COLUMN     "mimeType" TEXT;
@@ -0,0 +1,16 @@
+-- DropForeignKey
+ALTER TABLE "organization_ocr_request" DROP CONSTRAINT "organization_ocr_request_cacheFileHash_organizationId_raw__fkey";
+
+-- AlterTable
+ALTER TABLE "organization_file_cache" DROP CONSTRAINT "organization_file_cache_pkey",
+ADD COLUMN     "preprocess" TEXT NOT NULL DEFAULT '',
+ADD CONSTRAINT "organization_file_cache_pkey" PRIMARY KEY ("organizationId", "hash", "raw", "ocrEngine", "textLayer", "preprocess");
+
+-- AlterTable
+ALTER TABLE "organization_ocr_job" ADD COLUMN     "preprocess" TEXT NOT NULL DEFAULT '';
+
+-- AlterTable
+ALTER TABLE "organization_ocr_request" ADD COLUMN     "preprocess" TEXT NOT NULL DEFAULT '';
+
+-- AddForeignKey
+ALTER TABLE "organization_ocr_request" ADD CONSTRAINT "organization_ocr_request_cacheFileHash_organizationId_raw__fkey" FOREIGN KEY ("cacheFileHash", "organizationId", "raw", "ocrEngine", "textLayer", "preprocess") REFERENCES "organization_file_cache"("hash", "organizationId", "raw", "ocrEngine", "textLayer", "preprocess") ON DELETE RESTRICT ON UPDATE CASCADE;
@@ -0,0 +1,16 @@
+-- DropForeignKey
+ALTER TABLE "organization_ocr_request" DROP CONSTRAINT "organization_ocr_request_cacheFileHash_organizationId_raw__fkey";
+
+-- AlterTable
+ALTER TABLE "organization_file_cache" DROP CONSTRAINT "organization_file_cache_pkey",
+ADD COLUMN     "languages" TEXT NOT NULL DEFAULT 'en',
+ADD CONSTRAINT "organization_file_cache_pkey" PRIMARY KEY ("organizationId", "hash", "raw", "ocrEngine", "textLayer", "preprocess", "languages");
+
+-- AlterTable
+ALTER TABLE "organization_ocr_job" ADD COLUMN     "languages" TEXT NOT NULL DEFAULT 'en';
+
+-- AlterTable
+ALTER TABLE "organization_ocr_request" ADD COLUMN     "languages" TEXT NOT NULL DEFAULT 'en';
+
+-- AddForeignKey
+ALTER TABLE "organization_ocr_request" ADD CONSTRAINT "organization_ocr_request_cacheFileHash_organizationId_raw__fkey" FOREIGN KEY ("cacheFileHash", "organizationId", "raw", "ocrEngine", "textLayer", "preprocess", "languages") REFERENCES "organization_file_cache"("hash", "organizationId", "raw", "ocrEngine", "textLayer", "preprocess", "languages") ON DELETE RESTRICT ON UPDATE CASCADE;
@@ -0,0 +1,2 @@
+-- AlterTable
+ALTER TABLE "organization_ocr_job" ADD COLUMN     "format" TEXT NOT NULL DEFAULT 'flat';
@@ -0,0 +1,2 @@
+-- AlterTable
+ALTER TABLE "organization_ocr_job" ADD COLUMN     "outputFormat" TEXT NOT NULL DEFAULT 'json';
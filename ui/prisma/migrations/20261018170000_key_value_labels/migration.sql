@@ -0,0 +1,20 @@
+-- AlterTable
+ALTER TABLE "organization_ocr_job" ADD COLUMN     "extract" TEXT NOT NULL DEFAULT '';
+
+-- CreateTable
+CREATE TABLE "organization_key_value_label" (
+    "id" TEXT NOT NULL,
+    "organizationId" BIGINT NOT NULL,
+    "key" TEXT NOT NULL,
+    "synonyms" TEXT[],
+    "createdAt" TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "updatedAt" TIMESTAMP(3) NOT NULL,
+
+    CONSTRAINT "organization_key_value_label_pkey" PRIMARY KEY ("id")
+);
+
+-- CreateIndex
+CREATE UNIQUE INDEX "organization_key_value_label_organizationId_key_key" ON "organization_key_value_label"("organizationId", "key");
+
+-- AddForeignKey
+ALTER TABLE "organization_key_value_label" ADD CONSTRAINT "organization_key_value_label_organizationId_fkey" FOREIGN KEY ("organizationId") REFERENCES "organization"("id") ON DELETE CASCADE ON UPDATE CASCADE;
@@ -0,0 +1,19 @@
+-- CreateTable
+CREATE TABLE "organization_ocr_template" (
+    "id" TEXT NOT NULL,
+    "organizationId" BIGINT NOT NULL,
+    "name" TEXT NOT NULL,
+    "ocrEngine" "OCREngine" NOT NULL DEFAULT 'TESSERACT',
+    "languages" TEXT NOT NULL DEFAULT 'en',
+    "zones" JSONB NOT NULL,
+    "createdAt" TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "updatedAt" TIMESTAMP(3) NOT NULL,
+
+    CONSTRAINT "organization_ocr_template_pkey" PRIMARY KEY ("id")
+);
+
+-- CreateIndex
+CREATE UNIQUE INDEX "organization_ocr_template_organizationId_name_key" ON "organization_ocr_template"("organizationId", "name");
+
+-- AddForeignKey
+ALTER TABLE "organization_ocr_template" ADD CONSTRAINT "organization_ocr_template_organizationId_fkey" FOREIGN KEY ("organizationId") REFERENCES "organization"("id") ON DELETE CASCADE ON UPDATE CASCADE;
@@ -0,0 +1,16 @@
+-- DropForeignKey
+ALTER TABLE "organization_ocr_request" DROP CONSTRAINT "organization_ocr_request_cacheFileHash_organizationId_raw__fkey";
+
+-- AlterTable
+ALTER TABLE "organization_file_cache" DROP CONSTRAINT "organization_file_cache_pkey",
+ADD COLUMN     "barcodes" BOOLEAN NOT NULL DEFAULT false,
+ADD CONSTRAINT "organization_file_cache_pkey" PRIMARY KEY ("organizationId", "hash", "raw", "ocrEngine", "textLayer", "preprocess", "languages", "tables", "barcodes");
+
+-- AlterTable
+ALTER TABLE "organization_ocr_job" ADD COLUMN     "barcodes" BOOLEAN NOT NULL DEFAULT false;
+
+-- AlterTable
+ALTER TABLE "organization_ocr_request" ADD COLUMN     "barcodes" BOOLEAN NOT NULL DEFAULT false;
+
+-- AddForeignKey
+ALTER TABLE "organization_ocr_request" ADD CONSTRAINT "organization_ocr_request_cacheFileHash_organizationId_raw__fkey" FOREIGN KEY ("cacheFileHash", "organizationId", "raw", "ocrEngine", "textLayer", "preprocess", "languages", "tables", "barcodes") REFERENCES "organization_file_cache"("hash", "organizationId", "raw", "ocrEngine", "textLayer", "preprocess", "languages", "tables", "barcodes") ON DELETE RESTRICT ON UPDATE CASCADE;